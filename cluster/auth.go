@@ -0,0 +1,37 @@
+package cluster
+
+import (
+	"fmt"
+
+	"github.com/duanhf2012/origin/v2/log"
+	"github.com/duanhf2012/origin/v2/rpc"
+)
+
+// Auth 结点间连接的认证配置，连接建立后双方交换结点Id、集群名与协议版本，并以Secret校验HMAC，失败时断开连接。
+// 集群内所有结点需配置相同的ClusterName与Secret
+type Auth struct {
+	ClusterName string //集群名，用于拒绝误接入其他集群的结点
+	Secret      string //集群共享密钥
+}
+
+func (cls *Cluster) setupAuth() error {
+	if cls.auth == nil {
+		return nil
+	}
+
+	if cls.IsNatsMode() {
+		log.Warn("Auth config is ignored in nats mode")
+		return nil
+	}
+
+	err := rpc.SetHandshakeConfig(&rpc.HandshakeConfig{
+		NodeId:      cls.localNodeInfo.NodeId,
+		ClusterName: cls.auth.ClusterName,
+		Secret:      cls.auth.Secret,
+	})
+	if err != nil {
+		return fmt.Errorf("Auth config error:%s", err.Error())
+	}
+
+	return nil
+}
@@ -0,0 +1,34 @@
+package cluster
+
+import (
+	"fmt"
+
+	"github.com/duanhf2012/origin/v2/rpc"
+)
+
+// CaptureConfig 本结点RPC收发帧的抓包配置，用于离线回放排查问题，会影响性能，不建议在正式环境长期开启
+type CaptureConfig struct {
+	Dir         string   //抓包文件目录
+	MaxFileSize int64    //单个文件最大字节数，默认256MB
+	MaxFiles    int      //最多保留的文件数，默认16
+	Services    []string //只记录这些服务，不配置时记录所有服务
+}
+
+func (cls *Cluster) setupCapture() error {
+	captureCfg := cls.localNodeInfo.Capture
+	if captureCfg == nil {
+		return nil
+	}
+
+	err := rpc.StartCapture(&rpc.CaptureConfig{
+		Dir:         captureCfg.Dir,
+		MaxFileSize: captureCfg.MaxFileSize,
+		MaxFiles:    captureCfg.MaxFiles,
+		Services:    captureCfg.Services,
+	})
+	if err != nil {
+		return fmt.Errorf("node %s Capture config error:%s", cls.localNodeInfo.NodeId, err.Error())
+	}
+
+	return nil
+}
@@ -0,0 +1,42 @@
+package cluster
+
+import (
+	"time"
+
+	"github.com/duanhf2012/origin/v2/rpc"
+)
+
+// CircuitBreaker 跨结点调用的熔断配置，不配置时不熔断，未设置的项使用rpc.DefaultBreakerConfig
+type CircuitBreaker struct {
+	Disable          bool    //关闭熔断
+	Window           int     //统计窗口(毫秒)
+	MinRequests      int     //窗口内至少有多少次调用结果才进行判断
+	FailureRatio     float64 //失败比例达到该值时熔断
+	OpenTimeout      int     //熔断持续时间(毫秒)，之后放行探测调用
+	HalfOpenMaxCalls int     //同时进行的探测调用数
+	FailureCodes     []int32 //计为失败的错误码
+}
+
+func (cls *Cluster) setupCircuitBreaker() {
+	if cls.circuitBreaker == nil {
+		return
+	}
+
+	if cls.circuitBreaker.Disable == true {
+		rpc.SetBreakerConfig(nil)
+		return
+	}
+
+	cfg := rpc.BreakerConfig{
+		Window:           time.Duration(cls.circuitBreaker.Window) * time.Millisecond,
+		MinRequests:      cls.circuitBreaker.MinRequests,
+		FailureRatio:     cls.circuitBreaker.FailureRatio,
+		OpenTimeout:      time.Duration(cls.circuitBreaker.OpenTimeout) * time.Millisecond,
+		HalfOpenMaxCalls: cls.circuitBreaker.HalfOpenMaxCalls,
+	}
+	for _, code := range cls.circuitBreaker.FailureCodes {
+		cfg.FailureCodes = append(cfg.FailureCodes, rpc.ErrCode(code))
+	}
+
+	rpc.SetBreakerConfig(&cfg)
+}
@@ -7,6 +7,7 @@ import (
 	"github.com/duanhf2012/origin/v2/log"
 	"github.com/duanhf2012/origin/v2/rpc"
 	"github.com/duanhf2012/origin/v2/service"
+	"github.com/duanhf2012/origin/v2/util/hash"
 	"reflect"
 	"strings"
 	"sync"
@@ -32,14 +33,19 @@ type DiscoveryService struct {
 type NodeInfo struct {
 	NodeId            string
 	Private           bool
-	ListenAddr        string
+	ListenAddr        string             //Rpc监听地址，unix://开头时使用Unix domain socket，只有同一主机上的结点能连接
+	HostId            string             //结点所在主机，不配置时使用主机名
+	UnixListenAddr    string             //同一主机上的结点使用的unix://地址，可以缺省
 	MaxRpcParamLen    uint32             //最大Rpc参数长度
 	CompressBytesLen  int                //超过字节进行压缩的长度
+	CompressCodec     string             //压缩算法:lz4,zstd,snappy，默认lz4
 	ServiceList       []string           //所有的有序服务列表
 	PublicServiceList []string           //对外公开的服务列表
 	DiscoveryService  []DiscoveryService //筛选发现的服务，如果不配置，不进行筛选
 	status            NodeStatus
 	Retire            bool
+	TLS               *TLSConfig     //结点间RPC连接的TLS配置，不配置时不加密
+	Capture           *CaptureConfig //RPC抓包配置，不配置时不抓包
 
 	NetworkName string
 }
@@ -58,13 +64,22 @@ type Cluster struct {
 	rpcMode       RpcMode
 	globalCfg     interface{} //全局配置
 
-	localServiceCfg  map[string]interface{} //map[serviceName]配置数据*
-	serviceDiscovery IServiceDiscovery      //服务发现接口
-
-	locker                 sync.RWMutex                   //结点与服务关系保护锁
-	mapRpc                 map[string]*NodeRpcInfo        //nodeId
-	mapServiceNode         map[string]map[string]struct{} //map[serviceName]map[NodeId]
-	mapTemplateServiceNode map[string]map[string]struct{} //map[templateServiceName]map[serviceName]nodeId
+	localServiceCfg  map[string]interface{}     //map[serviceName]配置数据*
+	loadBalance      *LoadBalance               //负载均衡配置
+	rpcRetry         map[string]RpcRetryPolicy  //RPC重试策略
+	circuitBreaker   *CircuitBreaker            //熔断配置
+	compress         *Compress                  //压缩配置
+	auth             *Auth                      //结点认证配置
+	connPool         *ConnPool                  //与其他结点的连接数配置
+	overload         *Overload                  //服务过载保护配置
+	rateLimit        map[string]RateLimitPolicy //服务限流配置
+	serviceDiscovery IServiceDiscovery          //服务发现接口
+
+	locker                 sync.RWMutex                    //结点与服务关系保护锁
+	mapRpc                 map[string]*NodeRpcInfo         //nodeId
+	mapServiceNode         map[string]map[string]struct{}  //map[serviceName]map[NodeId]
+	mapTemplateServiceNode map[string]map[string]struct{}  //map[templateServiceName]map[serviceName]nodeId
+	mapServiceRing         map[string]*hash.ConsistentHash //map[serviceName]一致性哈希环
 
 	callSet   rpc.CallSet
 	rpcNats   rpc.RpcNats
@@ -72,12 +87,27 @@ type Cluster struct {
 
 	rpcEventLocker           sync.RWMutex        //Rpc事件监听保护锁
 	mapServiceListenRpcEvent map[string]struct{} //ServiceName
+
+	serviceMgr *service.ServiceManager //本结点的服务，为nil时使用进程默认的ServiceManager
 }
 
 func GetCluster() *Cluster {
 	return &cluster
 }
 
+// NewCluster 创建独立的Cluster，用于同一进程运行多个结点，如测试。服务安装到serviceMgr中
+func NewCluster(serviceMgr *service.ServiceManager) *Cluster {
+	return &Cluster{serviceMgr: serviceMgr}
+}
+
+func (cls *Cluster) getServiceMgr() *service.ServiceManager {
+	if cls.serviceMgr == nil {
+		return service.GetServiceManager()
+	}
+
+	return cls.serviceMgr
+}
+
 func SetConfigDir(cfgDir string) {
 	configDir = cfgDir
 }
@@ -92,6 +122,7 @@ func (cls *Cluster) Start() error {
 
 func (cls *Cluster) Stop() {
 	cls.rpcServer.Stop()
+	cls.callSet.StopCheckTimeout()
 }
 
 func (cls *Cluster) DiscardNode(nodeId string) {
@@ -124,6 +155,7 @@ func (cls *Cluster) DelNode(nodeId string) {
 	}
 
 	delete(cls.mapRpc, nodeId)
+	cls.updateHashRing(nodeRpc.nodeInfo.ServiceList...)
 	if ok == true {
 		nodeRpc.client.Close(false)
 	}
@@ -167,6 +199,12 @@ func (cls *Cluster) serviceDiscoverySetNodeInfo(nodeInfo *NodeInfo) {
 		return
 	}
 
+	rpcAddr, ok := cls.getRpcAddr(nodeInfo)
+	if ok == false {
+		log.Warn("node listens on unix socket of another host", log.String("NodeId", nodeInfo.NodeId), log.String("HostId", nodeInfo.HostId), log.String("ListenAddr", nodeInfo.ListenAddr))
+		return
+	}
+
 	cls.locker.Lock()
 	defer cls.locker.Unlock()
 
@@ -176,9 +214,11 @@ func (cls *Cluster) serviceDiscoverySetNodeInfo(nodeInfo *NodeInfo) {
 		for _, serviceName := range lastNodeInfo.nodeInfo.ServiceList {
 			cls.delServiceNode(serviceName, nodeInfo.NodeId)
 		}
+		defer cls.updateHashRing(lastNodeInfo.nodeInfo.ServiceList...)
 	}
+	defer cls.updateHashRing(nodeInfo.PublicServiceList...)
 
-	cluster.TriggerDiscoveryEvent(true, nodeInfo.NodeId, nodeInfo.PublicServiceList)
+	cls.TriggerDiscoveryEvent(true, nodeInfo.NodeId, nodeInfo.PublicServiceList)
 	//再重新组装
 	mapDuplicate := map[string]interface{}{} //预防重复数据
 	for _, serviceName := range nodeInfo.PublicServiceList {
@@ -220,13 +260,13 @@ func (cls *Cluster) serviceDiscoverySetNodeInfo(nodeInfo *NodeInfo) {
 	if cls.IsNatsMode() {
 		rpcInfo.client = cls.rpcNats.NewNatsClient(nodeInfo.NodeId, cls.GetLocalNodeInfo().NodeId, &cls.callSet, cls.NotifyAllService)
 	} else {
-		rpcInfo.client = rpc.NewRClient(nodeInfo.NodeId, nodeInfo.ListenAddr, nodeInfo.MaxRpcParamLen, cls.localNodeInfo.CompressBytesLen, &cls.callSet, cls.NotifyAllService)
+		rpcInfo.client = rpc.NewRClient(nodeInfo.NodeId, rpcAddr, nodeInfo.MaxRpcParamLen, cls.localNodeInfo.CompressBytesLen, &cls.callSet, cls.NotifyAllService)
 	}
 	cls.mapRpc[nodeInfo.NodeId] = &rpcInfo
 	if cls.IsNatsMode() == true || cls.discoveryInfo.discoveryType != OriginType {
 		log.Info("Discovery nodeId and new rpc client", log.String("NodeId", nodeInfo.NodeId), log.Any("services:", nodeInfo.PublicServiceList), log.Bool("Retire", nodeInfo.Retire))
 	} else {
-		log.Info("Discovery nodeId and new rpc client", log.String("NodeId", nodeInfo.NodeId), log.Any("services:", nodeInfo.PublicServiceList), log.Bool("Retire", nodeInfo.Retire), log.String("nodeListenAddr", nodeInfo.ListenAddr))
+		log.Info("Discovery nodeId and new rpc client", log.String("NodeId", nodeInfo.NodeId), log.Any("services:", nodeInfo.PublicServiceList), log.Bool("Retire", nodeInfo.Retire), log.String("nodeListenAddr", rpcAddr))
 	}
 }
 
@@ -236,15 +276,17 @@ func (cls *Cluster) Init(localNodeId string, setupServiceFun SetupServiceFun) er
 	if err != nil {
 		return err
 	}
+	rpc.SetLocalNodeId(localNodeId)
 
-	cls.callSet.Init()
-	if cls.IsNatsMode() {
-		cls.rpcNats.Init(cls.rpcMode.Nats.NatsUrl, cls.rpcMode.Nats.NoRandomize, cls.GetLocalNodeInfo().NodeId, cls.localNodeInfo.CompressBytesLen, cls, cluster.NotifyAllService)
-		cls.rpcServer = &cls.rpcNats
-	} else {
-		s := &rpc.Server{}
-		s.Init(cls.localNodeInfo.ListenAddr, cls.localNodeInfo.MaxRpcParamLen, cls.localNodeInfo.CompressBytesLen, cls)
-		cls.rpcServer = s
+	err = cls.setupRpcPolicy()
+	if err != nil {
+		return err
+	}
+
+	cls.setupRpcServer()
+	err = cls.setupRateLimit()
+	if err != nil {
+		return err
 	}
 
 	//2.安装服务发现结点
@@ -255,6 +297,7 @@ func (cls *Cluster) Init(localNodeId string, setupServiceFun SetupServiceFun) er
 	}
 	service.RegRpcEventFun = cls.RegRpcEvent
 	service.UnRegRpcEventFun = cls.UnRegRpcEvent
+	rpc.SetNodeIdByKeyFun(GetNodeIdByKey)
 
 	err = cls.serviceDiscovery.InitDiscovery(localNodeId, cls.serviceDiscoveryDelNode, cls.serviceDiscoverySetNodeInfo)
 	if err != nil {
@@ -264,8 +307,116 @@ func (cls *Cluster) Init(localNodeId string, setupServiceFun SetupServiceFun) er
 	return nil
 }
 
+// InitNode 不读取配置文件，按nodeInfo初始化结点，serviceCfg为map[serviceName]服务配置。
+// 只支持TCP方式的Rpc，Rpc调用策略等进程级的配置不在此设置。用于同一进程运行多个结点，
+// 结点的服务通过InitService安装
+func (cls *Cluster) InitNode(nodeInfo *NodeInfo, serviceCfg map[string]interface{}, serviceDiscovery IServiceDiscovery) error {
+	if serviceDiscovery == nil {
+		return errors.New("service discovery is nil")
+	}
+
+	cls.localServiceCfg = map[string]interface{}{}
+	for serviceName, cfg := range serviceCfg {
+		cls.localServiceCfg[serviceName] = cfg
+	}
+	cls.mapRpc = map[string]*NodeRpcInfo{}
+	cls.mapServiceNode = map[string]map[string]struct{}{}
+	cls.mapTemplateServiceNode = map[string]map[string]struct{}{}
+	cls.mapServiceRing = map[string]*hash.ConsistentHash{}
+
+	cls.localNodeInfo = *nodeInfo
+	cls.localNodeInfo.ServiceList = append([]string{}, nodeInfo.ServiceList...)
+	cls.localNodeInfo.PublicServiceList = nil
+	cls.localNodeInfo.setupPublicServiceList()
+
+	err := cls.setupHostId()
+	if err != nil {
+		return err
+	}
+
+	err = cls.parseLocalCfg()
+	if err != nil {
+		return err
+	}
+
+	cls.setupRpcServer()
+	cls.callSet.SetLocalNodeId(cls.localNodeInfo.NodeId)
+	err = cls.setupRateLimit()
+	if err != nil {
+		return err
+	}
+
+	cls.serviceDiscovery = serviceDiscovery
+	cls.getServiceMgr().SetRpcEventFun(cls.RegRpcEvent, cls.UnRegRpcEvent)
+
+	return cls.serviceDiscovery.InitDiscovery(cls.localNodeInfo.NodeId, cls.serviceDiscoveryDelNode, cls.serviceDiscoverySetNodeInfo)
+}
+
+// InitService 初始化服务并安装到本结点，服务通过本结点发现的结点进行Rpc调用。需在InitNode之后调用
+func (cls *Cluster) InitService(s service.IService) bool {
+	s.Init(s, cls.FindRpcClient, cls.GetRpcServer, cls.GetServiceCfg(s.GetName()))
+	s.GetRpcHandler().SetNodeIdByKeyFun(cls.GetNodeIdByKey)
+
+	return cls.getServiceMgr().Setup(s)
+}
+
+// setupRpcPolicy 设置配置文件中的Rpc调用策略
+func (cls *Cluster) setupRpcPolicy() error {
+	err := cls.setupLoadBalance()
+	if err != nil {
+		return err
+	}
+
+	err = cls.setupRpcRetry()
+	if err != nil {
+		return err
+	}
+	cls.setupCircuitBreaker()
+
+	err = cls.setupCompress()
+	if err != nil {
+		return err
+	}
+
+	err = cls.setupTLS()
+	if err != nil {
+		return err
+	}
+
+	err = cls.setupAuth()
+	if err != nil {
+		return err
+	}
+
+	err = cls.setupConnPool()
+	if err != nil {
+		return err
+	}
+
+	err = cls.setupOverload()
+	if err != nil {
+		return err
+	}
+
+	return cls.setupCapture()
+}
+
+func (cls *Cluster) setupRpcServer() {
+	cls.callSet.Init()
+	if cls.IsNatsMode() {
+		cls.rpcNats.Init(cls.rpcMode.Nats.NatsUrl, cls.rpcMode.Nats.NoRandomize, cls.GetLocalNodeInfo().NodeId, cls.localNodeInfo.CompressBytesLen, cls, cls.NotifyAllService)
+		cls.rpcServer = &cls.rpcNats
+	} else {
+		s := &rpc.Server{}
+		s.Init(cls.localNodeInfo.ListenAddr, cls.localNodeInfo.MaxRpcParamLen, cls.localNodeInfo.CompressBytesLen, cls)
+		s.SetUnixListenAddr(cls.localNodeInfo.UnixListenAddr)
+		s.SetLocalNodeId(cls.localNodeInfo.NodeId)
+		cls.rpcServer = s
+	}
+}
+
 func (cls *Cluster) FindRpcHandler(serviceName string) rpc.IRpcHandler {
-	pService := service.GetService(serviceName)
+	pService := cls.getServiceMgr().GetService(serviceName)
 	if pService == nil {
 		return nil
 	}
@@ -293,15 +444,20 @@ func GetNodeIdByTemplateService(templateServiceName string, rpcClientList []*rpc
 }
 
 func GetRpcClient(nodeId string, serviceMethod string, filterRetire bool, clientList []*rpc.Client) (error, []*rpc.Client) {
+	return cluster.FindRpcClient(nodeId, serviceMethod, filterRetire, clientList)
+}
+
+// FindRpcClient 找出调用serviceMethod的结点，nodeId为空时按服务名查找
+func (cls *Cluster) FindRpcClient(nodeId string, serviceMethod string, filterRetire bool, clientList []*rpc.Client) (error, []*rpc.Client) {
 	if nodeId != rpc.NodeIdNull {
-		pClient, retire := GetCluster().GetRpcClient(nodeId)
+		pClient, retire := cls.GetRpcClient(nodeId)
 		if pClient == nil {
-			return fmt.Errorf("cannot find  nodeid %s", nodeId), nil
+			return rpc.NewError(rpc.CodeNoNode, fmt.Sprintf("cannot find  nodeid %s", nodeId)), nil
 		}
 
 		//如果需要筛选掉退休结点
 		if filterRetire == true && retire == true {
-			return fmt.Errorf("cannot find  nodeid %s", nodeId), nil
+			return rpc.NewError(rpc.CodeNoNode, fmt.Sprintf("cannot find  nodeid %s", nodeId)), nil
 		}
 
 		clientList = append(clientList, pClient)
@@ -314,13 +470,17 @@ func GetRpcClient(nodeId string, serviceMethod string, filterRetire bool, client
 	}
 	serviceName := serviceMethod[:findIndex]
 
-	return GetCluster().GetNodeIdByService(serviceName, clientList, filterRetire)
+	return cls.GetNodeIdByService(serviceName, clientList, filterRetire)
 }
 
 func GetRpcServer() rpc.IServer {
 	return cluster.rpcServer
 }
 
+func (cls *Cluster) GetRpcServer() rpc.IServer {
+	return cls.rpcServer
+}
+
 func (cls *Cluster) IsNodeConnected(nodeId string) bool {
 	pClient, _ := cls.GetRpcClient(nodeId)
 	return pClient != nil && pClient.IsConnected()
@@ -339,7 +499,7 @@ func (cls *Cluster) NotifyAllService(event event.IEvent) {
 	defer cls.rpcEventLocker.Unlock()
 
 	for serviceName := range cls.mapServiceListenRpcEvent {
-		ser := service.GetService(serviceName)
+		ser := cls.getServiceMgr().GetService(serviceName)
 		if ser == nil {
 			log.Error("cannot find service name " + serviceName)
 			continue
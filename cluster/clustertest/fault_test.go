@@ -0,0 +1,157 @@
+package clustertest
+
+import (
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/duanhf2012/origin/v2/cluster"
+	"github.com/duanhf2012/origin/v2/rpc"
+	"github.com/duanhf2012/origin/v2/service"
+)
+
+// OrderService 按收到的顺序记录请求
+type OrderService struct {
+	service.Service
+
+	locker  sync.Mutex
+	seqList []int
+	done    chan struct{}
+	total   int
+}
+
+func (ors *OrderService) RPC_Record(seq *int, res *int) error {
+	ors.locker.Lock()
+	defer ors.locker.Unlock()
+
+	ors.seqList = append(ors.seqList, *seq)
+	if len(ors.seqList) == ors.total {
+		close(ors.done)
+	}
+	return nil
+}
+
+func clearFaults(t *testing.T) {
+	t.Cleanup(func() {
+		rpc.ClearFaultRules()
+		rpc.SetRetryPolicy("SumService.RPC_Sum", nil)
+	})
+}
+
+func callSumWithTimeout(node *Node, timeout time.Duration) error {
+	var res int
+	return node.GetService("CallerService").GetRpcHandler().CallWithTimeout(timeout, "SumService.RPC_Sum", &SumReq{A: 1, B: 2}, &res)
+}
+
+func TestCutLink(t *testing.T) {
+	clearFaults(t)
+	_, n1, _, _ := startNodes(t)
+	checkCallSum(t, n1)
+
+	rpc.CutLink("node_1", "node_2")
+	err := callSumWithTimeout(n1, 200*time.Millisecond)
+	if rpc.Code(err) != rpc.CodeTimeout {
+		t.Fatalf("call error = %v, want timeout", err)
+	}
+	//连接保持，只是帧被丢弃
+	if n1.IsNodeConnected("node_2") == false {
+		t.Fatal("link should keep connected")
+	}
+
+	dropped := uint64(0)
+	for _, stats := range rpc.GetFaultStats() {
+		dropped += stats.Dropped
+	}
+	if dropped == 0 {
+		t.Fatal("cut link does not drop frames")
+	}
+
+	rpc.RestoreLink("node_1", "node_2")
+	if len(rpc.GetFaultRules()) != 0 {
+		t.Fatalf("fault rules are not removed:%v", rpc.GetFaultRules())
+	}
+	checkCallSum(t, n1)
+}
+
+// TestDropWithRetry 请求被丢弃后调用超时，重试时恢复
+func TestDropWithRetry(t *testing.T) {
+	clearFaults(t)
+	_, n1, _, _ := startNodes(t)
+
+	err := rpc.SetFaultRule("drop", &rpc.FaultRule{FromNodeId: "node_1", ToNodeId: "node_2", DropRate: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = callSumWithTimeout(n1, 100*time.Millisecond); rpc.Code(err) != rpc.CodeTimeout {
+		t.Fatalf("call error = %v, want timeout", err)
+	}
+
+	rpc.SetFaultRule("drop", &rpc.FaultRule{FromNodeId: "node_1", ToNodeId: "node_2", DropRate: 1})
+	rpc.SetRetryPolicy("SumService.RPC_Sum", &rpc.RetryPolicy{MaxAttempts: 5, InitialBackoff: 20 * time.Millisecond})
+
+	//第一次请求被丢弃后删除规则，之后的重试成功
+	go func() {
+		for rpc.GetFaultStats()["drop"].Dropped == 0 {
+			time.Sleep(5 * time.Millisecond)
+		}
+		rpc.DelFaultRule("drop")
+	}()
+
+	if err = callSumWithTimeout(n1, 100*time.Millisecond); err != nil {
+		t.Fatalf("call should succeed by retry:%v", err)
+	}
+}
+
+// TestDelayedFrameOrder 延迟与抖动不改变同一方向帧的顺序
+func TestDelayedFrameOrder(t *testing.T) {
+	clearFaults(t)
+
+	const total = 50
+	h := NewHarness()
+	t.Cleanup(h.Close)
+
+	n1, err := h.AddNode(cluster.NodeInfo{NodeId: "node_1"}, nil, &CallerService{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	orderService := &OrderService{done: make(chan struct{}), total: total}
+	if _, err = h.AddNode(cluster.NodeInfo{NodeId: "node_2"}, nil, orderService); err != nil {
+		t.Fatal(err)
+	}
+	waitEvent(t, n1, MatchConnected("node_2"))
+
+	rule, err := rpc.ParseFaultRule("from=node_1,to=node_2,latency=20ms,jitter=30ms")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = rpc.SetFaultRule("delay", rule); err != nil {
+		t.Fatal(err)
+	}
+
+	handler := n1.GetService("CallerService").GetRpcHandler()
+	start := time.Now()
+	for seq := 0; seq < total; seq++ {
+		if err = handler.Go("OrderService.RPC_Record", &seq); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	select {
+	case <-orderService.done:
+	case <-time.After(waitTimeout):
+		t.Fatal("delayed frames are not delivered")
+	}
+	if time.Since(start) < rule.Latency {
+		t.Fatalf("frames are delivered in %s, latency is %s", time.Since(start), rule.Latency)
+	}
+
+	orderService.locker.Lock()
+	defer orderService.locker.Unlock()
+	if slices.IsSorted(orderService.seqList) == false {
+		t.Fatalf("frames are reordered:%v", orderService.seqList)
+	}
+	if stats := rpc.GetFaultStats()["delay"]; stats.Delayed != total || stats.Dropped != 0 {
+		t.Fatalf("fault stats = %+v", stats)
+	}
+}
@@ -0,0 +1,283 @@
+// Package clustertest 在一个进程中运行多个结点，用于测试跨结点的调用与服务发现。
+// 每个结点有独立的Cluster、ServiceManager与监听127.0.0.1的Rpc端口，结点之间通过内存中的服务发现互相发现，
+// 测试中可以断开与恢复结点间的连接、让结点退休，并等待结点收到的发现事件：
+//
+//	h := clustertest.NewHarness()
+//	defer h.Close()
+//	n1, err := h.AddNode(cluster.NodeInfo{NodeId: "node_1"}, nil, &TestService1{})
+//	n2, err := h.AddNode(cluster.NodeInfo{NodeId: "node_2"}, nil, &TestService2{})
+//	_, err = n1.WaitEvent(clustertest.MatchConnected("node_2"), 5*time.Second)
+//	err = n1.GetService("TestService1").GetRpcHandler().Call("TestService2.RPC_Sum", &req, &res)
+//	h.Disconnect("node_1", "node_2")
+//
+// Rpc调用策略、TLS等通过rpc包设置的配置在进程内共享，对所有结点生效。开启认证时ClusterName与Secret由所有结点共用，
+// 握手使用各结点自己的NodeId；IntrospectService只返回所在结点的服务
+package clustertest
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/duanhf2012/origin/v2/cluster"
+	"github.com/duanhf2012/origin/v2/service"
+	"github.com/duanhf2012/origin/v2/util/timer"
+)
+
+// RecorderServiceName 每个结点自动安装的事件记录服务
+const RecorderServiceName = "ClusterTestRecorder"
+
+var startTimerOnce sync.Once
+
+type linkKey struct {
+	nodeId1 string
+	nodeId2 string
+}
+
+func makeLinkKey(nodeId1 string, nodeId2 string) linkKey {
+	if nodeId1 > nodeId2 {
+		nodeId1, nodeId2 = nodeId2, nodeId1
+	}
+
+	return linkKey{nodeId1: nodeId1, nodeId2: nodeId2}
+}
+
+// Harness 管理进程内运行的所有结点
+type Harness struct {
+	locker     sync.Mutex
+	mapNode    map[string]*Node
+	nodeList   []*Node              //按加入顺序
+	mapCutLink map[linkKey]struct{} //被Disconnect断开的结点
+}
+
+func NewHarness() *Harness {
+	startTimerOnce.Do(func() {
+		timer.StartTimer(10*time.Millisecond, 1000000)
+	})
+
+	return &Harness{mapNode: map[string]*Node{}, mapCutLink: map[linkKey]struct{}{}}
+}
+
+// AddNode 启动结点并与已启动的结点互相发现。nodeInfo.ListenAddr为空时监听127.0.0.1的空闲端口，
+// ServiceList为空时按services的顺序生成，"_"开头的服务不对外公开。serviceCfg为map[serviceName]服务配置
+func (h *Harness) AddNode(nodeInfo cluster.NodeInfo, serviceCfg map[string]interface{}, services ...service.IService) (*Node, error) {
+	if nodeInfo.NodeId == "" {
+		return nil, errors.New("node id is empty")
+	}
+
+	h.locker.Lock()
+	_, ok := h.mapNode[nodeInfo.NodeId]
+	h.locker.Unlock()
+	if ok == true {
+		return nil, fmt.Errorf("node %s is already added", nodeInfo.NodeId)
+	}
+
+	for _, s := range services {
+		s.OnSetup(s)
+	}
+	if len(nodeInfo.ServiceList) == 0 {
+		for _, s := range services {
+			nodeInfo.ServiceList = append(nodeInfo.ServiceList, s.GetName())
+		}
+	}
+
+	if nodeInfo.ListenAddr == "" {
+		listenAddr, err := freeListenAddr()
+		if err != nil {
+			return nil, err
+		}
+		nodeInfo.ListenAddr = listenAddr
+	}
+
+	node := &Node{harness: h, serviceMgr: service.NewServiceManager()}
+	node.cls = cluster.NewCluster(node.serviceMgr)
+	node.eventCond = sync.NewCond(&node.eventLocker)
+	err := node.cls.InitNode(&nodeInfo, serviceCfg, &node.discovery)
+	if err != nil {
+		return nil, err
+	}
+	node.nodeInfo = *node.cls.GetLocalNodeInfo()
+
+	recorder := &recorderService{node: node}
+	recorder.SetName(RecorderServiceName)
+	for _, s := range append([]service.IService{recorder}, services...) {
+		if node.cls.InitService(s) == false {
+			return nil, fmt.Errorf("service %s is duplicated in node %s", s.GetName(), nodeInfo.NodeId)
+		}
+	}
+
+	err = node.serviceMgr.Init()
+	if err != nil {
+		return nil, err
+	}
+	node.serviceMgr.Start()
+	err = node.cls.Start()
+	if err != nil {
+		node.serviceMgr.StopAllService()
+		return nil, err
+	}
+
+	h.locker.Lock()
+	node.running = true
+	h.mapNode[nodeInfo.NodeId] = node
+	h.nodeList = append(h.nodeList, node)
+	peerList := h.linkedNodes(node)
+	h.locker.Unlock()
+
+	for _, peer := range peerList {
+		peer.discovery.setNode(node.getNodeInfo())
+		node.discovery.setNode(peer.getNodeInfo())
+	}
+
+	return node, nil
+}
+
+// GetNode 返回结点，不存在时返回nil
+func (h *Harness) GetNode(nodeId string) *Node {
+	h.locker.Lock()
+	defer h.locker.Unlock()
+
+	return h.mapNode[nodeId]
+}
+
+// Disconnect 让两个结点互相删除对方，直到调用Connect
+func (h *Harness) Disconnect(nodeId1 string, nodeId2 string) error {
+	node1, node2, err := h.getNodePair(nodeId1, nodeId2)
+	if err != nil {
+		return err
+	}
+
+	h.locker.Lock()
+	h.mapCutLink[makeLinkKey(nodeId1, nodeId2)] = struct{}{}
+	h.locker.Unlock()
+
+	node1.discovery.delNode(nodeId2)
+	node2.discovery.delNode(nodeId1)
+	return nil
+}
+
+// Connect 恢复被Disconnect断开的两个结点，两个结点重新互相发现
+func (h *Harness) Connect(nodeId1 string, nodeId2 string) error {
+	node1, node2, err := h.getNodePair(nodeId1, nodeId2)
+	if err != nil {
+		return err
+	}
+
+	h.locker.Lock()
+	delete(h.mapCutLink, makeLinkKey(nodeId1, nodeId2))
+	h.locker.Unlock()
+
+	node1.discovery.setNode(node2.getNodeInfo())
+	node2.discovery.setNode(node1.getNodeInfo())
+	return nil
+}
+
+// Retire 结点退休，其他结点收到Retire状态的结点信息，结点的所有服务收到OnRetire
+func (h *Harness) Retire(nodeId string) error {
+	node := h.GetNode(nodeId)
+	if node == nil {
+		return fmt.Errorf("cannot find node %s", nodeId)
+	}
+
+	h.locker.Lock()
+	node.nodeInfo.Retire = true
+	peerList := h.linkedNodes(node)
+	h.locker.Unlock()
+
+	for _, peer := range peerList {
+		peer.discovery.setNode(node.getNodeInfo())
+	}
+	node.serviceMgr.NotifyAllServiceRetire()
+
+	return nil
+}
+
+// StopNode 其他结点删除该结点后停止结点的服务与Rpc监听，停止的结点不能再启动
+func (h *Harness) StopNode(nodeId string) error {
+	node := h.GetNode(nodeId)
+	if node == nil {
+		return fmt.Errorf("cannot find node %s", nodeId)
+	}
+
+	h.locker.Lock()
+	if node.running == false {
+		h.locker.Unlock()
+		return nil
+	}
+	node.running = false
+	var peerList []*Node
+	for _, peer := range h.nodeList {
+		if peer != node && peer.running == true {
+			peerList = append(peerList, peer)
+		}
+	}
+	h.locker.Unlock()
+
+	for _, peer := range peerList {
+		peer.discovery.delNode(nodeId)
+		node.discovery.delNode(peer.nodeInfo.NodeId)
+	}
+
+	node.serviceMgr.StopAllService()
+	node.cls.Stop()
+	return nil
+}
+
+// Close 按加入的相反顺序停止所有结点
+func (h *Harness) Close() {
+	h.locker.Lock()
+	nodeList := append([]*Node{}, h.nodeList...)
+	h.locker.Unlock()
+
+	for i := len(nodeList) - 1; i >= 0; i-- {
+		h.StopNode(nodeList[i].nodeInfo.NodeId)
+	}
+}
+
+// linkedNodes 返回与node连通且运行中的其他结点，调用方需持有h.locker
+func (h *Harness) linkedNodes(node *Node) []*Node {
+	var peerList []*Node
+	for _, peer := range h.nodeList {
+		if peer == node || peer.running == false {
+			continue
+		}
+		if _, ok := h.mapCutLink[makeLinkKey(node.nodeInfo.NodeId, peer.nodeInfo.NodeId)]; ok == true {
+			continue
+		}
+		peerList = append(peerList, peer)
+	}
+
+	return peerList
+}
+
+func (h *Harness) getNodePair(nodeId1 string, nodeId2 string) (*Node, *Node, error) {
+	if nodeId1 == nodeId2 {
+		return nil, nil, errors.New("node ids are the same")
+	}
+
+	h.locker.Lock()
+	defer h.locker.Unlock()
+
+	node1, node2 := h.mapNode[nodeId1], h.mapNode[nodeId2]
+	if node1 == nil || node2 == nil {
+		return nil, nil, fmt.Errorf("cannot find node %s or %s", nodeId1, nodeId2)
+	}
+	if node1.running == false || node2.running == false {
+		return nil, nil, fmt.Errorf("node %s or %s is stopped", nodeId1, nodeId2)
+	}
+
+	return node1, node2, nil
+}
+
+// freeListenAddr 找出127.0.0.1上的空闲端口
+func freeListenAddr() (string, error) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return "", err
+	}
+	defer listener.Close()
+
+	return listener.Addr().String(), nil
+}
@@ -0,0 +1,268 @@
+package clustertest
+
+import (
+	"testing"
+	"time"
+
+	"github.com/duanhf2012/origin/v2/cluster"
+	"github.com/duanhf2012/origin/v2/rpc"
+	"github.com/duanhf2012/origin/v2/service"
+	"github.com/duanhf2012/origin/v2/sysservice/introspectservice"
+)
+
+const waitTimeout = 5 * time.Second
+
+type SumReq struct {
+	A int
+	B int
+}
+
+type CallerService struct {
+	service.Service
+}
+
+type SumService struct {
+	service.Service
+
+	retire chan struct{}
+}
+
+func newSumService() *SumService {
+	return &SumService{retire: make(chan struct{}, 1)}
+}
+
+func (ss *SumService) OnRetire() {
+	ss.retire <- struct{}{}
+}
+
+func (ss *SumService) RPC_Sum(req *SumReq, res *int) error {
+	*res = req.A + req.B
+	return nil
+}
+
+func waitEvent(t *testing.T, node *Node, match MatchEventFun) Event {
+	t.Helper()
+
+	ev, err := node.WaitEvent(match, waitTimeout)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return ev
+}
+
+func callSum(node *Node) (int, error) {
+	var res int
+	err := node.GetService("CallerService").GetRpcHandler().Call("SumService.RPC_Sum", &SumReq{A: 1, B: 2}, &res)
+	return res, err
+}
+
+func checkCallSum(t *testing.T, node *Node) {
+	t.Helper()
+
+	res, err := callSum(node)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res != 3 {
+		t.Fatalf("sum = %d, want 3", res)
+	}
+}
+
+// startNodes 启动调用方结点node_1与提供SumService的node_2，并等待两者连通
+func startNodes(t *testing.T) (*Harness, *Node, *Node, *SumService) {
+	t.Helper()
+
+	h := NewHarness()
+	t.Cleanup(h.Close)
+
+	n1, err := h.AddNode(cluster.NodeInfo{NodeId: "node_1"}, nil, &CallerService{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sumService := newSumService()
+	n2, err := h.AddNode(cluster.NodeInfo{NodeId: "node_2"}, nil, sumService)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	waitEvent(t, n1, MatchDiscovery("node_2", "SumService"))
+	waitEvent(t, n1, MatchConnected("node_2"))
+	return h, n1, n2, sumService
+}
+
+func TestAddNode(t *testing.T) {
+	h, n1, n2, _ := startNodes(t)
+
+	if _, err := h.AddNode(cluster.NodeInfo{NodeId: "node_2"}, nil); err == nil {
+		t.Fatal("node_2 should not be added twice")
+	}
+	if h.GetNode("node_1") != n1 || h.GetNode("node_2") != n2 {
+		t.Fatal("GetNode mismatch")
+	}
+	if n2.GetListenAddr() == "" || n2.GetService("SumService") == nil {
+		t.Fatal("node_2 is not started")
+	}
+
+	waitEvent(t, n2, MatchDiscovery("node_1", "CallerService"))
+	checkCallSum(t, n1)
+}
+
+func TestDisconnectConnect(t *testing.T) {
+	h, n1, _, _ := startNodes(t)
+	checkCallSum(t, n1)
+
+	n1.ClearEvents()
+	if err := h.Disconnect("node_1", "node_2"); err != nil {
+		t.Fatal(err)
+	}
+	waitEvent(t, n1, MatchUnDiscovery("node_2", "SumService"))
+	waitEvent(t, n1, MatchDisconnect("node_2"))
+	if n1.IsNodeConnected("node_2") == true {
+		t.Fatal("node_2 should be disconnected")
+	}
+	if _, err := callSum(n1); err == nil {
+		t.Fatal("call should fail after disconnect")
+	}
+
+	n1.ClearEvents()
+	if err := h.Connect("node_1", "node_2"); err != nil {
+		t.Fatal(err)
+	}
+	waitEvent(t, n1, MatchDiscovery("node_2", "SumService"))
+	waitEvent(t, n1, MatchConnected("node_2"))
+	checkCallSum(t, n1)
+}
+
+func TestRetire(t *testing.T) {
+	h, n1, _, sumService := startNodes(t)
+
+	if err := h.Retire("node_2"); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case <-sumService.retire:
+	case <-time.After(waitTimeout):
+		t.Fatal("OnRetire is not called")
+	}
+
+	deadline := time.Now().Add(waitTimeout)
+	for n1.GetCluster().IsNodeRetire("node_2") == false {
+		if time.Now().After(deadline) {
+			t.Fatal("node_1 does not know node_2 is retired")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	//只剩退休结点时仍然可以调用
+	checkCallSum(t, n1)
+}
+
+func TestStopNode(t *testing.T) {
+	h, n1, _, _ := startNodes(t)
+
+	n1.ClearEvents()
+	if err := h.StopNode("node_2"); err != nil {
+		t.Fatal(err)
+	}
+	waitEvent(t, n1, MatchUnDiscovery("node_2", "SumService"))
+	waitEvent(t, n1, MatchDisconnect("node_2"))
+	if _, err := callSum(n1); err == nil {
+		t.Fatal("call should fail after node_2 is stopped")
+	}
+
+	//停止的结点不能再连接
+	if err := h.Connect("node_1", "node_2"); err == nil {
+		t.Fatal("stopped node should not be connected")
+	}
+	if err := h.StopNode("node_2"); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// TestHandshakeMultiNode 开启认证时，同一进程的结点共用密钥，各自使用自己的结点Id握手
+func TestHandshakeMultiNode(t *testing.T) {
+	err := rpc.SetHandshakeConfig(&rpc.HandshakeConfig{ClusterName: "clustertest", Secret: "secret"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		rpc.SetHandshakeConfig(nil)
+	})
+
+	h := NewHarness()
+	t.Cleanup(h.Close)
+
+	nodeIdList := []string{"node_1", "node_2", "node_3"}
+	nodeList := make([]*Node, 0, len(nodeIdList))
+	for _, nodeId := range nodeIdList {
+		node, err := h.AddNode(cluster.NodeInfo{NodeId: nodeId}, nil, &CallerService{}, newSumService())
+		if err != nil {
+			t.Fatal(err)
+		}
+		nodeList = append(nodeList, node)
+	}
+
+	for _, node := range nodeList {
+		for _, peerNodeId := range nodeIdList {
+			if peerNodeId == node.GetNodeId() {
+				continue
+			}
+
+			waitEvent(t, node, MatchConnected(peerNodeId))
+			var res int
+			err = node.GetService("CallerService").GetRpcHandler().CallNode(peerNodeId, "SumService.RPC_Sum", &SumReq{A: 1, B: 2}, &res)
+			if err != nil {
+				t.Fatalf("%s call %s fail:%v", node.GetNodeId(), peerNodeId, err)
+			}
+		}
+	}
+}
+
+// TestIntrospectMultiNode 每个结点的IntrospectService只返回所在结点的服务
+func TestIntrospectMultiNode(t *testing.T) {
+	h := NewHarness()
+	t.Cleanup(h.Close)
+
+	n1, err := h.AddNode(cluster.NodeInfo{NodeId: "node_1"}, nil, &CallerService{}, &introspectservice.IntrospectService{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = h.AddNode(cluster.NodeInfo{NodeId: "node_2"}, nil, newSumService(), &introspectservice.IntrospectService{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	waitEvent(t, n1, MatchConnected("node_2"))
+
+	handler := n1.GetService("CallerService").GetRpcHandler()
+	tests := []struct {
+		nodeId      string
+		serviceName string
+		notExist    string
+	}{
+		{"node_1", "CallerService", "SumService"},
+		{"node_2", "SumService", "CallerService"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.nodeId, func(t *testing.T) {
+			var res introspectservice.ListServicesRes
+			err := handler.CallNode(tt.nodeId, "IntrospectService.RPC_ListServices", &introspectservice.ListServicesReq{}, &res)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if res.NodeId != tt.nodeId {
+				t.Fatalf("node id = %s, want %s", res.NodeId, tt.nodeId)
+			}
+
+			mapService := map[string]bool{}
+			for _, desc := range res.Services {
+				mapService[desc.Name] = true
+			}
+			if mapService[tt.serviceName] == false || mapService[tt.notExist] == true {
+				t.Fatalf("services of %s are %v", tt.nodeId, mapService)
+			}
+		})
+	}
+}
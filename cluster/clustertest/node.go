@@ -0,0 +1,239 @@
+package clustertest
+
+import (
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/duanhf2012/origin/v2/cluster"
+	"github.com/duanhf2012/origin/v2/service"
+)
+
+type EventType int
+
+const (
+	EventNodeConnected  EventType = 1 //与结点的Rpc连接建立
+	EventNodeDisconnect EventType = 2 //与结点的Rpc连接断开
+	EventDiscovery      EventType = 3 //发现结点的服务
+	EventUnDiscovery    EventType = 4 //结点的服务被删除
+	EventHashRingChange EventType = 5 //服务的一致性哈希环发生变化
+)
+
+func (eventType EventType) String() string {
+	switch eventType {
+	case EventNodeConnected:
+		return "NodeConnected"
+	case EventNodeDisconnect:
+		return "NodeDisconnect"
+	case EventDiscovery:
+		return "Discovery"
+	case EventUnDiscovery:
+		return "UnDiscovery"
+	case EventHashRingChange:
+		return "HashRingChange"
+	}
+
+	return "Unknown"
+}
+
+// Event 结点的服务收到的连接与发现事件
+type Event struct {
+	Type        EventType
+	NodeId      string   //对方结点，EventHashRingChange为空
+	ServiceName []string //EventDiscovery、EventUnDiscovery与EventHashRingChange涉及的服务
+	Time        time.Time
+}
+
+type MatchEventFun func(ev *Event) bool
+
+// MatchConnected 匹配与nodeId的连接建立事件
+func MatchConnected(nodeId string) MatchEventFun {
+	return func(ev *Event) bool {
+		return ev.Type == EventNodeConnected && ev.NodeId == nodeId
+	}
+}
+
+// MatchDisconnect 匹配与nodeId的连接断开事件
+func MatchDisconnect(nodeId string) MatchEventFun {
+	return func(ev *Event) bool {
+		return ev.Type == EventNodeDisconnect && ev.NodeId == nodeId
+	}
+}
+
+// MatchDiscovery 匹配发现nodeId的事件，serviceName不为空时事件需包含该服务
+func MatchDiscovery(nodeId string, serviceName string) MatchEventFun {
+	return func(ev *Event) bool {
+		return ev.Type == EventDiscovery && ev.NodeId == nodeId && (serviceName == "" || slices.Contains(ev.ServiceName, serviceName))
+	}
+}
+
+// MatchUnDiscovery 匹配删除nodeId的事件，serviceName不为空时事件需包含该服务
+func MatchUnDiscovery(nodeId string, serviceName string) MatchEventFun {
+	return func(ev *Event) bool {
+		return ev.Type == EventUnDiscovery && ev.NodeId == nodeId && (serviceName == "" || slices.Contains(ev.ServiceName, serviceName))
+	}
+}
+
+// Node 进程内运行的结点
+type Node struct {
+	harness    *Harness
+	nodeInfo   cluster.NodeInfo //发布给其他结点的信息
+	cls        *cluster.Cluster
+	serviceMgr *service.ServiceManager
+	discovery  memoryDiscovery
+	running    bool
+
+	eventLocker sync.Mutex
+	eventCond   *sync.Cond
+	eventList   []Event
+	eventGen    int //ClearEvents的次数
+}
+
+func (node *Node) GetNodeId() string {
+	return node.nodeInfo.NodeId
+}
+
+// GetListenAddr 返回结点的Rpc监听地址
+func (node *Node) GetListenAddr() string {
+	return node.nodeInfo.ListenAddr
+}
+
+func (node *Node) GetCluster() *cluster.Cluster {
+	return node.cls
+}
+
+func (node *Node) GetServiceManager() *service.ServiceManager {
+	return node.serviceMgr
+}
+
+// GetService 返回结点的服务，不存在时返回nil
+func (node *Node) GetService(serviceName string) service.IService {
+	return node.serviceMgr.GetService(serviceName)
+}
+
+func (node *Node) getNodeInfo() *cluster.NodeInfo {
+	node.harness.locker.Lock()
+	defer node.harness.locker.Unlock()
+
+	nodeInfo := node.nodeInfo
+	nodeInfo.ServiceList = append([]string{}, node.nodeInfo.ServiceList...)
+	nodeInfo.PublicServiceList = append([]string{}, node.nodeInfo.PublicServiceList...)
+	return &nodeInfo
+}
+
+// Events 返回结点目前收到的所有事件
+func (node *Node) Events() []Event {
+	node.eventLocker.Lock()
+	defer node.eventLocker.Unlock()
+
+	return append([]Event{}, node.eventList...)
+}
+
+// ClearEvents 清除已收到的事件，之后的WaitEvent只匹配新事件
+func (node *Node) ClearEvents() {
+	node.eventLocker.Lock()
+	node.eventList = nil
+	node.eventGen++
+	node.eventLocker.Unlock()
+}
+
+// WaitEvent 等待结点收到匹配的事件，已收到的事件也参与匹配
+func (node *Node) WaitEvent(match MatchEventFun, timeout time.Duration) (Event, error) {
+	deadline := time.Now().Add(timeout)
+	timer := time.AfterFunc(timeout, func() {
+		node.eventLocker.Lock()
+		node.eventCond.Broadcast()
+		node.eventLocker.Unlock()
+	})
+	defer timer.Stop()
+
+	node.eventLocker.Lock()
+	defer node.eventLocker.Unlock()
+
+	checked, gen := 0, node.eventGen
+	for {
+		for ; checked < len(node.eventList); checked++ {
+			if match(&node.eventList[checked]) == true {
+				return node.eventList[checked], nil
+			}
+		}
+
+		if time.Now().Before(deadline) == false {
+			return Event{}, fmt.Errorf("node %s wait event is timeout", node.nodeInfo.NodeId)
+		}
+		node.eventCond.Wait()
+		//ClearEvents后从头匹配
+		if gen != node.eventGen {
+			checked, gen = 0, node.eventGen
+		}
+	}
+}
+
+// IsNodeConnected 结点与nodeId的Rpc连接是否已建立
+func (node *Node) IsNodeConnected(nodeId string) bool {
+	return node.cls.IsNodeConnected(nodeId)
+}
+
+func (node *Node) pushEvent(ev Event) {
+	ev.Time = time.Now()
+
+	node.eventLocker.Lock()
+	node.eventList = append(node.eventList, ev)
+	node.eventCond.Broadcast()
+	node.eventLocker.Unlock()
+}
+
+// memoryDiscovery 由Harness通知结点的增加与删除
+type memoryDiscovery struct {
+	funDelNode cluster.FunDelNode
+	funSetNode cluster.FunSetNode
+}
+
+func (discovery *memoryDiscovery) InitDiscovery(localNodeId string, funDelNode cluster.FunDelNode, funSetNode cluster.FunSetNode) error {
+	discovery.funDelNode = funDelNode
+	discovery.funSetNode = funSetNode
+	return nil
+}
+
+func (discovery *memoryDiscovery) setNode(nodeInfo *cluster.NodeInfo) {
+	discovery.funSetNode(nodeInfo)
+}
+
+func (discovery *memoryDiscovery) delNode(nodeId string) {
+	discovery.funDelNode(nodeId)
+}
+
+// recorderService 记录结点收到的连接与发现事件
+type recorderService struct {
+	service.Service
+
+	node *Node
+}
+
+func (rs *recorderService) OnInit() error {
+	rs.RegNodeConnListener(rs)
+	rs.RegDiscoverListener(rs)
+	rs.RegHashRingListener(rs)
+	return nil
+}
+
+func (rs *recorderService) OnNodeConnected(nodeId string) {
+	rs.node.pushEvent(Event{Type: EventNodeConnected, NodeId: nodeId})
+}
+
+func (rs *recorderService) OnNodeDisconnect(nodeId string) {
+	rs.node.pushEvent(Event{Type: EventNodeDisconnect, NodeId: nodeId})
+}
+
+func (rs *recorderService) OnDiscoveryService(nodeId string, serviceName []string) {
+	rs.node.pushEvent(Event{Type: EventDiscovery, NodeId: nodeId, ServiceName: append([]string{}, serviceName...)})
+}
+
+func (rs *recorderService) OnUnDiscoveryService(nodeId string, serviceName []string) {
+	rs.node.pushEvent(Event{Type: EventUnDiscovery, NodeId: nodeId, ServiceName: append([]string{}, serviceName...)})
+}
+
+func (rs *recorderService) OnHashRingChange(serviceName []string) {
+	rs.node.pushEvent(Event{Type: EventHashRingChange, ServiceName: append([]string{}, serviceName...)})
+}
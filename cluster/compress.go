@@ -0,0 +1,74 @@
+package cluster
+
+import (
+	"fmt"
+
+	"github.com/duanhf2012/origin/v2/rpc"
+)
+
+// CompressPolicy 压缩策略，Codec不配置时使用本结点的CompressCodec，BytesLen为0时使用本结点的CompressBytesLen，小于0时不压缩
+type CompressPolicy struct {
+	Codec    string
+	BytesLen int
+}
+
+// Compress 按结点与RPC函数配置压缩策略，RPC函数的配置优先
+type Compress struct {
+	Node   map[string]CompressPolicy //map[NodeId]发往该结点的请求的压缩策略
+	Method map[string]CompressPolicy //map["Service.RPC_Method"]请求与返回的压缩策略
+}
+
+func (cls *Cluster) getCompressPolicy(cfg CompressPolicy, defaultCodec rpc.CodecId) (*rpc.CompressPolicy, error) {
+	policy := rpc.CompressPolicy{Codec: defaultCodec, BytesLen: cfg.BytesLen}
+	if cfg.Codec != "" {
+		codec, ok := rpc.GetCodecByName(cfg.Codec)
+		if ok == false {
+			return nil, fmt.Errorf("codec %s is not supported", cfg.Codec)
+		}
+		policy.Codec = codec
+	}
+
+	if cfg.BytesLen == 0 {
+		policy.BytesLen = cls.localNodeInfo.CompressBytesLen
+	}
+
+	return &policy, nil
+}
+
+func (cls *Cluster) setupCompress() error {
+	defaultCodec := rpc.CodecLz4
+	if cls.localNodeInfo.CompressCodec != "" {
+		codec, ok := rpc.GetCodecByName(cls.localNodeInfo.CompressCodec)
+		if ok == false {
+			return fmt.Errorf("CompressCodec %s is not supported", cls.localNodeInfo.CompressCodec)
+		}
+		defaultCodec = codec
+	}
+
+	err := rpc.SetDefaultCodec(defaultCodec)
+	if err != nil {
+		return err
+	}
+
+	if cls.compress == nil {
+		return nil
+	}
+
+	for nodeId, cfg := range cls.compress.Node {
+		policy, pErr := cls.getCompressPolicy(cfg, defaultCodec)
+		if pErr != nil {
+			return fmt.Errorf("Compress.Node %s config error:%s", nodeId, pErr.Error())
+		}
+		rpc.SetNodeCompressPolicy(nodeId, policy)
+	}
+
+	for serviceMethod, cfg := range cls.compress.Method {
+		policy, pErr := cls.getCompressPolicy(cfg, defaultCodec)
+		if pErr != nil {
+			return fmt.Errorf("Compress.Method %s config error:%s", serviceMethod, pErr.Error())
+		}
+		rpc.SetMethodCompressPolicy(serviceMethod, policy)
+	}
+
+	return nil
+}
@@ -0,0 +1,41 @@
+package cluster
+
+import (
+	"fmt"
+
+	"github.com/duanhf2012/origin/v2/log"
+	"github.com/duanhf2012/origin/v2/rpc"
+)
+
+// ConnPool 与其他结点的连接数配置，多条连接时调用按序号分散到各连接，按Key的调用使用固定的连接
+type ConnPool struct {
+	ConnNum int            //与每个结点的连接数，默认1
+	Node    map[string]int //map[nodeId]连接数，单独设置与指定结点的连接数
+}
+
+func (cls *Cluster) setupConnPool() error {
+	if cls.connPool == nil {
+		return nil
+	}
+
+	if cls.IsNatsMode() {
+		log.Warn("ConnPool config is ignored in nats mode")
+		return nil
+	}
+
+	if cls.connPool.ConnNum < 0 {
+		return fmt.Errorf("ConnPool config error:ConnNum %d is invalid", cls.connPool.ConnNum)
+	}
+	if cls.connPool.ConnNum > 0 {
+		rpc.SetDefaultConnNum(cls.connPool.ConnNum)
+	}
+
+	for nodeId, connNum := range cls.connPool.Node {
+		if connNum <= 0 {
+			return fmt.Errorf("ConnPool config error:ConnNum %d of node %s is invalid", connNum, nodeId)
+		}
+		rpc.SetNodeConnNum(nodeId, connNum)
+	}
+
+	return nil
+}
@@ -237,6 +237,8 @@ func (ed *EtcdDiscoveryService) marshalNodeInfo() error {
 	var nodeInfo rpc.NodeInfo
 	nodeInfo.NodeId = nInfo.NodeId
 	nodeInfo.ListenAddr = nInfo.ListenAddr
+	nodeInfo.HostId = nInfo.HostId
+	nodeInfo.UnixListenAddr = nInfo.UnixListenAddr
 	nodeInfo.Retire = ed.bRetire
 	nodeInfo.PublicServiceList = nInfo.PublicServiceList
 	nodeInfo.MaxRpcParamLen = nInfo.MaxRpcParamLen
@@ -271,6 +273,8 @@ func (ed *EtcdDiscoveryService) setNodeInfo(networkName string, nodeInfo *rpc.No
 	nInfo.PublicServiceList = discoverServiceSlice
 	nInfo.NodeId = nodeInfo.NodeId
 	nInfo.ListenAddr = nodeInfo.ListenAddr
+	nInfo.HostId = nodeInfo.HostId
+	nInfo.UnixListenAddr = nodeInfo.UnixListenAddr
 	nInfo.MaxRpcParamLen = nodeInfo.MaxRpcParamLen
 	nInfo.Retire = nodeInfo.Retire
 	nInfo.Private = nodeInfo.Private
@@ -0,0 +1,94 @@
+package cluster
+
+import (
+	"fmt"
+	"github.com/duanhf2012/origin/v2/service"
+	"github.com/duanhf2012/origin/v2/util/hash"
+	"strings"
+)
+
+// updateHashRing 按当前发现的结点重建服务的一致性哈希环，退休结点不参与Key的分配。调用方需要持有cls.locker写锁
+func (cls *Cluster) updateHashRing(serviceNameList ...string) {
+	var changeServiceList []string
+	for _, serviceName := range serviceNameList {
+		splitServiceName := strings.Split(serviceName, ":")
+		if len(splitServiceName) == 2 {
+			serviceName = splitServiceName[0]
+		}
+
+		var nodeIdList []string
+		for nodeId := range cls.mapServiceNode[serviceName] {
+			nodeRpc, ok := cls.mapRpc[nodeId]
+			if ok == false || nodeRpc.nodeInfo.Retire == true {
+				continue
+			}
+			nodeIdList = append(nodeIdList, nodeId)
+		}
+
+		ring := cls.mapServiceRing[serviceName]
+		if ring != nil && isSameMembers(ring.GetMembers(), nodeIdList) {
+			continue
+		}
+
+		if ring == nil && len(nodeIdList) == 0 {
+			continue
+		}
+
+		if len(nodeIdList) == 0 {
+			delete(cls.mapServiceRing, serviceName)
+		} else {
+			cls.mapServiceRing[serviceName] = hash.NewConsistentHash(hash.DefaultReplicas, nodeIdList...)
+		}
+		changeServiceList = append(changeServiceList, serviceName)
+	}
+
+	if len(changeServiceList) > 0 {
+		cls.TriggerHashRingEvent(changeServiceList)
+	}
+}
+
+func isSameMembers(sortedMembers []string, nodeIdList []string) bool {
+	if len(sortedMembers) != len(nodeIdList) {
+		return false
+	}
+
+	for _, nodeId := range nodeIdList {
+		found := false
+		for _, member := range sortedMembers {
+			if member == nodeId {
+				found = true
+				break
+			}
+		}
+
+		if found == false {
+			return false
+		}
+	}
+
+	return true
+}
+
+func (cls *Cluster) TriggerHashRingEvent(serviceName []string) {
+	var eventData service.HashRingChangeEvent
+	eventData.ServiceName = serviceName
+
+	cls.NotifyAllService(&eventData)
+}
+
+// GetNodeIdByKey 通过一致性哈希找出Key在服务中所属的结点
+func (cls *Cluster) GetNodeIdByKey(serviceName string, key string) (string, error) {
+	cls.locker.RLock()
+	defer cls.locker.RUnlock()
+
+	ring, ok := cls.mapServiceRing[serviceName]
+	if ok == false || ring.IsEmpty() {
+		return "", fmt.Errorf("cannot find service %s in hash ring", serviceName)
+	}
+
+	return ring.Get(key), nil
+}
+
+func GetNodeIdByKey(serviceName string, key string) (string, error) {
+	return GetCluster().GetNodeIdByKey(serviceName, key)
+}
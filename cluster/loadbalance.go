@@ -0,0 +1,41 @@
+package cluster
+
+import (
+	"fmt"
+	"github.com/duanhf2012/origin/v2/rpc"
+)
+
+// ServiceLoadBalance 单个服务的负载均衡配置
+type ServiceLoadBalance struct {
+	Type   string         //负载均衡策略:Random,RoundRobin,Weighted,LeastPending
+	Weight map[string]int //Weighted策略下各结点的权重,map[NodeId]权重,未配置的结点权重为1
+}
+
+// LoadBalance 服务部署在多个结点时，未指定结点的Call/AsyncCall/Go调用选择结点的策略
+type LoadBalance struct {
+	Default string                        //默认策略，不配置时为Random
+	Service map[string]ServiceLoadBalance //map[ServiceName]负载均衡配置
+}
+
+func (cls *Cluster) setupLoadBalance() error {
+	if cls.loadBalance == nil {
+		return nil
+	}
+
+	defaultSelector, err := rpc.NewSelector(cls.loadBalance.Default, nil)
+	if err != nil {
+		return fmt.Errorf("LoadBalance.Default config error:%s", err.Error())
+	}
+	rpc.SetDefaultSelector(defaultSelector)
+
+	for serviceName, cfg := range cls.loadBalance.Service {
+		selector, sErr := rpc.NewSelector(cfg.Type, cfg.Weight)
+		if sErr != nil {
+			return fmt.Errorf("LoadBalance.Service %s config error:%s", serviceName, sErr.Error())
+		}
+
+		rpc.SetServiceSelector(serviceName, selector)
+	}
+
+	return nil
+}
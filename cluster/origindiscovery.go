@@ -137,6 +137,8 @@ func (ds *OriginDiscoveryMaster) OnStart() {
 	localNodeInfo := cluster.GetLocalNodeInfo()
 	nodeInfo.NodeId = localNodeInfo.NodeId
 	nodeInfo.ListenAddr = localNodeInfo.ListenAddr
+	nodeInfo.HostId = localNodeInfo.HostId
+	nodeInfo.UnixListenAddr = localNodeInfo.UnixListenAddr
 	nodeInfo.PublicServiceList = localNodeInfo.PublicServiceList
 	nodeInfo.MaxRpcParamLen = localNodeInfo.MaxRpcParamLen
 	nodeInfo.Private = localNodeInfo.Private
@@ -249,6 +251,8 @@ func (ds *OriginDiscoveryMaster) RPC_RegServiceDiscover(req *rpc.RegServiceDisco
 	nodeInfo.ServiceList = req.NodeInfo.PublicServiceList
 	nodeInfo.PublicServiceList = req.NodeInfo.PublicServiceList
 	nodeInfo.ListenAddr = req.NodeInfo.ListenAddr
+	nodeInfo.HostId = req.NodeInfo.HostId
+	nodeInfo.UnixListenAddr = req.NodeInfo.UnixListenAddr
 	nodeInfo.MaxRpcParamLen = req.NodeInfo.MaxRpcParamLen
 	nodeInfo.Retire = req.NodeInfo.Retire
 
@@ -409,6 +413,8 @@ func (dc *OriginDiscoveryClient) RPC_SubServiceDiscover(req *rpc.SubscribeDiscov
 				nInfo = &rpc.NodeInfo{}
 				nInfo.NodeId = nodeInfo.NodeId
 				nInfo.ListenAddr = nodeInfo.ListenAddr
+				nInfo.HostId = nodeInfo.HostId
+				nInfo.UnixListenAddr = nodeInfo.UnixListenAddr
 				nInfo.MaxRpcParamLen = nodeInfo.MaxRpcParamLen
 				nInfo.Retire = nodeInfo.Retire
 				nInfo.Private = nodeInfo.Private
@@ -486,6 +492,8 @@ func (dc *OriginDiscoveryClient) OnRetire() {
 		nodeRetireReq.NodeInfo = &rpc.NodeInfo{}
 		nodeRetireReq.NodeInfo.NodeId = cluster.localNodeInfo.NodeId
 		nodeRetireReq.NodeInfo.ListenAddr = cluster.localNodeInfo.ListenAddr
+		nodeRetireReq.NodeInfo.HostId = cluster.localNodeInfo.HostId
+		nodeRetireReq.NodeInfo.UnixListenAddr = cluster.localNodeInfo.UnixListenAddr
 		nodeRetireReq.NodeInfo.MaxRpcParamLen = cluster.localNodeInfo.MaxRpcParamLen
 		nodeRetireReq.NodeInfo.PublicServiceList = cluster.localNodeInfo.PublicServiceList
 		nodeRetireReq.NodeInfo.Retire = dc.bRetire
@@ -517,6 +525,8 @@ func (dc *OriginDiscoveryClient) regServiceDiscover(nodeId string) {
 	req.NodeInfo = &rpc.NodeInfo{}
 	req.NodeInfo.NodeId = cluster.localNodeInfo.NodeId
 	req.NodeInfo.ListenAddr = cluster.localNodeInfo.ListenAddr
+	req.NodeInfo.HostId = cluster.localNodeInfo.HostId
+	req.NodeInfo.UnixListenAddr = cluster.localNodeInfo.UnixListenAddr
 	req.NodeInfo.MaxRpcParamLen = cluster.localNodeInfo.MaxRpcParamLen
 	req.NodeInfo.PublicServiceList = cluster.localNodeInfo.PublicServiceList
 	req.NodeInfo.Retire = dc.bRetire
@@ -562,6 +572,8 @@ func (dc *OriginDiscoveryClient) setNodeInfo(masterNodeId string, nodeInfo *rpc.
 	nInfo.PublicServiceList = discoverServiceSlice
 	nInfo.NodeId = nodeInfo.NodeId
 	nInfo.ListenAddr = nodeInfo.ListenAddr
+	nInfo.HostId = nodeInfo.HostId
+	nInfo.UnixListenAddr = nodeInfo.UnixListenAddr
 	nInfo.MaxRpcParamLen = nodeInfo.MaxRpcParamLen
 	nInfo.Retire = nodeInfo.Retire
 	nInfo.Private = nodeInfo.Private
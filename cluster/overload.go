@@ -0,0 +1,52 @@
+package cluster
+
+import (
+	"fmt"
+
+	"github.com/duanhf2012/origin/v2/event"
+	"github.com/duanhf2012/origin/v2/rpc"
+)
+
+// OverloadPolicy 服务事件队列的过载保护
+type OverloadPolicy struct {
+	HighWater      int   //队列长度达到该值时进入过载，拒绝新的Rpc请求
+	LowWater       int   //队列长度降到该值时恢复，不配置时为HighWater的一半
+	DropEventTypes []int //过载时丢弃的事件类型
+}
+
+// Overload 过载保护配置，Service中的配置优先于Default
+type Overload struct {
+	Default *OverloadPolicy           //所有服务的默认配置
+	Service map[string]OverloadPolicy //map[serviceName]配置
+}
+
+func (policy *OverloadPolicy) toConfig() *rpc.OverloadConfig {
+	cfg := rpc.OverloadConfig{HighWater: policy.HighWater, LowWater: policy.LowWater}
+	for _, eventType := range policy.DropEventTypes {
+		cfg.DropEventTypes = append(cfg.DropEventTypes, event.EventType(eventType))
+	}
+
+	return &cfg
+}
+
+func (cls *Cluster) setupOverload() error {
+	if cls.overload == nil {
+		return nil
+	}
+
+	if cls.overload.Default != nil {
+		err := rpc.SetDefaultOverloadConfig(cls.overload.Default.toConfig())
+		if err != nil {
+			return fmt.Errorf("Overload Default config error:%s", err.Error())
+		}
+	}
+
+	for serviceName, policy := range cls.overload.Service {
+		err := rpc.SetOverloadConfig(serviceName, policy.toConfig())
+		if err != nil {
+			return fmt.Errorf("Overload %s config error:%s", serviceName, err.Error())
+		}
+	}
+
+	return nil
+}
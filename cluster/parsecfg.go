@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"github.com/duanhf2012/origin/v2/log"
 	"github.com/duanhf2012/origin/v2/rpc"
+	"github.com/duanhf2012/origin/v2/util/hash"
 	jsoniter "github.com/json-iterator/go"
 	"gopkg.in/yaml.v3"
 	"os"
@@ -59,9 +60,17 @@ type RpcMode struct {
 }
 
 type NodeInfoList struct {
-	RpcMode   RpcMode
-	Discovery DiscoveryInfo
-	NodeList  []NodeInfo
+	RpcMode     RpcMode
+	Discovery   DiscoveryInfo
+	NodeList    []NodeInfo
+	LoadBalance *LoadBalance
+	RpcRetry    map[string]RpcRetryPolicy //map["Service.RPC_Method"或"Service"]重试策略
+	CircuitBreaker *CircuitBreaker
+	Compress       *Compress
+	Auth           *Auth
+	ConnPool       *ConnPool
+	Overload       *Overload
+	RateLimit      map[string]RateLimitPolicy //map["Service.RPC_Method"或"Service"]限流
 }
 
 func validConfigFile(f os.DirEntry) bool {
@@ -311,17 +320,101 @@ func (cls *Cluster) readLocalClusterConfig(nodeId string) (DiscoveryInfo, []Node
 	}
 
 	for i := range nodeInfoList {
-		for j, s := range nodeInfoList[i].ServiceList {
-			//私有结点不加入到Public服务列表中
-			if strings.HasPrefix(s, "_") == false && nodeInfoList[i].Private == false {
-				nodeInfoList[i].PublicServiceList = append(nodeInfoList[i].PublicServiceList, strings.TrimLeft(s, "_"))
-			} else {
-				nodeInfoList[i].ServiceList[j] = strings.TrimLeft(s, "_")
+		nodeInfoList[i].setupPublicServiceList()
+	}
+
+	return discoveryInfo, nodeInfoList, rpcMode, nil
+}
+
+// setupPublicServiceList 按ServiceList生成对外公开的服务列表，"_"开头的服务与私有结点的服务不公开
+func (nodeInfo *NodeInfo) setupPublicServiceList() {
+	for j, s := range nodeInfo.ServiceList {
+		//私有结点不加入到Public服务列表中
+		if strings.HasPrefix(s, "_") == false && nodeInfo.Private == false {
+			nodeInfo.PublicServiceList = append(nodeInfo.PublicServiceList, strings.TrimLeft(s, "_"))
+		} else {
+			nodeInfo.ServiceList[j] = strings.TrimLeft(s, "_")
+		}
+	}
+}
+
+// readLocalRpcPolicy 读取集群中Rpc调用策略相关的配置
+func (cls *Cluster) readLocalRpcPolicy() error {
+	clusterCfgPath := strings.TrimRight(configDir, "/") + "/cluster"
+	fileInfoList, err := os.ReadDir(clusterCfgPath)
+	if err != nil {
+		return fmt.Errorf("read dir %s is fail :%+v", clusterCfgPath, err)
+	}
+
+	for _, f := range fileInfoList {
+		if !validConfigFile(f) {
+			continue
+		}
+
+		filePath := strings.TrimRight(strings.TrimRight(clusterCfgPath, "/"), "\\") + "/" + f.Name()
+		fileNodeInfoList, rErr := cls.ReadClusterConfig(filePath)
+		if rErr != nil {
+			return fmt.Errorf("read file path %s is error:%+v", filePath, rErr)
+		}
+
+		if fileNodeInfoList.LoadBalance != nil {
+			if cls.loadBalance != nil {
+				return fmt.Errorf("LoadBalance does not allow repeated configuration in %s", f.Name())
+			}
+			cls.loadBalance = fileNodeInfoList.LoadBalance
+		}
+
+		if fileNodeInfoList.RpcRetry != nil {
+			if cls.rpcRetry != nil {
+				return fmt.Errorf("RpcRetry does not allow repeated configuration in %s", f.Name())
 			}
+			cls.rpcRetry = fileNodeInfoList.RpcRetry
+		}
+
+		if fileNodeInfoList.CircuitBreaker != nil {
+			if cls.circuitBreaker != nil {
+				return fmt.Errorf("CircuitBreaker does not allow repeated configuration in %s", f.Name())
+			}
+			cls.circuitBreaker = fileNodeInfoList.CircuitBreaker
+		}
+
+		if fileNodeInfoList.Compress != nil {
+			if cls.compress != nil {
+				return fmt.Errorf("Compress does not allow repeated configuration in %s", f.Name())
+			}
+			cls.compress = fileNodeInfoList.Compress
+		}
+
+		if fileNodeInfoList.Auth != nil {
+			if cls.auth != nil {
+				return fmt.Errorf("Auth does not allow repeated configuration in %s", f.Name())
+			}
+			cls.auth = fileNodeInfoList.Auth
+		}
+
+		if fileNodeInfoList.ConnPool != nil {
+			if cls.connPool != nil {
+				return fmt.Errorf("ConnPool does not allow repeated configuration in %s", f.Name())
+			}
+			cls.connPool = fileNodeInfoList.ConnPool
+		}
+
+		if fileNodeInfoList.Overload != nil {
+			if cls.overload != nil {
+				return fmt.Errorf("Overload does not allow repeated configuration in %s", f.Name())
+			}
+			cls.overload = fileNodeInfoList.Overload
+		}
+
+		if fileNodeInfoList.RateLimit != nil {
+			if cls.rateLimit != nil {
+				return fmt.Errorf("RateLimit does not allow repeated configuration in %s", f.Name())
+			}
+			cls.rateLimit = fileNodeInfoList.RateLimit
 		}
 	}
 
-	return discoveryInfo, nodeInfoList, rpcMode, nil
+	return nil
 }
 
 func (cls *Cluster) readLocalService(localNodeId string) error {
@@ -444,6 +537,7 @@ func (cls *Cluster) parseLocalCfg() error{
 		}
 		cls.mapServiceNode[serviceName][cls.localNodeInfo.NodeId] = struct{}{}
 	}
+	cls.updateHashRing(cls.localNodeInfo.ServiceList...)
 
 	return nil
 }
@@ -461,6 +555,7 @@ func (cls *Cluster) InitCfg(localNodeId string) error {
 	cls.mapRpc = map[string]*NodeRpcInfo{}
 	cls.mapServiceNode = map[string]map[string]struct{}{}
 	cls.mapTemplateServiceNode = map[string]map[string]struct{}{}
+	cls.mapServiceRing = map[string]*hash.ConsistentHash{}
 
 	//加载本地结点的NodeList配置
 	discoveryInfo, nodeInfoList, rpcMode, err := cls.readLocalClusterConfig(localNodeId)
@@ -471,12 +566,23 @@ func (cls *Cluster) InitCfg(localNodeId string) error {
 	cls.discoveryInfo = discoveryInfo
 	cls.rpcMode = rpcMode
 
+	err = cls.setupHostId()
+	if err != nil {
+		return err
+	}
+
 	//读取本地服务配置
 	err = cls.readLocalService(localNodeId)
 	if err != nil {
 		return err
 	}
 
+	//读取Rpc调用策略配置
+	err = cls.readLocalRpcPolicy()
+	if err != nil {
+		return err
+	}
+
 	//本地配置服务加到全局map信息中
 	return cls.parseLocalCfg()
 }
@@ -502,7 +608,7 @@ func (cls *Cluster) GetNodeIdByTemplateService(templateServiceName string, rpcCl
 		mapNodeId, ok := cls.mapServiceNode[serviceName]
 		if ok == true {
 			for nodeId := range mapNodeId {
-				pClient, retire := GetCluster().getRpcClient(nodeId)
+				pClient, retire := cls.getRpcClient(nodeId)
 				if pClient == nil || pClient.IsConnected() == false {
 					continue
 				}
@@ -526,7 +632,7 @@ func (cls *Cluster) GetNodeIdByService(serviceName string, rpcClientList []*rpc.
 	mapNodeId, ok := cls.mapServiceNode[serviceName]
 	if ok == true {
 		for nodeId := range mapNodeId {
-			pClient, retire := GetCluster().getRpcClient(nodeId)
+			pClient, retire := cls.getRpcClient(nodeId)
 			if pClient == nil || pClient.IsConnected() == false {
 				continue
 			}
@@ -0,0 +1,26 @@
+package cluster
+
+import (
+	"fmt"
+
+	"github.com/duanhf2012/origin/v2/rpc"
+)
+
+// RateLimitPolicy 本结点服务的令牌桶限流，超过限制的请求返回CodeRateLimited
+type RateLimitPolicy struct {
+	Rate      float64 //每秒允许的请求数
+	Burst     int     //允许的瞬时请求数，不配置时为Rate向上取整
+	PerCaller bool    //每个调用方结点单独计算，否则所有调用方共用
+}
+
+// setupRateLimit 限流设置在本结点的Server上，同一进程的结点互不影响
+func (cls *Cluster) setupRateLimit() error {
+	for serviceMethod, cfg := range cls.rateLimit {
+		err := cls.rpcServer.SetRateLimit(serviceMethod, &rpc.RateLimit{Rate: cfg.Rate, Burst: cfg.Burst, PerCaller: cfg.PerCaller})
+		if err != nil {
+			return fmt.Errorf("RateLimit %s config error", serviceMethod)
+		}
+	}
+
+	return nil
+}
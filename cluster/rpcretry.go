@@ -0,0 +1,39 @@
+package cluster
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/duanhf2012/origin/v2/rpc"
+)
+
+// RpcRetryPolicy 调用失败时的重试策略，只应配置幂等的RPC函数
+type RpcRetryPolicy struct {
+	MaxAttempts    int     //最大调用次数(包括第一次)，小于等于1时不重试
+	InitialBackoff int     //第一次重试前的等待时间(毫秒)，不配置时为100
+	MaxBackoff     int     //最大等待时间(毫秒)，不配置时为2000
+	Multiplier     float64 //每次重试等待时间的增长倍数，不配置时为2
+	RetryableCodes []int32 //可重试的错误码，不配置时为Disconnected,NoNode,Overload,Timeout
+}
+
+func (cls *Cluster) setupRpcRetry() error {
+	for serviceMethod, cfg := range cls.rpcRetry {
+		if cfg.MaxAttempts < 0 || cfg.InitialBackoff < 0 || cfg.MaxBackoff < 0 || cfg.Multiplier < 0 {
+			return fmt.Errorf("RpcRetry %s config error", serviceMethod)
+		}
+
+		policy := rpc.RetryPolicy{
+			MaxAttempts:    cfg.MaxAttempts,
+			InitialBackoff: time.Duration(cfg.InitialBackoff) * time.Millisecond,
+			MaxBackoff:     time.Duration(cfg.MaxBackoff) * time.Millisecond,
+			Multiplier:     cfg.Multiplier,
+		}
+		for _, code := range cfg.RetryableCodes {
+			policy.RetryableCodes = append(policy.RetryableCodes, rpc.ErrCode(code))
+		}
+
+		rpc.SetRetryPolicy(serviceMethod, &policy)
+	}
+
+	return nil
+}
@@ -0,0 +1,43 @@
+package cluster
+
+import (
+	"fmt"
+
+	"github.com/duanhf2012/origin/v2/log"
+	"github.com/duanhf2012/origin/v2/rpc"
+)
+
+// TLSConfig 本结点RPC连接的TLS配置，集群内所有结点需同时开启或关闭。证书轮换后调用rpc.ReloadTLS重新读取，
+// 结点身份校验通过rpc.SetVerifyPeerFun设置
+type TLSConfig struct {
+	CertFile   string //本结点证书
+	KeyFile    string //本结点私钥
+	CAFile     string //校验对端证书的CA，不配置时使用系统CA
+	ServerName string //校验服务端证书中的域名，不配置时只校验证书链
+	ClientAuth bool   //开启mTLS，服务端校验客户端证书
+}
+
+func (cls *Cluster) setupTLS() error {
+	tlsCfg := cls.localNodeInfo.TLS
+	if tlsCfg == nil {
+		return nil
+	}
+
+	if cls.IsNatsMode() {
+		log.Warn("TLS config is ignored in nats mode")
+		return nil
+	}
+
+	err := rpc.SetTLSConfig(&rpc.TLSConfig{
+		CertFile:   tlsCfg.CertFile,
+		KeyFile:    tlsCfg.KeyFile,
+		CAFile:     tlsCfg.CAFile,
+		ServerName: tlsCfg.ServerName,
+		ClientAuth: tlsCfg.ClientAuth,
+	})
+	if err != nil {
+		return fmt.Errorf("node %s TLS config error:%s", cls.localNodeInfo.NodeId, err.Error())
+	}
+
+	return nil
+}
@@ -0,0 +1,52 @@
+package cluster
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/duanhf2012/origin/v2/network"
+)
+
+// setupHostId 本结点未配置HostId时使用主机名
+func (cls *Cluster) setupHostId() error {
+	if netType, _ := network.ParseAddr(cls.localNodeInfo.UnixListenAddr); cls.localNodeInfo.UnixListenAddr != "" && netType != "unix" {
+		return fmt.Errorf("node %s UnixListenAddr %s must start with %s", cls.localNodeInfo.NodeId, cls.localNodeInfo.UnixListenAddr, network.UnixAddrPrefix)
+	}
+
+	if cls.localNodeInfo.HostId != "" {
+		return nil
+	}
+
+	hostName, err := os.Hostname()
+	if err != nil {
+		return fmt.Errorf("get host name fail:%s", err.Error())
+	}
+	cls.localNodeInfo.HostId = hostName
+
+	return nil
+}
+
+func (cls *Cluster) isSameHost(nodeInfo *NodeInfo) bool {
+	return nodeInfo.HostId != "" && nodeInfo.HostId == cls.localNodeInfo.HostId
+}
+
+// getRpcAddr 选择连接结点的地址，同一主机上的结点优先使用UnixListenAddr。
+// 结点只监听unix://地址且确定不在同一主机时返回false，未配置HostId的结点视为在同一主机
+func (cls *Cluster) getRpcAddr(nodeInfo *NodeInfo) (string, bool) {
+	if cls.IsNatsMode() {
+		return nodeInfo.ListenAddr, true
+	}
+
+	if cls.isSameHost(nodeInfo) == true {
+		if nodeInfo.UnixListenAddr != "" {
+			return nodeInfo.UnixListenAddr, true
+		}
+		return nodeInfo.ListenAddr, true
+	}
+
+	if netType, _ := network.ParseAddr(nodeInfo.ListenAddr); netType == "unix" && nodeInfo.HostId != "" {
+		return "", false
+	}
+
+	return nodeInfo.ListenAddr, true
+}
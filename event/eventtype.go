@@ -18,6 +18,7 @@ const (
 	Sys_Event_EtcdDiscovery   EventType = -11
 	Sys_Event_Gin_Event       EventType = -12
 	Sys_Event_FrameTick       EventType = -13
+	Sys_Event_HashRing_Change EventType = -14
 
 	Sys_Event_User_Define EventType = 1
 )
@@ -1,9 +1,11 @@
 package network
 
 import (
+	"crypto/tls"
 	"errors"
 	"github.com/duanhf2012/origin/v2/log"
 	"net"
+	"strings"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -21,6 +23,18 @@ type Conn interface {
 
 type ConnSet map[net.Conn]struct{}
 
+// UnixAddrPrefix 以该前缀开头的地址使用Unix domain socket，如unix:///tmp/node_1.sock
+const UnixAddrPrefix = "unix://"
+
+// ParseAddr 返回地址的网络类型(tcp或unix)与去掉前缀后的地址
+func ParseAddr(addr string) (string, string) {
+	if strings.HasPrefix(addr, UnixAddrPrefix) {
+		return "unix", addr[len(UnixAddrPrefix):]
+	}
+
+	return "tcp", addr
+}
+
 type NetConn struct {
 	sync.Mutex
 	conn      net.Conn
@@ -134,6 +148,16 @@ func (netConn *NetConn) RemoteAddr() net.Addr {
 	return netConn.conn.RemoteAddr()
 }
 
+// TLSConnectionState 返回TLS连接的状态，非TLS连接返回false
+func (netConn *NetConn) TLSConnectionState() (tls.ConnectionState, bool) {
+	tlsConn, ok := netConn.conn.(*tls.Conn)
+	if ok == false {
+		return tls.ConnectionState{}, false
+	}
+
+	return tlsConn.ConnectionState(), true
+}
+
 func (netConn *NetConn) ReadMsg() ([]byte, error) {
 	return netConn.msgParser.Read(netConn)
 }
@@ -1,6 +1,7 @@
 package network
 
 import (
+	"crypto/tls"
 	"github.com/duanhf2012/origin/v2/log"
 	"net"
 	"sync"
@@ -9,13 +10,14 @@ import (
 
 type TCPClient struct {
 	sync.Mutex
-	Addr            string
+	Addr            string //以unix://开头时连接Unix domain socket
 	ConnNum         int
 	ConnectInterval time.Duration
 	PendingWriteNum int
 	ReadDeadline    time.Duration
 	WriteDeadline   time.Duration
 	AutoReconnect   bool
+	TLSConfig       *tls.Config //不为nil时使用TLS，握手失败按连接失败处理
 	NewAgent        func(conn *NetConn) Agent
 	cons            ConnSet
 	wg              sync.WaitGroup
@@ -93,13 +95,23 @@ func (client *TCPClient) GetCloseFlag() bool {
 }
 
 func (client *TCPClient) dial() net.Conn {
+	netType, address := ParseAddr(client.Addr)
 	for {
-		conn, err := net.Dial("tcp", client.Addr)
-		if client.closeFlag {
+		conn, err := net.Dial(netType, address)
+		if client.GetCloseFlag() {
 			return conn
 		} else if err == nil && conn != nil {
-			conn.(*net.TCPConn).SetNoDelay(true)
-			return conn
+			if tcpConn, ok := conn.(*net.TCPConn); ok == true {
+				tcpConn.SetNoDelay(true)
+			}
+			if client.TLSConfig == nil {
+				return conn
+			}
+
+			conn, err = client.handshake(conn)
+			if err == nil {
+				return conn
+			}
 		}
 
 		log.Warn("connect error ", log.String("error", err.Error()), log.String("Addr", client.Addr))
@@ -108,6 +120,20 @@ func (client *TCPClient) dial() net.Conn {
 	}
 }
 
+// handshake 完成TLS握手，握手时间不超过WriteDeadline
+func (client *TCPClient) handshake(conn net.Conn) (net.Conn, error) {
+	tlsConn := tls.Client(conn, client.TLSConfig)
+	tlsConn.SetDeadline(time.Now().Add(client.WriteDeadline))
+	err := tlsConn.Handshake()
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	tlsConn.SetDeadline(time.Time{})
+
+	return tlsConn, nil
+}
+
 func (client *TCPClient) connect() {
 	defer client.wg.Done()
 
@@ -1,11 +1,13 @@
 package network
 
 import (
+	"crypto/tls"
 	"errors"
 	"fmt"
 	"github.com/duanhf2012/origin/v2/log"
 	"github.com/duanhf2012/origin/v2/util/bytespool"
 	"net"
+	"os"
 	"sync"
 	"time"
 )
@@ -21,11 +23,12 @@ const (
 )
 
 type TCPServer struct {
-	Addr            string
+	Addr            string //以unix://开头时监听Unix domain socket
 	MaxConnNum      int
 	PendingWriteNum int
 	ReadDeadline    time.Duration
 	WriteDeadline   time.Duration
+	TLSConfig       *tls.Config //不为nil时使用TLS，握手在首次读写时进行
 
 	NewAgent   func(conn Conn) Agent
 	ln         net.Listener
@@ -50,9 +53,17 @@ func (server *TCPServer) Start() error {
 }
 
 func (server *TCPServer) init() error {
-	ln, err := net.Listen("tcp", server.Addr)
+	netType, address := ParseAddr(server.Addr)
+	if netType == "unix" {
+		err := removeStaleSocket(address)
+		if err != nil {
+			return err
+		}
+	}
+
+	ln, err := net.Listen(netType, address)
 	if err != nil {
-		return fmt.Errorf("listen tcp fail,error:%s", err.Error())
+		return fmt.Errorf("listen %s fail,error:%s", netType, err.Error())
 	}
 
 	if server.MaxConnNum <= 0 {
@@ -107,6 +118,26 @@ func (server *TCPServer) init() error {
 	return nil
 }
 
+// removeStaleSocket 删除进程异常退出后残留的socket文件，仍有进程在监听时返回错误
+func removeStaleSocket(path string) error {
+	fileInfo, err := os.Stat(path)
+	if err != nil {
+		return nil
+	}
+
+	if fileInfo.Mode()&os.ModeSocket == 0 {
+		return fmt.Errorf("%s is not a socket file", path)
+	}
+
+	conn, err := net.Dial("unix", path)
+	if err == nil {
+		conn.Close()
+		return fmt.Errorf("%s is in use", path)
+	}
+
+	return os.Remove(path)
+}
+
 func (server *TCPServer) SetNetMemPool(memPool bytespool.IBytesMemPool) {
 	server.IBytesMemPool = memPool
 }
@@ -138,9 +169,14 @@ func (server *TCPServer) run() {
 			return
 		}
 
-		conn.(*net.TCPConn).SetLinger(0)
-		conn.(*net.TCPConn).SetNoDelay(true)
+		if tcpConn, ok := conn.(*net.TCPConn); ok == true {
+			tcpConn.SetLinger(0)
+			tcpConn.SetNoDelay(true)
+		}
 		tempDelay = 0
+		if server.TLSConfig != nil {
+			conn = tls.Server(conn, server.TLSConfig)
+		}
 
 		server.mutexConns.Lock()
 		if len(server.conns) >= server.MaxConnNum {
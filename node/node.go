@@ -7,6 +7,7 @@ import (
 	"github.com/duanhf2012/origin/v2/console"
 	"github.com/duanhf2012/origin/v2/log"
 	"github.com/duanhf2012/origin/v2/profiler"
+	"github.com/duanhf2012/origin/v2/rpc"
 	"github.com/duanhf2012/origin/v2/service"
 	"github.com/duanhf2012/origin/v2/util/buildtime"
 	"github.com/duanhf2012/origin/v2/util/sysprocess"
@@ -60,6 +61,7 @@ func init() {
 	console.RegisterCommandString("logpath", "", "<-logpath path> Set log file path.", setLogPath)
 	console.RegisterCommandInt("logsize", 0, "<-logsize size> Set log size(MB).", setLogSize)
 	console.RegisterCommandString("pprof", "", "<-pprof ip:port> Open performance analysis.", setPprof)
+	console.RegisterCommandString("fault", "", "<-fault to=nodeid,latency=50ms,drop=0.1[;rule...]> Inject rpc network faults for testing.", setFault)
 }
 
 func notifyAllServiceRetire() {
@@ -102,6 +104,35 @@ func setPprof(val interface{}) error {
 	return nil
 }
 
+// setFault 按;分隔设置多条故障规则，规则名为fault1、fault2...
+func setFault(val interface{}) error {
+	faultRules := val.(string)
+	if faultRules == "" {
+		return nil
+	}
+
+	//空规则不占用编号
+	ruleNum := 0
+	for _, s := range strings.Split(faultRules, ";") {
+		if strings.TrimSpace(s) == "" {
+			continue
+		}
+
+		rule, err := rpc.ParseFaultRule(s)
+		if err != nil {
+			return err
+		}
+
+		ruleNum++
+		err = rpc.SetFaultRule(fmt.Sprintf("fault%d", ruleNum), rule)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
 func setConfigPath(val interface{}) error {
 	configPath := val.(string)
 	if configPath == "" {
@@ -0,0 +1,282 @@
+package rpc
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+type BreakerState int32
+
+const (
+	BreakerClosed   BreakerState = 0 //正常调用
+	BreakerOpen     BreakerState = 1 //熔断中，调用直接返回CodeCircuitOpen
+	BreakerHalfOpen BreakerState = 2 //熔断时间已过，只放行少量探测调用
+)
+
+func (state BreakerState) String() string {
+	switch state {
+	case BreakerClosed:
+		return "Closed"
+	case BreakerOpen:
+		return "Open"
+	case BreakerHalfOpen:
+		return "HalfOpen"
+	}
+
+	return "Unknown"
+}
+
+// BreakerConfig 结点熔断配置，统计窗口内调用数不少于MinRequests且失败比例达到FailureRatio时熔断
+type BreakerConfig struct {
+	Window           time.Duration //统计窗口，窗口结束后重新计数
+	MinRequests      int           //窗口内至少有多少次调用结果才进行判断
+	FailureRatio     float64       //失败比例
+	OpenTimeout      time.Duration //熔断持续时间，之后进入HalfOpen
+	HalfOpenMaxCalls int           //HalfOpen时允许同时进行的探测调用数，全部成功后恢复
+	FailureCodes     []ErrCode     //计为失败的错误码，其他错误(如业务错误)计为成功
+}
+
+// DefaultBreakerConfig SetBreakerConfig中未设置的项使用的默认值
+var DefaultBreakerConfig = BreakerConfig{
+	Window:           10 * time.Second,
+	MinRequests:      20,
+	FailureRatio:     0.5,
+	OpenTimeout:      5 * time.Second,
+	HalfOpenMaxCalls: 1,
+	FailureCodes:     []ErrCode{CodeTimeout, CodeOverload},
+}
+
+// breakerConfig 为nil时不熔断，默认关闭
+var breakerConfig atomic.Pointer[BreakerConfig]
+
+// breakerNow 熔断器使用的时钟，测试中可以替换
+var breakerNow = time.Now
+
+// SetBreakerConfig 设置所有结点的熔断配置，cfg为nil时关闭熔断，默认关闭
+func SetBreakerConfig(cfg *BreakerConfig) {
+	if cfg == nil {
+		breakerConfig.Store(nil)
+		return
+	}
+
+	c := *cfg
+	if c.Window <= 0 {
+		c.Window = DefaultBreakerConfig.Window
+	}
+	if c.MinRequests <= 0 {
+		c.MinRequests = DefaultBreakerConfig.MinRequests
+	}
+	if c.FailureRatio <= 0 || c.FailureRatio > 1 {
+		c.FailureRatio = DefaultBreakerConfig.FailureRatio
+	}
+	if c.OpenTimeout <= 0 {
+		c.OpenTimeout = DefaultBreakerConfig.OpenTimeout
+	}
+	if c.HalfOpenMaxCalls <= 0 {
+		c.HalfOpenMaxCalls = DefaultBreakerConfig.HalfOpenMaxCalls
+	}
+	if len(c.FailureCodes) == 0 {
+		c.FailureCodes = DefaultBreakerConfig.FailureCodes
+	}
+	breakerConfig.Store(&c)
+}
+
+func getBreakerConfig() *BreakerConfig {
+	return breakerConfig.Load()
+}
+
+// CircuitBreaker 每个跨结点Client的熔断器，结点存活但处理不过来(超时、队列已满)时快速失败，避免调用堆积
+type CircuitBreaker struct {
+	locker     sync.Mutex
+	state      BreakerState
+	generation uint64 //状态或统计窗口变化时递增，旧的调用结果不再计入
+
+	windowEnd time.Time
+	requests  int
+	failures  int
+
+	openUntil time.Time
+	probing   int //HalfOpen时正在进行的探测调用数
+	successes int //HalfOpen时探测成功数
+}
+
+func (cb *CircuitBreaker) setState(state BreakerState, now time.Time, cfg *BreakerConfig) {
+	cb.state = state
+	cb.generation++
+	cb.requests = 0
+	cb.failures = 0
+	cb.probing = 0
+	cb.successes = 0
+	cb.windowEnd = now.Add(cfg.Window)
+	if state == BreakerOpen {
+		cb.openUntil = now.Add(cfg.OpenTimeout)
+	}
+}
+
+// currentState 更新到期的状态，调用前需加锁
+func (cb *CircuitBreaker) currentState(now time.Time, cfg *BreakerConfig) BreakerState {
+	switch cb.state {
+	case BreakerClosed:
+		if now.After(cb.windowEnd) {
+			cb.setState(BreakerClosed, now, cfg)
+		}
+	case BreakerOpen:
+		if now.After(cb.openUntil) {
+			cb.setState(BreakerHalfOpen, now, cfg)
+		}
+	}
+
+	return cb.state
+}
+
+// allow 发起需要返回的调用前检查，返回调用结果需要计入的generation
+func (cb *CircuitBreaker) allow() (uint64, bool) {
+	cfg := getBreakerConfig()
+	if cb == nil || cfg == nil {
+		return 0, true
+	}
+
+	cb.locker.Lock()
+	defer cb.locker.Unlock()
+
+	switch cb.currentState(breakerNow(), cfg) {
+	case BreakerOpen:
+		return 0, false
+	case BreakerHalfOpen:
+		if cb.probing >= cfg.HalfOpenMaxCalls {
+			return 0, false
+		}
+		cb.probing++
+	}
+
+	return cb.generation, true
+}
+
+// allowNoReply 不需要返回的调用不计入统计，只在Closed时放行
+func (cb *CircuitBreaker) allowNoReply() bool {
+	cfg := getBreakerConfig()
+	if cb == nil || cfg == nil {
+		return true
+	}
+
+	cb.locker.Lock()
+	defer cb.locker.Unlock()
+
+	return cb.currentState(breakerNow(), cfg) == BreakerClosed
+}
+
+// available 选择结点时判断是否可以调用
+func (cb *CircuitBreaker) available() bool {
+	cfg := getBreakerConfig()
+	if cb == nil || cfg == nil {
+		return true
+	}
+
+	cb.locker.Lock()
+	defer cb.locker.Unlock()
+
+	switch cb.currentState(breakerNow(), cfg) {
+	case BreakerOpen:
+		return false
+	case BreakerHalfOpen:
+		return cb.probing < cfg.HalfOpenMaxCalls
+	}
+
+	return true
+}
+
+// onResult 记录调用结果，neutral为true时(如调用被取消)只释放探测名额
+func (cb *CircuitBreaker) onResult(generation uint64, err error, neutral bool) {
+	cfg := getBreakerConfig()
+	if cb == nil || cfg == nil {
+		return
+	}
+
+	cb.locker.Lock()
+	defer cb.locker.Unlock()
+
+	now := breakerNow()
+	state := cb.currentState(now, cfg)
+	if generation != cb.generation {
+		return
+	}
+
+	failure := false
+	if err != nil && neutral == false {
+		code := Code(err)
+		for _, c := range cfg.FailureCodes {
+			if c == code {
+				failure = true
+				break
+			}
+		}
+	}
+
+	switch state {
+	case BreakerClosed:
+		if neutral == true {
+			return
+		}
+
+		cb.requests++
+		if failure == true {
+			cb.failures++
+		}
+		if cb.requests >= cfg.MinRequests && float64(cb.failures) >= float64(cb.requests)*cfg.FailureRatio {
+			cb.setState(BreakerOpen, now, cfg)
+		}
+	case BreakerHalfOpen:
+		cb.probing--
+		if neutral == true {
+			return
+		}
+
+		if failure == true {
+			cb.setState(BreakerOpen, now, cfg)
+			return
+		}
+
+		cb.successes++
+		if cb.successes >= cfg.HalfOpenMaxCalls {
+			cb.setState(BreakerClosed, now, cfg)
+		}
+	}
+}
+
+func (cb *CircuitBreaker) getState() BreakerState {
+	cfg := getBreakerConfig()
+	if cb == nil || cfg == nil {
+		return BreakerClosed
+	}
+
+	cb.locker.Lock()
+	defer cb.locker.Unlock()
+
+	return cb.currentState(breakerNow(), cfg)
+}
+
+// GetBreakerState 获取结点的熔断状态，本结点的Client没有熔断器，总是Closed
+func (client *Client) GetBreakerState() BreakerState {
+	return client.breaker.getState()
+}
+
+// bindBreaker 调用记录熔断器，结果返回时计入
+func (call *Call) bindBreaker(breaker *CircuitBreaker, generation uint64) {
+	call.breaker = breaker
+	call.breakerGen = generation
+}
+
+// reportBreaker 调用结束时将结果计入熔断器，只计一次
+func (call *Call) reportBreaker(err error, neutral bool) {
+	if call.breaker == nil {
+		return
+	}
+
+	call.breaker.onResult(call.breakerGen, err, neutral)
+	call.breaker = nil
+}
+
+func circuitOpenError(nodeId string, serviceMethod string) error {
+	return NewError(CodeCircuitOpen, "node "+nodeId+" circuit breaker is open,call "+serviceMethod)
+}
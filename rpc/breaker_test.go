@@ -0,0 +1,149 @@
+package rpc
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+// breakerClock 替换熔断器的时钟，测试结束后恢复配置与时钟
+func breakerClock(t *testing.T, cfg *BreakerConfig) *time.Time {
+	now := time.Unix(1700000000, 0)
+	oldNow := breakerNow
+	breakerNow = func() time.Time { return now }
+	SetBreakerConfig(cfg)
+	t.Cleanup(func() {
+		breakerNow = oldNow
+		SetBreakerConfig(nil)
+	})
+
+	return &now
+}
+
+type breakerStep struct {
+	name    string
+	advance time.Duration //执行前时钟前进的时间
+	do      func(t *testing.T, cb *CircuitBreaker)
+	state   BreakerState //执行后的状态
+}
+
+func report(cb *CircuitBreaker, results ...error) {
+	for _, err := range results {
+		generation, ok := cb.allow()
+		if ok == false {
+			panic("call is not allowed")
+		}
+		cb.onResult(generation, err, false)
+	}
+}
+
+func expectAllow(want bool) func(t *testing.T, cb *CircuitBreaker) {
+	return func(t *testing.T, cb *CircuitBreaker) {
+		t.Helper()
+		if _, ok := cb.allow(); ok != want {
+			t.Fatalf("allow = %v, want %v", ok, want)
+		}
+	}
+}
+
+func TestCircuitBreaker(t *testing.T) {
+	cfg := &BreakerConfig{Window: 10 * time.Second, MinRequests: 4, FailureRatio: 0.5, OpenTimeout: 5 * time.Second, HalfOpenMaxCalls: 1}
+	errBusiness := NewError(CodeBusiness, "business error")
+	var staleGeneration uint64
+
+	tests := []struct {
+		name  string
+		steps []breakerStep
+	}{
+		{"business errors do not open", []breakerStep{
+			{"report", 0, func(t *testing.T, cb *CircuitBreaker) { report(cb, errBusiness, errBusiness, errBusiness, errBusiness) }, BreakerClosed},
+		}},
+		{"below min requests", []breakerStep{
+			{"report", 0, func(t *testing.T, cb *CircuitBreaker) { report(cb, ErrTimeout, ErrTimeout, ErrTimeout) }, BreakerClosed},
+		}},
+		{"window reset", []breakerStep{
+			{"report", 0, func(t *testing.T, cb *CircuitBreaker) { report(cb, ErrTimeout, ErrTimeout, ErrTimeout) }, BreakerClosed},
+			{"next window", 11 * time.Second, func(t *testing.T, cb *CircuitBreaker) { report(cb, ErrTimeout) }, BreakerClosed},
+		}},
+		{"open and recover", []breakerStep{
+			{"open", 0, func(t *testing.T, cb *CircuitBreaker) { report(cb, nil, nil, ErrTimeout, ErrOverload) }, BreakerOpen},
+			{"reject", time.Second, expectAllow(false), BreakerOpen},
+			{"reject no reply", 0, func(t *testing.T, cb *CircuitBreaker) {
+				if cb.allowNoReply() == true || cb.available() == true {
+					t.Fatal("open breaker should reject calls")
+				}
+			}, BreakerOpen},
+			{"half open", 5 * time.Second, func(t *testing.T, cb *CircuitBreaker) {
+				if cb.allowNoReply() == true {
+					t.Fatal("half open breaker should reject calls without reply")
+				}
+				if cb.available() == false {
+					t.Fatal("half open breaker should be available")
+				}
+			}, BreakerHalfOpen},
+			{"probe success", 0, func(t *testing.T, cb *CircuitBreaker) { report(cb, nil) }, BreakerClosed},
+			{"closed", 0, expectAllow(true), BreakerClosed},
+		}},
+		{"probe failure", []breakerStep{
+			{"open", 0, func(t *testing.T, cb *CircuitBreaker) { report(cb, ErrTimeout, ErrTimeout, ErrTimeout, ErrTimeout) }, BreakerOpen},
+			{"probe failure", 6 * time.Second, func(t *testing.T, cb *CircuitBreaker) { report(cb, ErrTimeout) }, BreakerOpen},
+			{"reject", 0, expectAllow(false), BreakerOpen},
+		}},
+		{"probe limit", []breakerStep{
+			{"open", 0, func(t *testing.T, cb *CircuitBreaker) { report(cb, ErrTimeout, ErrTimeout, ErrTimeout, ErrTimeout) }, BreakerOpen},
+			{"probe", 6 * time.Second, func(t *testing.T, cb *CircuitBreaker) {
+				generation, ok := cb.allow()
+				if ok == false {
+					t.Fatal("probe should be allowed")
+				}
+				if _, ok = cb.allow(); ok == true || cb.available() == true {
+					t.Fatal("only one probe is allowed")
+				}
+				//取消的探测只释放名额
+				cb.onResult(generation, ErrCanceled, true)
+			}, BreakerHalfOpen},
+			{"probe again", 0, expectAllow(true), BreakerHalfOpen},
+		}},
+		{"stale result", []breakerStep{
+			{"open", 0, func(t *testing.T, cb *CircuitBreaker) {
+				staleGeneration, _ = cb.allow()
+				report(cb, ErrTimeout, ErrTimeout, ErrTimeout, ErrTimeout)
+			}, BreakerOpen},
+			//熔断前发出的调用在HalfOpen时返回，不计入探测结果
+			{"stale success", 6 * time.Second, func(t *testing.T, cb *CircuitBreaker) { cb.onResult(staleGeneration, nil, false) }, BreakerHalfOpen},
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			now := breakerClock(t, cfg)
+			cb := &CircuitBreaker{}
+			for _, step := range tt.steps {
+				*now = now.Add(step.advance)
+				step.do(t, cb)
+				if state := cb.getState(); state != step.state {
+					t.Fatalf("%s: state = %s, want %s", step.name, state, step.state)
+				}
+			}
+		})
+	}
+}
+
+func TestCircuitBreakerDisabled(t *testing.T) {
+	if getBreakerConfig() != nil {
+		t.Fatal("breaker should be disabled by default")
+	}
+	breakerClock(t, nil)
+
+	cb := &CircuitBreaker{}
+	report(cb, ErrTimeout, ErrTimeout, ErrTimeout, ErrTimeout, ErrTimeout)
+	if cb.available() == false || cb.allowNoReply() == false || cb.getState() != BreakerClosed {
+		t.Fatal("breaker is disabled by default")
+	}
+
+	var nilBreaker *CircuitBreaker
+	if _, ok := nilBreaker.allow(); ok == false {
+		t.Fatal("local client has no breaker")
+	}
+	nilBreaker.onResult(0, errors.New("err"), false)
+}
@@ -1,8 +1,6 @@
 package rpc
 
 import (
-	"errors"
-
 	"github.com/duanhf2012/origin/v2/log"
 	"strconv"
 	"sync"
@@ -14,21 +12,49 @@ type CallSet struct {
 	pendingLock          sync.RWMutex
 	startSeq             uint64
 	pending              map[uint64]*Call
+	mapNodePending       map[string]int //map[NodeId]等待返回的调用数
 	callRpcTimeout       time.Duration
 	maxCheckCallRpcCount int
+	localNodeId          string //发出请求的结点Id，为空时使用SetLocalNodeId设置的Id
 
 	callTimerHeap CallTimerHeap
+	closeCh       chan struct{}
 }
 
 func (cs *CallSet) Init() {
 	cs.pendingLock.Lock()
 	cs.callTimerHeap.Init()
 	cs.pending = make(map[uint64]*Call, 4096)
+	cs.mapNodePending = make(map[string]int, 32)
 
 	cs.maxCheckCallRpcCount = DefaultMaxCheckCallRpcCount
 	cs.callRpcTimeout = DefaultRpcTimeout
 
-	go cs.checkRpcCallTimeout()
+	cs.closeCh = make(chan struct{})
+	go cs.checkRpcCallTimeout(cs.closeCh)
+	cs.pendingLock.Unlock()
+}
+
+// SetLocalNodeId 设置通过该CallSet发出请求的结点Id，同一进程运行多个结点时使用
+func (cs *CallSet) SetLocalNodeId(nodeId string) {
+	cs.localNodeId = nodeId
+}
+
+func (cs *CallSet) getLocalNodeId() string {
+	if cs.localNodeId != "" {
+		return cs.localNodeId
+	}
+
+	return localNodeId
+}
+
+// StopCheckTimeout 停止超时检查，同一进程运行多个结点时停止结点后调用
+func (cs *CallSet) StopCheckTimeout() {
+	cs.pendingLock.Lock()
+	if cs.closeCh != nil {
+		close(cs.closeCh)
+		cs.closeCh = nil
+	}
 	cs.pendingLock.Unlock()
 }
 
@@ -40,9 +66,17 @@ func (cs *CallSet) makeCallFail(call *Call) {
 	}
 }
 
-func (cs *CallSet) checkRpcCallTimeout() {
+func (cs *CallSet) checkRpcCallTimeout(closeCh chan struct{}) {
+	ticker := time.NewTicker(DefaultCheckRpcCallTimeoutInterval)
+	defer ticker.Stop()
+
 	for {
-		time.Sleep(DefaultCheckRpcCallTimeoutInterval)
+		select {
+		case <-closeCh:
+			return
+		case <-ticker.C:
+		}
+
 		for i := 0; i < cs.maxCheckCallRpcCount; i++ {
 			cs.pendingLock.Lock()
 
@@ -59,10 +93,14 @@ func (cs *CallSet) checkRpcCallTimeout() {
 				continue
 			}
 
-			delete(cs.pending, callSeq)
+			cs.deletePending(pCall)
 			strTimeout := strconv.FormatInt(int64(pCall.TimeOut.Seconds()), 10)
-			pCall.Err = errors.New("RPC call takes more than " + strTimeout + " seconds,method is " + pCall.ServiceMethod)
+			pCall.Err = NewError(CodeTimeout, "RPC call takes more than "+strTimeout+" seconds,method is "+pCall.ServiceMethod)
 			log.Error("call timeout", log.String("error", pCall.Err.Error()))
+			pCall.reportBreaker(pCall.Err, false)
+			if pCall.stream != nil {
+				pCall.stream.cancel()
+			}
 			cs.makeCallFail(pCall)
 			cs.pendingLock.Unlock()
 			continue
@@ -80,6 +118,7 @@ func (cs *CallSet) AddPending(call *Call) {
 	}
 
 	cs.pending[call.Seq] = call
+	cs.mapNodePending[call.nodeId]++
 	cs.callTimerHeap.AddTimer(call.Seq, call.TimeOut)
 
 	cs.pendingLock.Unlock()
@@ -102,10 +141,27 @@ func (cs *CallSet) removePending(seq uint64) *Call {
 	}
 
 	cs.callTimerHeap.Cancel(seq)
-	delete(cs.pending, seq)
+	cs.deletePending(v)
 	return v
 }
 
+func (cs *CallSet) deletePending(call *Call) {
+	delete(cs.pending, call.Seq)
+	if cs.mapNodePending[call.nodeId] <= 1 {
+		delete(cs.mapNodePending, call.nodeId)
+	} else {
+		cs.mapNodePending[call.nodeId]--
+	}
+}
+
+// GetPendingNum 获取发往指定结点还未返回的调用数
+func (cs *CallSet) GetPendingNum(nodeId string) int {
+	cs.pendingLock.RLock()
+	defer cs.pendingLock.RUnlock()
+
+	return cs.mapNodePending[nodeId]
+}
+
 func (cs *CallSet) FindPending(seq uint64) (pCall *Call) {
 	if seq == 0 {
 		return nil
@@ -118,21 +174,48 @@ func (cs *CallSet) FindPending(seq uint64) (pCall *Call) {
 	return pCall
 }
 
-func (cs *CallSet) cleanPending() {
+// pendingServiceMethod 获取未返回调用的服务方法，用于抓包记录
+func (cs *CallSet) pendingServiceMethod(seq uint64) string {
+	cs.pendingLock.RLock()
+	defer cs.pendingLock.RUnlock()
+
+	if pCall := cs.pending[seq]; pCall != nil {
+		return pCall.ServiceMethod
+	}
+
+	return ""
+}
+
+// cleanPending 与结点断开时，使发往该结点的调用立即失败，CallSet由所有结点共用，不能影响其他结点
+func (cs *CallSet) cleanPending(nodeId string) {
 	cs.pendingLock.Lock()
-	for {
-		callSeq := cs.callTimerHeap.PopFirst()
-		if callSeq == 0 {
-			break
+	for callSeq, pCall := range cs.pending {
+		if pCall.nodeId != nodeId {
+			continue
 		}
-		pCall := cs.pending[callSeq]
-		if pCall == nil {
-			log.Error("call Seq is not find", log.Uint64("seq", callSeq))
+
+		cs.callTimerHeap.Cancel(callSeq)
+		cs.deletePending(pCall)
+		pCall.Err = NewError(CodeDisconnected, "node is disconnect ")
+		pCall.reportBreaker(pCall.Err, true)
+		cs.makeCallFail(pCall)
+	}
+
+	cs.pendingLock.Unlock()
+}
+
+// cleanLinkPending 与结点的一条连接断开时，使通过该连接发出的调用立即失败，其他连接上的调用不受影响
+func (cs *CallSet) cleanLinkPending(nodeId string, connId int) {
+	cs.pendingLock.Lock()
+	for callSeq, pCall := range cs.pending {
+		if pCall.nodeId != nodeId || pCall.connId != connId {
 			continue
 		}
 
-		delete(cs.pending, callSeq)
-		pCall.Err = errors.New("node is disconnect ")
+		cs.callTimerHeap.Cancel(callSeq)
+		cs.deletePending(pCall)
+		pCall.Err = NewError(CodeDisconnected, "rpc connection is disconnect ")
+		pCall.reportBreaker(pCall.Err, true)
 		cs.makeCallFail(pCall)
 	}
 
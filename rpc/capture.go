@@ -0,0 +1,362 @@
+package rpc
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/duanhf2012/origin/v2/log"
+)
+
+// 抓包文件以captureMagic开头，之后为连续的记录，每条记录为4字节长度(大端)加记录内容
+const captureMagic = "ORPCCAP1"
+
+const (
+	captureFilePrefix = "rpc-capture-"
+	captureFileExt    = ".cap"
+
+	DefaultCaptureMaxFileSize = 256 * 1024 * 1024
+	DefaultCaptureMaxFiles    = 16
+)
+
+type CaptureDirection uint8
+
+const (
+	CaptureServerRequest  CaptureDirection = 1 //Server收到的请求，包括取消与流式窗口等控制帧
+	CaptureServerResponse CaptureDirection = 2 //Server发出的返回与流式消息
+	CaptureClientRequest  CaptureDirection = 3 //Client发出的请求
+	CaptureClientResponse CaptureDirection = 4 //Client收到的返回与流式消息
+)
+
+func (direction CaptureDirection) String() string {
+	switch direction {
+	case CaptureServerRequest:
+		return "ServerRequest"
+	case CaptureServerResponse:
+		return "ServerResponse"
+	case CaptureClientRequest:
+		return "ClientRequest"
+	case CaptureClientResponse:
+		return "ClientResponse"
+	}
+
+	return "Unknown"
+}
+
+// CaptureRecord 一条抓包记录，Payload为未压缩的RpcRequestData或RpcResponseData
+type CaptureRecord struct {
+	Time          time.Time
+	Direction     CaptureDirection
+	ProcessorType RpcProcessorType
+	Seq           uint64
+	PeerNodeId    string //对端结点，Server收到的请求为调用方结点，Client为目标结点，未知时为空
+	ConnTag       string //Server端的连接标识，用于区分同一调用方的不同连接
+	ServiceMethod string
+	Payload       []byte
+}
+
+// CaptureConfig 抓包配置，记录写入Dir下的滚动文件
+type CaptureConfig struct {
+	Dir         string
+	MaxFileSize int64    //单个文件的最大字节数，超过后写入新文件
+	MaxFiles    int      //最多保留的文件数，超过后删除最早的文件
+	Services    []string //只记录这些服务，为空时记录所有服务。控制帧没有服务名，总是记录
+}
+
+type captureWriter struct {
+	cfg      CaptureConfig
+	services map[string]struct{}
+
+	locker   sync.Mutex
+	file     *os.File
+	fileSize int64
+	buf      []byte
+}
+
+var captureSetting atomic.Pointer[captureWriter]
+
+// StartCapture 开始记录Server与Client收发的帧，已经开始时先停止之前的记录
+func StartCapture(cfg *CaptureConfig) error {
+	if cfg == nil || cfg.Dir == "" {
+		return errors.New("capture Dir must be configured")
+	}
+
+	writer := &captureWriter{cfg: *cfg}
+	if writer.cfg.MaxFileSize <= 0 {
+		writer.cfg.MaxFileSize = DefaultCaptureMaxFileSize
+	}
+	if writer.cfg.MaxFiles <= 0 {
+		writer.cfg.MaxFiles = DefaultCaptureMaxFiles
+	}
+	if len(cfg.Services) > 0 {
+		writer.services = make(map[string]struct{}, len(cfg.Services))
+		for _, serviceName := range cfg.Services {
+			writer.services[serviceName] = struct{}{}
+		}
+	}
+
+	err := os.MkdirAll(writer.cfg.Dir, 0755)
+	if err != nil {
+		return err
+	}
+
+	err = writer.rotate()
+	if err != nil {
+		return err
+	}
+
+	if old := captureSetting.Swap(writer); old != nil {
+		old.close()
+	}
+
+	log.Info("rpc capture is started", log.String("dir", writer.cfg.Dir))
+	return nil
+}
+
+// StopCapture 停止记录并关闭文件
+func StopCapture() {
+	writer := captureSetting.Swap(nil)
+	if writer == nil {
+		return
+	}
+
+	writer.close()
+	log.Info("rpc capture is stopped", log.String("dir", writer.cfg.Dir))
+}
+
+// IsCapturing 是否正在抓包
+func IsCapturing() bool {
+	return captureSetting.Load() != nil
+}
+
+func captureFrame(direction CaptureDirection, processorType RpcProcessorType, seq uint64, peerNodeId string, connTag string, serviceMethod string, payload []byte) {
+	writer := captureSetting.Load()
+	if writer == nil {
+		return
+	}
+
+	writer.write(&CaptureRecord{
+		Time:          time.Now(),
+		Direction:     direction,
+		ProcessorType: processorType,
+		Seq:           seq,
+		PeerNodeId:    peerNodeId,
+		ConnTag:       connTag,
+		ServiceMethod: serviceMethod,
+		Payload:       payload,
+	})
+}
+
+func (writer *captureWriter) match(serviceMethod string) bool {
+	if writer.services == nil || serviceMethod == "" {
+		return true
+	}
+
+	serviceName, _, _ := strings.Cut(serviceMethod, ".")
+	_, ok := writer.services[serviceName]
+	return ok
+}
+
+func (writer *captureWriter) write(record *CaptureRecord) {
+	if writer.match(record.ServiceMethod) == false {
+		return
+	}
+
+	writer.locker.Lock()
+	defer writer.locker.Unlock()
+
+	if writer.file == nil {
+		return
+	}
+
+	writer.buf = appendCaptureRecord(writer.buf[:0], record)
+	if writer.fileSize+int64(len(writer.buf)) > writer.cfg.MaxFileSize && writer.fileSize > int64(len(captureMagic)) {
+		err := writer.rotate()
+		if err != nil {
+			log.Error("rotate rpc capture file is failed", log.ErrorField("error", err))
+			return
+		}
+	}
+
+	n, err := writer.file.Write(writer.buf)
+	writer.fileSize += int64(n)
+	if err != nil {
+		log.Error("write rpc capture file is failed", log.ErrorField("error", err))
+	}
+}
+
+// rotate 关闭当前文件，打开新文件并删除超出数量的旧文件
+func (writer *captureWriter) rotate() error {
+	if writer.file != nil {
+		writer.file.Close()
+		writer.file = nil
+	}
+
+	fileName := filepath.Join(writer.cfg.Dir, captureFilePrefix+time.Now().Format("20060102-150405.000000")+captureFileExt)
+	file, err := os.OpenFile(fileName, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+
+	_, err = file.WriteString(captureMagic)
+	if err != nil {
+		file.Close()
+		return err
+	}
+
+	writer.file = file
+	writer.fileSize = int64(len(captureMagic))
+
+	files, err := ListCaptureFiles(writer.cfg.Dir)
+	if err != nil {
+		return nil
+	}
+	for i := 0; i < len(files)-writer.cfg.MaxFiles; i++ {
+		os.Remove(files[i])
+	}
+
+	return nil
+}
+
+func (writer *captureWriter) close() {
+	writer.locker.Lock()
+	defer writer.locker.Unlock()
+
+	if writer.file != nil {
+		writer.file.Close()
+		writer.file = nil
+	}
+}
+
+// ListCaptureFiles 按时间顺序返回目录下的抓包文件
+func ListCaptureFiles(dir string) ([]string, error) {
+	files, err := filepath.Glob(filepath.Join(dir, captureFilePrefix+"*"+captureFileExt))
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Strings(files)
+	return files, nil
+}
+
+func appendCaptureString(buf []byte, s string) []byte {
+	buf = binary.BigEndian.AppendUint16(buf, uint16(len(s)))
+	return append(buf, s...)
+}
+
+// appendCaptureRecord 记录格式：时间(纳秒) 方向 处理器类型 Seq PeerNodeId ConnTag ServiceMethod Payload
+func appendCaptureRecord(buf []byte, record *CaptureRecord) []byte {
+	buf = append(buf, 0, 0, 0, 0)
+	buf = binary.BigEndian.AppendUint64(buf, uint64(record.Time.UnixNano()))
+	buf = append(buf, uint8(record.Direction), uint8(record.ProcessorType))
+	buf = binary.BigEndian.AppendUint64(buf, record.Seq)
+	buf = appendCaptureString(buf, record.PeerNodeId)
+	buf = appendCaptureString(buf, record.ConnTag)
+	buf = appendCaptureString(buf, record.ServiceMethod)
+	buf = binary.BigEndian.AppendUint32(buf, uint32(len(record.Payload)))
+	buf = append(buf, record.Payload...)
+
+	binary.BigEndian.PutUint32(buf, uint32(len(buf)-4))
+	return buf
+}
+
+// CaptureReader 读取抓包文件
+type CaptureReader struct {
+	file   *os.File
+	reader *bufio.Reader
+}
+
+// OpenCapture 打开抓包文件，用Next依次读取记录
+func OpenCapture(fileName string) (*CaptureReader, error) {
+	file, err := os.Open(fileName)
+	if err != nil {
+		return nil, err
+	}
+
+	reader := bufio.NewReader(file)
+	magic := make([]byte, len(captureMagic))
+	_, err = io.ReadFull(reader, magic)
+	if err != nil || string(magic) != captureMagic {
+		file.Close()
+		return nil, fmt.Errorf("%s is not a rpc capture file", fileName)
+	}
+
+	return &CaptureReader{file: file, reader: reader}, nil
+}
+
+// Next 读取下一条记录，读完时返回io.EOF。写入中途停止的不完整记录返回io.ErrUnexpectedEOF
+func (captureReader *CaptureReader) Next() (*CaptureRecord, error) {
+	var lenBuf [4]byte
+	_, err := io.ReadFull(captureReader.reader, lenBuf[:])
+	if err != nil {
+		return nil, err
+	}
+
+	data := make([]byte, binary.BigEndian.Uint32(lenBuf[:]))
+	_, err = io.ReadFull(captureReader.reader, data)
+	if err == io.EOF {
+		err = io.ErrUnexpectedEOF
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return parseCaptureRecord(data)
+}
+
+func (captureReader *CaptureReader) Close() error {
+	return captureReader.file.Close()
+}
+
+var errCaptureRecord = errors.New("rpc capture record is invalid")
+
+func parseCaptureRecord(data []byte) (*CaptureRecord, error) {
+	if len(data) < 18 {
+		return nil, errCaptureRecord
+	}
+
+	record := &CaptureRecord{}
+	record.Time = time.Unix(0, int64(binary.BigEndian.Uint64(data)))
+	record.Direction = CaptureDirection(data[8])
+	record.ProcessorType = RpcProcessorType(data[9])
+	record.Seq = binary.BigEndian.Uint64(data[10:])
+	data = data[18:]
+
+	readString := func() (string, bool) {
+		if len(data) < 2 {
+			return "", false
+		}
+		l := int(binary.BigEndian.Uint16(data))
+		if len(data) < 2+l {
+			return "", false
+		}
+		s := string(data[2 : 2+l])
+		data = data[2+l:]
+		return s, true
+	}
+
+	var ok1, ok2, ok3 bool
+	record.PeerNodeId, ok1 = readString()
+	record.ConnTag, ok2 = readString()
+	record.ServiceMethod, ok3 = readString()
+	if ok1 == false || ok2 == false || ok3 == false || len(data) < 4 {
+		return nil, errCaptureRecord
+	}
+
+	payloadLen := binary.BigEndian.Uint32(data)
+	if uint32(len(data)-4) != payloadLen {
+		return nil, errCaptureRecord
+	}
+	record.Payload = data[4:]
+
+	return record, nil
+}
@@ -0,0 +1,192 @@
+package rpc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/duanhf2012/origin/v2/log"
+)
+
+// CastReply 广播调用中一个结点的返回
+type CastReply struct {
+	NodeId string
+	Reply  interface{} //与调用时传入的reply类型相同，Err不为nil时为空值
+	Err    error
+}
+
+var errorType = reflect.TypeOf((*error)(nil)).Elem()
+
+// CastFilter 广播调用时过滤结点，返回false的结点不调用
+type CastFilter func(nodeId string) bool
+
+// CastCall 调用所有结点的服务并收集返回，reply只用于确定返回类型，每个结点的返回都会新建。
+// 所有结点返回或超时后返回，单个结点的错误在CastReply.Err中，找不到任何结点时返回错误
+func (handler *RpcHandler) CastCall(serviceMethod string, args interface{}, reply interface{}) ([]CastReply, error) {
+	return handler.castCall(context.Background(), DefaultRpcTimeout, nil, serviceMethod, args, reply)
+}
+
+// CastCallContext 广播调用，超时时间与取消由ctx决定，filter为nil时调用所有结点
+func (handler *RpcHandler) CastCallContext(ctx context.Context, filter CastFilter, serviceMethod string, args interface{}, reply interface{}) ([]CastReply, error) {
+	timeout, err := getContextTimeout(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return handler.castCall(ctx, timeout, filter, serviceMethod, args, reply)
+}
+
+// AsyncCastCall 异步广播调用，所有结点返回或超时后在本服务协程中回调一次。通过CancelRpc取消后不再回调
+func (handler *RpcHandler) AsyncCastCall(serviceMethod string, args interface{}, reply interface{}, callback func(replies []CastReply, err error)) (CancelRpc, error) {
+	return handler.asyncCastCall(context.Background(), DefaultRpcTimeout, nil, serviceMethod, args, reply, callback)
+}
+
+// AsyncCastCallContext 异步广播调用，超时时间与取消由ctx决定，filter为nil时调用所有结点
+func (handler *RpcHandler) AsyncCastCallContext(ctx context.Context, filter CastFilter, serviceMethod string, args interface{}, reply interface{}, callback func(replies []CastReply, err error)) (CancelRpc, error) {
+	timeout, err := getContextTimeout(ctx)
+	if err != nil {
+		callback(nil, err)
+		return emptyCancelRpc, nil
+	}
+
+	return handler.asyncCastCall(ctx, timeout, filter, serviceMethod, args, reply, callback)
+}
+
+// getCastClient 找出广播调用的结点，退休结点也会被调用
+func (handler *RpcHandler) getCastClient(filter CastFilter, serviceMethod string) ([]*Client, error) {
+	pClientList := make([]*Client, 0, maxClusterNode)
+	err, pClientList := handler.funcRpcClient(NodeIdNull, serviceMethod, false, pClientList)
+	if err != nil {
+		return nil, err
+	}
+
+	if filter != nil {
+		clientList := pClientList[:0]
+		for _, pClient := range pClientList {
+			if filter(pClient.GetTargetNodeId()) == true {
+				clientList = append(clientList, pClient)
+			}
+		}
+		pClientList = clientList
+	}
+
+	if len(pClientList) == 0 {
+		return nil, NewError(CodeNoNode, fmt.Sprintf("no %s service found in the origin network", serviceMethod))
+	}
+
+	return pClientList, nil
+}
+
+func getReplyType(serviceMethod string, reply interface{}) (reflect.Type, error) {
+	replyType := reflect.TypeOf(reply)
+	if replyType == nil || replyType.Kind() != reflect.Ptr {
+		return nil, errors.New("call " + serviceMethod + " reply param must be a pointer")
+	}
+
+	return replyType, nil
+}
+
+func (handler *RpcHandler) castCall(ctx context.Context, timeout time.Duration, filter CastFilter, serviceMethod string, args interface{}, reply interface{}) ([]CastReply, error) {
+	replyType, err := getReplyType(serviceMethod, reply)
+	if err != nil {
+		return nil, err
+	}
+
+	pClientList, err := handler.getCastClient(filter, serviceMethod)
+	if err != nil {
+		log.Error("cast call serviceMethod is failed", log.String("serviceMethod", serviceMethod), log.ErrorField("error", err))
+		return nil, err
+	}
+
+	replies := make([]CastReply, len(pClientList))
+	var wg sync.WaitGroup
+	for i, pClient := range pClientList {
+		replies[i].NodeId = pClient.GetTargetNodeId()
+		replies[i].Reply = reflect.New(replyType.Elem()).Interface()
+
+		//本结点的本服务会直接调用RPC函数，只能在本服务协程中执行
+		if _, ok := pClient.IRealClient.(*LClient); ok == true && serviceMethod[:strings.Index(serviceMethod, ".")] == handler.GetName() {
+			replies[i].Err = handler.callClient(ctx, timeout, pClient, serviceMethod, args, replies[i].Reply)
+			continue
+		}
+
+		wg.Add(1)
+		go func(castReply *CastReply, pClient *Client) {
+			defer wg.Done()
+			castReply.Err = handler.callClient(ctx, timeout, pClient, serviceMethod, args, castReply.Reply)
+		}(&replies[i], pClient)
+	}
+	wg.Wait()
+
+	return replies, nil
+}
+
+func (handler *RpcHandler) asyncCastCall(ctx context.Context, timeout time.Duration, filter CastFilter, serviceMethod string, args interface{}, reply interface{}, callback func(replies []CastReply, err error)) (CancelRpc, error) {
+	if callback == nil {
+		return emptyCancelRpc, errors.New("call " + serviceMethod + " callback is nil")
+	}
+
+	replyType, err := getReplyType(serviceMethod, reply)
+	if err != nil {
+		return emptyCancelRpc, err
+	}
+
+	pClientList, err := handler.getCastClient(filter, serviceMethod)
+	if err != nil {
+		log.Error("cast call serviceMethod is failed", log.String("serviceMethod", serviceMethod), log.ErrorField("error", err))
+		callback(nil, err)
+		return emptyCancelRpc, nil
+	}
+
+	//各结点的回调都在本服务协程中执行，无需加锁
+	replies := make([]CastReply, len(pClientList))
+	returned := make([]bool, len(pClientList))
+	remain := len(pClientList)
+	onReply := func(index int, reply interface{}, err error) {
+		if returned[index] == true {
+			return
+		}
+
+		returned[index] = true
+		if reply != nil {
+			replies[index].Reply = reply
+		}
+		replies[index].Err = err
+		remain--
+		if remain == 0 {
+			callback(replies, nil)
+		}
+	}
+
+	cbType := reflect.FuncOf([]reflect.Type{replyType, errorType}, nil, false)
+	cancelList := make([]CancelRpc, 0, len(pClientList))
+	for i, pClient := range pClientList {
+		index := i
+		replies[i].NodeId = pClient.GetTargetNodeId()
+		replies[i].Reply = reflect.New(replyType.Elem()).Interface()
+		fVal := reflect.MakeFunc(cbType, func(in []reflect.Value) []reflect.Value {
+			var err error
+			if in[1].IsNil() == false {
+				err = in[1].Interface().(error)
+			}
+			onReply(index, in[0].Interface(), err)
+			return nil
+		})
+
+		cancelRpc, callErr := handler.asyncCallClient(ctx, timeout, pClient, serviceMethod, fVal, args, replies[i].Reply, 0)
+		cancelList = append(cancelList, cancelRpc)
+		if callErr != nil {
+			onReply(index, nil, callErr)
+		}
+	}
+
+	return func() {
+		for _, cancelRpc := range cancelList {
+			cancelRpc()
+		}
+	}, nil
+}
@@ -1,10 +1,9 @@
 package rpc
 
 import (
-	"errors"
-	"fmt"
+	"context"
 	"github.com/duanhf2012/origin/v2/log"
-	"github.com/duanhf2012/origin/v2/network"
+	"io"
 	"reflect"
 	"time"
 )
@@ -23,22 +22,29 @@ const (
 
 var clientSeq uint32
 
+// localNodeId 发出的请求携带本结点Id，被调用方据此按调用方限流
+var localNodeId string
+
+// SetLocalNodeId 设置本结点Id，需在创建Client之前调用
+func SetLocalNodeId(nodeId string) {
+	localNodeId = nodeId
+}
+
 type IWriter interface {
 	WriteMsg(nodeId string, args ...[]byte) error
 	IsConnected() bool
 }
 
 type IRealClient interface {
-	SetConn(conn *network.NetConn)
 	Close(waitDone bool)
 
-	AsyncCall(NodeId string, timeout time.Duration, rpcHandler IRpcHandler, serviceMethod string, callback reflect.Value, args interface{}, replyParam interface{}) (CancelRpc, error)
-	Go(NodeId string, timeout time.Duration, rpcHandler IRpcHandler, noReply bool, serviceMethod string, args interface{}, reply interface{}) *Call
+	AsyncCall(ctx context.Context, NodeId string, timeout time.Duration, rpcHandler IRpcHandler, serviceMethod string, callback reflect.Value, args interface{}, replyParam interface{}) (CancelRpc, error)
+	StreamCall(ctx context.Context, NodeId string, timeout time.Duration, rpcHandler IRpcHandler, serviceMethod string, callback reflect.Value, args interface{}, replyParam interface{}, window uint32) (CancelRpc, error)
+	Go(ctx context.Context, NodeId string, timeout time.Duration, rpcHandler IRpcHandler, noReply bool, serviceMethod string, args interface{}, reply interface{}) *Call
 	RawGo(NodeId string, timeout time.Duration, rpcHandler IRpcHandler, processor IRpcProcessor, noReply bool, rpcMethodId uint32, serviceMethod string, rawArgs []byte, reply interface{}) *Call
 	IsConnected() bool
-
-	Run()
-	OnClose()
+	SendCancel(NodeId string, callSeq uint64, connId int)
+	SendStreamCredit(NodeId string, callSeq uint64, connId int, credit uint32)
 
 	Bind(server IServer)
 }
@@ -47,17 +53,12 @@ type Client struct {
 	clientId         uint32
 	targetNodeId     string
 	compressBytesLen int
+	breaker          *CircuitBreaker //跨结点调用的熔断器，本结点的Client为nil
 
 	*CallSet
 	IRealClient
 }
 
-func (client *Client) NewClientAgent(conn *network.NetConn) network.Agent {
-	client.SetConn(conn)
-
-	return client
-}
-
 func (client *Client) GetTargetNodeId() string {
 	return client.targetNodeId
 }
@@ -66,40 +67,29 @@ func (client *Client) GetClientId() uint32 {
 	return client.clientId
 }
 
+// GetPendingNum 获取发往该结点还未返回的调用数
+func (client *Client) GetPendingNum() int {
+	return client.CallSet.GetPendingNum(client.targetNodeId)
+}
+
 func (client *Client) processRpcResponse(responseData []byte) error {
-	bCompress := (responseData[0] >> 7) > 0
-	processor := GetProcessor(responseData[0] & 0x7f)
-	if processor == nil {
-		//rc.conn.ReleaseReadMsg(responseData)
-		err := errors.New(fmt.Sprintf("cannot find process %d", responseData[0]&0x7f))
+	//解析帧头并解压缩
+	processor, byteData, release, err := uncompressFrame(responseData)
+	if err != nil {
 		log.Error(err.Error())
 		return err
 	}
 
 	//1.解析head
 	response := RpcResponse{}
-	response.RpcResponseData = processor.MakeRpcResponse(0, "", nil)
-
-	//解压缩
-	byteData := responseData[1:]
-	var compressBuff []byte
-
-	if bCompress == true {
-		var unCompressErr error
-		compressBuff, unCompressErr = compressor.UncompressBlock(byteData)
-		if unCompressErr != nil {
-			//rc.conn.ReleaseReadMsg(responseData)
-			err := fmt.Errorf("uncompressBlock failed,err :%s", unCompressErr.Error())
-			return err
-		}
+	response.RpcResponseData = processor.MakeRpcResponse(0, nil, nil)
 
-		byteData = compressBuff
-	}
-
-	err := processor.Unmarshal(byteData, response.RpcResponseData)
-	if cap(compressBuff) > 0 {
-		compressor.UnCompressBufferCollection(compressBuff)
+	err = processor.Unmarshal(byteData, response.RpcResponseData)
+	if err == nil && IsCapturing() == true {
+		seq := response.RpcResponseData.GetSeq()
+		captureFrame(CaptureClientResponse, processor.GetProcessorType(), seq, client.targetNodeId, "", client.pendingServiceMethod(seq), byteData)
 	}
+	release()
 
 	//rc.conn.ReleaseReadMsg(bytes)
 	if err != nil {
@@ -108,6 +98,13 @@ func (client *Client) processRpcResponse(responseData []byte) error {
 		return nil
 	}
 
+	//流式调用的一条消息
+	if response.RpcResponseData.IsStreamMsg() == true {
+		client.processStreamMsg(processor, response.RpcResponseData)
+		processor.ReleaseRpcResponse(response.RpcResponseData)
+		return nil
+	}
+
 	v := client.RemovePending(response.RpcResponseData.GetSeq())
 	if v == nil {
 		log.Error("rpcClient cannot find seq", log.Uint64("seq", response.RpcResponseData.GetSeq()))
@@ -124,6 +121,14 @@ func (client *Client) processRpcResponse(responseData []byte) error {
 		if response.RpcResponseData.GetErr() != nil {
 			v.Err = response.RpcResponseData.GetErr()
 		}
+		v.reportBreaker(v.Err, false)
+		if v.stream != nil && v.Err == nil {
+			v.Err = io.EOF
+		}
+
+		if v.replyMetadata != nil {
+			*v.replyMetadata = response.RpcResponseData.GetMetadata()
+		}
 
 		if v.callback != nil && v.callback.IsValid() {
 			v.rpcHandler.PushRpcResponse(v)
@@ -150,14 +155,21 @@ func (client *Client) processRpcResponse(responseData []byte) error {
 //	return rc.RawGo(timeout,rpcHandler,processor, noReply, 0, serviceMethod, InParam, reply)
 //}
 
-func (client *Client) rawGo(nodeId string, w IWriter, timeout time.Duration, rpcHandler IRpcHandler, processor IRpcProcessor, noReply bool, rpcMethodId uint32, serviceMethod string, rawArgs []byte, reply interface{}) *Call {
+func (client *Client) rawGo(ctx context.Context, nodeId string, w IWriter, timeout time.Duration, rpcHandler IRpcHandler, processor IRpcProcessor, noReply bool, rpcMethodId uint32, serviceMethod string, rawArgs []byte, reply interface{}) *Call {
 	call := MakeCall()
 	call.ServiceMethod = serviceMethod
 	call.Reply = reply
 	call.Seq = client.generateSeq()
 	call.TimeOut = timeout
+	call.nodeId = nodeId
+	call.replyMetadata = replyMetadataFromContext(ctx)
 
 	request := MakeRpcRequest(processor, call.Seq, rpcMethodId, serviceMethod, noReply, rawArgs)
+	request.RpcRequestData.SetMetadata(FromOutgoingContext(ctx))
+	request.RpcRequestData.SetCallerNodeId(client.getLocalNodeId())
+	if noReply == false {
+		request.RpcRequestData.SetTimeout(timeout.Milliseconds())
+	}
 	bytes, err := processor.Marshal(request.RpcRequestData)
 	ReleaseRpcRequest(request)
 
@@ -170,37 +182,43 @@ func (client *Client) rawGo(nodeId string, w IWriter, timeout time.Duration, rpc
 
 	if w == nil || w.IsConnected() == false {
 		call.Seq = 0
-		sErr := errors.New(serviceMethod + "  was called failed,rpc client is disconnect")
+		sErr := NewError(CodeDisconnected, serviceMethod+"  was called failed,rpc client is disconnect")
 		log.Error("conn is disconnect", log.String("error", sErr.Error()))
 		call.DoError(sErr)
 		return call
 	}
 
-	var compressBuff []byte
-	bCompress := uint8(0)
-	if client.compressBytesLen > 0 && len(bytes) >= client.compressBytesLen {
-		var cErr error
-		compressBuff, cErr = compressor.CompressBlock(bytes)
-		if cErr != nil {
+	if noReply == false {
+		generation, ok := client.breaker.allow()
+		if ok == false {
 			call.Seq = 0
-			log.Error("compress fail", log.String("error", cErr.Error()))
-			call.DoError(cErr)
+			call.DoError(circuitOpenError(nodeId, serviceMethod))
 			return call
 		}
-		if len(compressBuff) < len(bytes) {
-			bytes = compressBuff
-			bCompress = 1 << 7
-		}
+		call.bindBreaker(client.breaker, generation)
+	} else if client.breaker.allowNoReply() == false {
+		call.Seq = 0
+		call.DoError(circuitOpenError(nodeId, serviceMethod))
+		return call
+	}
+
+	captureFrame(CaptureClientRequest, processor.GetProcessorType(), call.Seq, nodeId, "", serviceMethod, bytes)
+	head, bytes, release, cErr := compressFrame(uint8(processor.GetProcessorType()), getCompressPolicy(nodeId, serviceMethod, client.compressBytesLen), bytes)
+	if cErr != nil {
+		call.Seq = 0
+		log.Error("compress fail", log.String("error", cErr.Error()))
+		call.DoError(cErr)
+		return call
 	}
 
+	call.connId = selectLink(w, ctx, call.Seq)
 	if noReply == false {
 		client.AddPending(call)
+		client.watchContext(ctx, call)
 	}
 
-	err = w.WriteMsg(nodeId, []byte{uint8(processor.GetProcessorType()) | bCompress}, bytes)
-	if cap(compressBuff) > 0 {
-		compressor.CompressBufferCollection(compressBuff)
-	}
+	err = writeLinkMsg(w, nodeId, call.connId, head, bytes)
+	release()
 	if err != nil {
 		client.RemovePending(call.Seq)
 		log.Error("WriteMsg is fail", log.ErrorField("error", err))
@@ -211,7 +229,8 @@ func (client *Client) rawGo(nodeId string, w IWriter, timeout time.Duration, rpc
 	return call
 }
 
-func (client *Client) asyncCall(nodeId string, w IWriter, timeout time.Duration, rpcHandler IRpcHandler, serviceMethod string, callback reflect.Value, args interface{}, replyParam interface{}) (CancelRpc, error) {
+// asyncCall 发起异步调用，window大于0时为流式调用
+func (client *Client) asyncCall(ctx context.Context, nodeId string, w IWriter, timeout time.Duration, rpcHandler IRpcHandler, serviceMethod string, callback reflect.Value, args interface{}, replyParam interface{}, window uint32) (CancelRpc, error) {
 	processorType, processor := GetProcessorType(args)
 	InParam, herr := processor.Marshal(args)
 	if herr != nil {
@@ -220,6 +239,10 @@ func (client *Client) asyncCall(nodeId string, w IWriter, timeout time.Duration,
 
 	seq := client.generateSeq()
 	request := MakeRpcRequest(processor, seq, 0, serviceMethod, false, InParam)
+	request.RpcRequestData.SetTimeout(timeout.Milliseconds())
+	request.RpcRequestData.SetMetadata(FromOutgoingContext(ctx))
+	request.RpcRequestData.SetStreamWindow(window)
+	request.RpcRequestData.SetCallerNodeId(client.getLocalNodeId())
 	bytes, err := processor.Marshal(request.RpcRequestData)
 	ReleaseRpcRequest(request)
 	if err != nil {
@@ -227,37 +250,40 @@ func (client *Client) asyncCall(nodeId string, w IWriter, timeout time.Duration,
 	}
 
 	if w == nil || w.IsConnected() == false {
-		return emptyCancelRpc, errors.New("Rpc server is disconnect,call " + serviceMethod)
+		return emptyCancelRpc, NewError(CodeDisconnected, "Rpc server is disconnect,call "+serviceMethod)
 	}
 
-	var compressBuff []byte
-	bCompress := uint8(0)
-	if client.compressBytesLen > 0 && len(bytes) >= client.compressBytesLen {
-		var cErr error
-		compressBuff, cErr = compressor.CompressBlock(bytes)
-		if cErr != nil {
-			return emptyCancelRpc, cErr
-		}
+	captureFrame(CaptureClientRequest, processorType, seq, nodeId, "", serviceMethod, bytes)
+	head, bytes, release, cErr := compressFrame(uint8(processorType), getCompressPolicy(nodeId, serviceMethod, client.compressBytesLen), bytes)
+	if cErr != nil {
+		return emptyCancelRpc, cErr
+	}
 
-		if len(compressBuff) < len(bytes) {
-			bytes = compressBuff
-			bCompress = 1 << 7
-		}
+	generation, ok := client.breaker.allow()
+	if ok == false {
+		release()
+		return emptyCancelRpc, circuitOpenError(nodeId, serviceMethod)
 	}
 
 	call := MakeCall()
+	call.bindBreaker(client.breaker, generation)
 	call.Reply = replyParam
 	call.callback = &callback
 	call.rpcHandler = rpcHandler
 	call.ServiceMethod = serviceMethod
 	call.Seq = seq
 	call.TimeOut = timeout
+	call.nodeId = nodeId
+	call.replyMetadata = replyMetadataFromContext(ctx)
+	call.connId = selectLink(w, ctx, seq)
+	if window > 0 {
+		call.stream = &clientStream{client: client, nodeId: nodeId, seq: seq, connId: call.connId, window: window}
+	}
 	client.AddPending(call)
+	client.watchContext(ctx, call)
 
-	err = w.WriteMsg(nodeId, []byte{uint8(processorType) | bCompress}, bytes)
-	if cap(compressBuff) > 0 {
-		compressor.CompressBufferCollection(compressBuff)
-	}
+	err = writeLinkMsg(w, nodeId, call.connId, head, bytes)
+	release()
 	if err != nil {
 		client.RemovePending(call.Seq)
 		ReleaseCall(call)
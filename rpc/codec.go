@@ -0,0 +1,299 @@
+package rpc
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"sync"
+
+	"github.com/klauspost/compress/snappy"
+	"github.com/klauspost/compress/zstd"
+)
+
+// 帧头第一个字节：低6位为处理器类型，0x80表示已压缩，0x40表示其后还有一个字节的编码id。
+// 使用CodecLz4压缩时不写编码id，与旧版本结点的帧格式一致
+const (
+	frameCompressFlag  = 0x80
+	frameCodecFlag     = 0x40
+	frameProcessorMask = 0x3f
+)
+
+// maxUncompressRatio 解压后与压缩数据的最大长度比，与lz4帧的上限一致，防止对端用很小的帧让接收方分配大量内存
+const maxUncompressRatio = 255
+
+type CodecId uint8
+
+const (
+	CodecLz4    CodecId = 0
+	CodecZstd   CodecId = 1
+	CodecSnappy CodecId = 2
+)
+
+// CompressPolicy 压缩策略，数据不小于BytesLen时使用Codec压缩，BytesLen小于等于0时不压缩
+type CompressPolicy struct {
+	Codec    CodecId
+	BytesLen int
+}
+
+type codecInfo struct {
+	name string
+	cp   ICompressor
+}
+
+var codecLocker sync.RWMutex
+var codecs [256]*codecInfo
+var defaultCodec CodecId
+var mapNodeCompress = map[string]CompressPolicy{}
+var mapMethodCompress = map[string]CompressPolicy{}
+
+// RegisterCodec 注册压缩算法，收到的数据按编码id解压，所以接收方需要注册发送方使用的所有算法
+func RegisterCodec(id CodecId, name string, cp ICompressor) {
+	codecLocker.Lock()
+	codecs[id] = &codecInfo{name: strings.ToLower(name), cp: cp}
+	codecLocker.Unlock()
+}
+
+func getCodec(id CodecId) ICompressor {
+	codecLocker.RLock()
+	defer codecLocker.RUnlock()
+
+	info := codecs[id]
+	if info == nil {
+		return nil
+	}
+
+	return info.cp
+}
+
+// GetCodecByName 通过名称(lz4、zstd、snappy)查找编码id
+func GetCodecByName(name string) (CodecId, bool) {
+	codecLocker.RLock()
+	defer codecLocker.RUnlock()
+
+	name = strings.ToLower(name)
+	for id, info := range codecs {
+		if info != nil && info.name == name {
+			return CodecId(id), true
+		}
+	}
+
+	return 0, false
+}
+
+// SetDefaultCodec 设置本结点默认的压缩算法，与旧版本结点通信时只能使用CodecLz4
+func SetDefaultCodec(id CodecId) error {
+	if getCodec(id) == nil {
+		return fmt.Errorf("codec %d is not registered", id)
+	}
+
+	codecLocker.Lock()
+	defaultCodec = id
+	codecLocker.Unlock()
+	return nil
+}
+
+// SetNodeCompressPolicy 设置发往指定结点的请求与返回给该结点的结果的压缩策略，policy为nil时删除
+func SetNodeCompressPolicy(nodeId string, policy *CompressPolicy) {
+	setCompressPolicy(mapNodeCompress, nodeId, policy)
+}
+
+// SetMethodCompressPolicy 设置指定RPC函数的请求与返回的压缩策略，优先于结点的配置，policy为nil时删除
+func SetMethodCompressPolicy(serviceMethod string, policy *CompressPolicy) {
+	setCompressPolicy(mapMethodCompress, serviceMethod, policy)
+}
+
+func setCompressPolicy(mapPolicy map[string]CompressPolicy, key string, policy *CompressPolicy) {
+	codecLocker.Lock()
+	defer codecLocker.Unlock()
+
+	if policy == nil {
+		delete(mapPolicy, key)
+		return
+	}
+	mapPolicy[key] = *policy
+}
+
+// getCompressPolicy 获取压缩策略，优先级为RPC函数、结点、本结点默认(默认算法与CompressBytesLen)
+func getCompressPolicy(nodeId string, serviceMethod string, bytesLen int) CompressPolicy {
+	codecLocker.RLock()
+	defer codecLocker.RUnlock()
+
+	if policy, ok := mapMethodCompress[serviceMethod]; ok == true {
+		return policy
+	}
+
+	if policy, ok := mapNodeCompress[nodeId]; ok == true {
+		return policy
+	}
+
+	return CompressPolicy{Codec: defaultCodec, BytesLen: bytesLen}
+}
+
+func noRelease() {}
+
+// compressFrame 按压缩策略压缩数据并生成帧头，压缩后没有变小时不压缩。发送完成后需要调用release回收内存
+func compressFrame(processorType uint8, policy CompressPolicy, src []byte) (head []byte, data []byte, release func(), err error) {
+	if policy.BytesLen <= 0 || len(src) < policy.BytesLen {
+		return []byte{processorType}, src, noRelease, nil
+	}
+
+	cp := getCodec(policy.Codec)
+	if cp == nil {
+		return nil, nil, noRelease, fmt.Errorf("codec %d is not registered", policy.Codec)
+	}
+
+	compressBuff, err := cp.CompressBlock(src)
+	if err != nil {
+		return nil, nil, noRelease, err
+	}
+
+	//压缩率超过接收方的上限时不压缩
+	if len(compressBuff) >= len(src) || len(compressBuff)*maxUncompressRatio < len(src) {
+		cp.CompressBufferCollection(compressBuff)
+		return []byte{processorType}, src, noRelease, nil
+	}
+
+	release = func() {
+		cp.CompressBufferCollection(compressBuff)
+	}
+	if policy.Codec == CodecLz4 {
+		return []byte{processorType | frameCompressFlag}, compressBuff, release, nil
+	}
+
+	return []byte{processorType | frameCompressFlag | frameCodecFlag, uint8(policy.Codec)}, compressBuff, release, nil
+}
+
+// uncompressFrame 解析帧头，返回处理器与解压后的数据，处理完成后需要调用release回收内存
+func uncompressFrame(frame []byte) (processor IRpcProcessor, data []byte, release func(), err error) {
+	if len(frame) == 0 {
+		return nil, nil, noRelease, errors.New("frame is empty")
+	}
+
+	processor = GetProcessor(frame[0] & frameProcessorMask)
+	if processor == nil {
+		return nil, nil, noRelease, fmt.Errorf("cannot find processor %d", frame[0]&frameProcessorMask)
+	}
+
+	data = frame[1:]
+	if frame[0]&frameCompressFlag == 0 {
+		return processor, data, noRelease, nil
+	}
+
+	codec := CodecLz4
+	if frame[0]&frameCodecFlag != 0 {
+		if len(data) == 0 {
+			return nil, nil, noRelease, errors.New("frame codec is missing")
+		}
+		codec = CodecId(data[0])
+		data = data[1:]
+	}
+
+	cp := getCodec(codec)
+	if cp == nil {
+		return nil, nil, noRelease, fmt.Errorf("codec %d is not registered", codec)
+	}
+
+	uncompressBuff, err := cp.UncompressBlock(data)
+	if err != nil {
+		return nil, nil, noRelease, fmt.Errorf("uncompressBlock failed,err :%s", err.Error())
+	}
+
+	return processor, uncompressBuff, func() { cp.UnCompressBufferCollection(uncompressBuff) }, nil
+}
+
+// ZstdCompressor 压缩率较高，适合较大的数据。压缩数据前写入原始长度
+type ZstdCompressor struct {
+	encoder *zstd.Encoder
+	decoder *zstd.Decoder
+}
+
+func newZstdCompressor() *ZstdCompressor {
+	encoder, _ := zstd.NewWriter(nil, zstd.WithEncoderLevel(zstd.SpeedFastest))
+	decoder, _ := zstd.NewReader(nil, zstd.WithDecoderConcurrency(0))
+	return &ZstdCompressor{encoder: encoder, decoder: decoder}
+}
+
+func (zc *ZstdCompressor) CompressBlock(src []byte) ([]byte, error) {
+	bound := binary.MaxVarintLen64 + len(src) + len(src)>>7 + 64
+	dest := memPool.MakeBytes(bound)
+	n := binary.PutUvarint(dest, uint64(len(src)))
+	out := zc.encoder.EncodeAll(src, dest[:n])
+	if cap(out) != cap(dest) {
+		memPool.ReleaseBytes(dest)
+		return nil, errors.New("zstd compress buffer overflow")
+	}
+
+	return out, nil
+}
+
+func (zc *ZstdCompressor) UncompressBlock(src []byte) ([]byte, error) {
+	rawLen, n := binary.Uvarint(src)
+	if n <= 0 || rawLen > math.MaxInt32 || rawLen > uint64(len(src))*maxUncompressRatio {
+		return nil, errors.New("zstd data length is error")
+	}
+
+	dest := memPool.MakeBytes(int(rawLen))
+	out, err := zc.decoder.DecodeAll(src[n:], dest[:0])
+	if err != nil {
+		memPool.ReleaseBytes(dest)
+		return nil, err
+	}
+
+	if len(out) != int(rawLen) || cap(out) != cap(dest) {
+		memPool.ReleaseBytes(dest)
+		return nil, errors.New("zstd data length is error")
+	}
+
+	return out, nil
+}
+
+func (zc *ZstdCompressor) CompressBufferCollection(buffer []byte) {
+	memPool.ReleaseBytes(buffer)
+}
+
+func (zc *ZstdCompressor) UnCompressBufferCollection(buffer []byte) {
+	memPool.ReleaseBytes(buffer)
+}
+
+// SnappyCompressor 压缩与解压速度较快，压缩率低于zstd
+type SnappyCompressor struct {
+}
+
+func (sc *SnappyCompressor) CompressBlock(src []byte) ([]byte, error) {
+	bound := snappy.MaxEncodedLen(len(src))
+	if bound < 0 {
+		return nil, errors.New("snappy data is too large")
+	}
+
+	dest := memPool.MakeBytes(bound)
+	return snappy.Encode(dest, src), nil
+}
+
+func (sc *SnappyCompressor) UncompressBlock(src []byte) ([]byte, error) {
+	rawLen, err := snappy.DecodedLen(src)
+	if err != nil {
+		return nil, err
+	}
+	if rawLen > len(src)*maxUncompressRatio {
+		return nil, errors.New("snappy data length is error")
+	}
+
+	dest := memPool.MakeBytes(rawLen)
+	out, err := snappy.Decode(dest, src)
+	if err != nil {
+		memPool.ReleaseBytes(dest)
+		return nil, err
+	}
+
+	return out, nil
+}
+
+func (sc *SnappyCompressor) CompressBufferCollection(buffer []byte) {
+	memPool.ReleaseBytes(buffer)
+}
+
+func (sc *SnappyCompressor) UnCompressBufferCollection(buffer []byte) {
+	memPool.ReleaseBytes(buffer)
+}
@@ -0,0 +1,185 @@
+package rpc
+
+import (
+	"bytes"
+	"crypto/rand"
+	"strings"
+	"testing"
+)
+
+var compressibleData = bytes.Repeat([]byte("origin rpc frame "), 256)
+
+func TestCompressFrame(t *testing.T) {
+	tests := []struct {
+		name  string
+		codec CodecId
+		head  func(processorType uint8) []byte
+	}{
+		{"lz4", CodecLz4, func(processorType uint8) []byte { return []byte{processorType | frameCompressFlag} }},
+		{"zstd", CodecZstd, func(processorType uint8) []byte {
+			return []byte{processorType | frameCompressFlag | frameCodecFlag, uint8(CodecZstd)}
+		}},
+		{"snappy", CodecSnappy, func(processorType uint8) []byte {
+			return []byte{processorType | frameCompressFlag | frameCodecFlag, uint8(CodecSnappy)}
+		}},
+	}
+
+	for _, tt := range tests {
+		for _, processorType := range []RpcProcessorType{RpcProcessorJson, RpcProcessorPB} {
+			t.Run(tt.name+"/"+processorType.String(), func(t *testing.T) {
+				head, data, release, err := compressFrame(uint8(processorType), CompressPolicy{Codec: tt.codec, BytesLen: 1}, compressibleData)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if bytes.Equal(head, tt.head(uint8(processorType))) == false {
+					t.Fatalf("head = %v, want %v", head, tt.head(uint8(processorType)))
+				}
+				if len(data) >= len(compressibleData) {
+					t.Fatalf("data is not compressed, %d >= %d", len(data), len(compressibleData))
+				}
+
+				frame := append(append([]byte{}, head...), data...)
+				release()
+
+				processor, raw, releaseRaw, err := uncompressFrame(frame)
+				if err != nil {
+					t.Fatal(err)
+				}
+				defer releaseRaw()
+				if processor.GetProcessorType() != processorType || bytes.Equal(raw, compressibleData) == false {
+					t.Fatalf("processor %d, raw data mismatch", processor.GetProcessorType())
+				}
+			})
+		}
+	}
+}
+
+func TestCompressFrameSkip(t *testing.T) {
+	randomData := make([]byte, 512)
+	rand.Read(randomData)
+
+	tests := []struct {
+		name   string
+		policy CompressPolicy
+		src    []byte
+	}{
+		{"disabled", CompressPolicy{Codec: CodecZstd, BytesLen: 0}, compressibleData},
+		{"too short", CompressPolicy{Codec: CodecZstd, BytesLen: len(compressibleData) + 1}, compressibleData},
+		{"not smaller", CompressPolicy{Codec: CodecSnappy, BytesLen: 1}, randomData},
+		{"ratio too high", CompressPolicy{Codec: CodecZstd, BytesLen: 1}, make([]byte, 1<<20)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			head, data, release, err := compressFrame(uint8(RpcProcessorPB), tt.policy, tt.src)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer release()
+
+			if bytes.Equal(head, []byte{uint8(RpcProcessorPB)}) == false || bytes.Equal(data, tt.src) == false {
+				t.Fatalf("head = %v, data is compressed", head)
+			}
+
+			_, raw, releaseRaw, err := uncompressFrame(append(head, data...))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer releaseRaw()
+			if bytes.Equal(raw, tt.src) == false {
+				t.Fatal("raw data mismatch")
+			}
+		})
+	}
+}
+
+// TestLegacyLz4Frame 旧版本结点的压缩帧：帧头为处理器类型|1<<7，其后为lz4数据，没有编码id
+func TestLegacyLz4Frame(t *testing.T) {
+	lz4 := &Lz4Compressor{}
+	compressBuff, err := lz4.CompressBlock(compressibleData)
+	if err != nil {
+		t.Fatal(err)
+	}
+	legacyFrame := append([]byte{uint8(RpcProcessorPB) | 1<<7}, compressBuff...)
+	lz4.CompressBufferCollection(compressBuff)
+
+	processor, raw, release, err := uncompressFrame(legacyFrame)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer release()
+	if processor.GetProcessorType() != RpcProcessorPB || bytes.Equal(raw, compressibleData) == false {
+		t.Fatal("legacy lz4 frame mismatch")
+	}
+
+	//新结点使用CodecLz4时发出与旧版本相同的帧
+	head, data, releaseData, err := compressFrame(uint8(RpcProcessorPB), CompressPolicy{Codec: CodecLz4, BytesLen: 1}, compressibleData)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer releaseData()
+	if bytes.Equal(append(head, data...), legacyFrame) == false {
+		t.Fatal("lz4 frame is not compatible with legacy nodes")
+	}
+}
+
+func TestUncompressFrameError(t *testing.T) {
+	tests := []struct {
+		name  string
+		frame []byte
+	}{
+		{"empty", nil},
+		{"unknown processor", []byte{0x3f, 1}},
+		{"codec missing", []byte{uint8(RpcProcessorPB) | frameCompressFlag | frameCodecFlag}},
+		{"codec not registered", []byte{uint8(RpcProcessorPB) | frameCompressFlag | frameCodecFlag, 200, 1, 2}},
+		{"corrupt zstd", []byte{uint8(RpcProcessorPB) | frameCompressFlag | frameCodecFlag, uint8(CodecZstd), 10, 1, 2, 3}},
+		{"corrupt snappy", []byte{uint8(RpcProcessorPB) | frameCompressFlag | frameCodecFlag, uint8(CodecSnappy), 0xff, 0xff, 0xff}},
+		//声明的原始长度远大于压缩数据，不能按声明的长度分配内存
+		{"zstd length too large", []byte{uint8(RpcProcessorPB) | frameCompressFlag | frameCodecFlag, uint8(CodecZstd), 0xff, 0xff, 0xff, 0xff, 0x07, 1, 2}},
+		{"snappy length too large", []byte{uint8(RpcProcessorPB) | frameCompressFlag | frameCodecFlag, uint8(CodecSnappy), 0xff, 0xff, 0xff, 0xff, 0x07, 1, 2}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, _, release, err := uncompressFrame(tt.frame)
+			release()
+			if err == nil {
+				t.Fatal("uncompressFrame should fail")
+			}
+		})
+	}
+
+	if _, _, _, err := compressFrame(uint8(RpcProcessorPB), CompressPolicy{Codec: 200, BytesLen: 1}, compressibleData); err == nil {
+		t.Fatal("compressFrame with unregistered codec should fail")
+	}
+}
+
+func TestUncompressLengthLimit(t *testing.T) {
+	//原始长度为(1<<31)-1，压缩数据只有几个字节
+	lengthPrefix := []byte{0xff, 0xff, 0xff, 0xff, 0x07, 1, 2}
+	for _, cp := range []ICompressor{getCodec(CodecZstd), getCodec(CodecSnappy)} {
+		_, err := cp.UncompressBlock(lengthPrefix)
+		if err == nil || strings.Contains(err.Error(), "data length is error") == false {
+			t.Fatalf("%T uncompress error = %v", cp, err)
+		}
+	}
+}
+
+func TestGetCompressPolicy(t *testing.T) {
+	nodePolicy := CompressPolicy{Codec: CodecSnappy, BytesLen: 100}
+	methodPolicy := CompressPolicy{Codec: CodecZstd, BytesLen: 200}
+	SetNodeCompressPolicy("codec_node", &nodePolicy)
+	SetMethodCompressPolicy("CodecService.RPC_Test", &methodPolicy)
+	defer SetNodeCompressPolicy("codec_node", nil)
+	defer SetMethodCompressPolicy("CodecService.RPC_Test", nil)
+
+	if policy := getCompressPolicy("codec_node", "CodecService.RPC_Test", 10); policy != methodPolicy {
+		t.Fatalf("method policy = %+v", policy)
+	}
+	if policy := getCompressPolicy("codec_node", "CodecService.RPC_Other", 10); policy != nodePolicy {
+		t.Fatalf("node policy = %+v", policy)
+	}
+	if policy := getCompressPolicy("other_node", "CodecService.RPC_Other", 10); policy != (CompressPolicy{Codec: CodecLz4, BytesLen: 10}) {
+		t.Fatalf("default policy = %+v", policy)
+	}
+}
@@ -18,14 +18,15 @@ type ICompressor interface {
 	UnCompressBufferCollection(buffer []byte) //解压缩的Buffer内存回收
 }
 
-var compressor ICompressor
-
 func init() {
-	SetCompressor(&Lz4Compressor{})
+	RegisterCodec(CodecLz4, "lz4", &Lz4Compressor{})
+	RegisterCodec(CodecZstd, "zstd", newZstdCompressor())
+	RegisterCodec(CodecSnappy, "snappy", &SnappyCompressor{})
 }
 
+// SetCompressor 替换CodecLz4使用的压缩算法，CodecLz4使用旧的帧格式，需要所有结点一致
 func SetCompressor(cp ICompressor) {
-	compressor = cp
+	RegisterCodec(CodecLz4, "lz4", cp)
 }
 
 type Lz4Compressor struct {
@@ -0,0 +1,157 @@
+package rpc
+
+import (
+	"context"
+	"errors"
+	"strconv"
+)
+
+type ErrCode int32
+
+// 框架内置的错误码，业务自定义错误码请从CodeBusiness开始
+const (
+	CodeOK             ErrCode = 0
+	CodeUnknown        ErrCode = 1  //未分类的错误，包括RPC函数返回的普通error
+	CodeTimeout        ErrCode = 2  //调用超时
+	CodeCanceled       ErrCode = 3  //调用被取消
+	CodeNoNode         ErrCode = 4  //找不到提供服务的结点
+	CodeMethodNotFound ErrCode = 5  //找不到服务或RPC函数
+	CodePanic          ErrCode = 6  //RPC函数执行时崩溃
+	CodeOverload       ErrCode = 7  //服务过载，如服务的事件队列已满
+	CodeDisconnected   ErrCode = 8  //与结点的连接已断开
+	CodeInvalidParam   ErrCode = 9  //参数解析失败或RPC函数格式不匹配
+	CodeCircuitOpen    ErrCode = 10 //结点熔断中，调用未发出
+	CodeRateLimited    ErrCode = 11 //超过被调用方的限流，请求未执行
+
+	CodeBusiness ErrCode = 1000
+)
+
+// 可用于errors.Is比较，只比较错误码
+var (
+	ErrTimeout        = &Error{Code: CodeTimeout}
+	ErrCanceled       = &Error{Code: CodeCanceled}
+	ErrNoNode         = &Error{Code: CodeNoNode}
+	ErrMethodNotFound = &Error{Code: CodeMethodNotFound}
+	ErrPanic          = &Error{Code: CodePanic}
+	ErrOverload       = &Error{Code: CodeOverload}
+	ErrDisconnected   = &Error{Code: CodeDisconnected}
+	ErrInvalidParam   = &Error{Code: CodeInvalidParam}
+	ErrCircuitOpen    = &Error{Code: CodeCircuitOpen}
+	ErrRateLimited    = &Error{Code: CodeRateLimited}
+)
+
+// Error 带错误码的RPC错误，跨结点返回时错误码、信息与附加数据都会被保留
+type Error struct {
+	Code    ErrCode
+	Msg     string
+	Details []byte
+}
+
+func NewError(code ErrCode, msg string) *Error {
+	return &Error{Code: code, Msg: msg}
+}
+
+func NewErrorWithDetails(code ErrCode, msg string, details []byte) *Error {
+	return &Error{Code: code, Msg: msg, Details: details}
+}
+
+// Error 只返回Msg，与原来字符串形式的RpcError保持一致
+func (e *Error) Error() string {
+	if e.Msg == "" {
+		return "rpc error code " + strconv.Itoa(int(e.Code))
+	}
+
+	return e.Msg
+}
+
+// Is 错误码相同即认为是同一错误，超时与取消也可以与context中的错误比较
+func (e *Error) Is(target error) bool {
+	switch target {
+	case context.DeadlineExceeded:
+		return e.Code == CodeTimeout
+	case context.Canceled:
+		return e.Code == CodeCanceled
+	}
+
+	t, ok := target.(*Error)
+	if ok == false {
+		return false
+	}
+
+	return t.Code == e.Code
+}
+
+// Code 获取错误的错误码
+func Code(err error) ErrCode {
+	if err == nil {
+		return CodeOK
+	}
+
+	var rpcErr *Error
+	if errors.As(err, &rpcErr) {
+		return rpcErr.Code
+	}
+
+	if errors.Is(err, context.DeadlineExceeded) {
+		return CodeTimeout
+	}
+
+	if errors.Is(err, context.Canceled) {
+		return CodeCanceled
+	}
+
+	if rErr, ok := err.(RpcError); ok && rErr == NilError {
+		return CodeOK
+	}
+
+	return CodeUnknown
+}
+
+// contextError 将ctx结束的原因转换为带错误码的错误，仍可与context.Canceled、context.DeadlineExceeded进行errors.Is比较
+func contextError(err error) error {
+	switch err {
+	case context.DeadlineExceeded:
+		return NewError(CodeTimeout, err.Error())
+	case context.Canceled:
+		return NewError(CodeCanceled, err.Error())
+	}
+
+	return err
+}
+
+// normalizeError RpcError("")表示没有错误
+func normalizeError(err error) error {
+	if rErr, ok := err.(RpcError); ok && rErr == NilError {
+		return nil
+	}
+
+	return err
+}
+
+// marshalError 拆分为写入返回包的错误信息、错误码与附加数据
+func marshalError(err error) (string, int32, []byte) {
+	err = normalizeError(err)
+	if err == nil {
+		return "", 0, nil
+	}
+
+	var rpcErr *Error
+	if errors.As(err, &rpcErr) {
+		return rpcErr.Error(), int32(rpcErr.Code), rpcErr.Details
+	}
+
+	return err.Error(), int32(Code(err)), nil
+}
+
+// unmarshalError 从返回包中还原错误，旧版本结点没有错误码时为CodeUnknown
+func unmarshalError(msg string, code int32, details []byte) error {
+	if msg == "" && code == 0 {
+		return nil
+	}
+
+	if code == 0 {
+		code = int32(CodeUnknown)
+	}
+
+	return &Error{Code: ErrCode(code), Msg: msg, Details: details}
+}
@@ -0,0 +1,140 @@
+package rpc
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestMarshalError(t *testing.T) {
+	tests := []struct {
+		name    string
+		err     error
+		msg     string
+		code    ErrCode
+		details []byte
+	}{
+		{"nil", nil, "", CodeOK, nil},
+		{"NilError", NilError, "", CodeOK, nil},
+		{"rpc error", NewErrorWithDetails(CodeBusiness+1, "no money", []byte{1, 2}), "no money", CodeBusiness + 1, []byte{1, 2}},
+		{"wrapped", fmt.Errorf("call: %w", NewError(CodeOverload, "queue is full")), "queue is full", CodeOverload, nil},
+		{"empty msg", &Error{Code: CodeNoNode}, "rpc error code 4", CodeNoNode, nil},
+		{"plain error", errors.New("boom"), "boom", CodeUnknown, nil},
+		{"deadline", context.DeadlineExceeded, context.DeadlineExceeded.Error(), CodeTimeout, nil},
+		{"canceled", context.Canceled, context.Canceled.Error(), CodeCanceled, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			msg, code, details := marshalError(tt.err)
+			if msg != tt.msg || ErrCode(code) != tt.code || bytes.Equal(details, tt.details) == false {
+				t.Fatalf("marshalError = (%q, %d, %v), want (%q, %d, %v)", msg, code, details, tt.msg, tt.code, tt.details)
+			}
+
+			err := unmarshalError(msg, code, details)
+			if tt.code == CodeOK {
+				if err != nil {
+					t.Fatalf("unmarshalError = %v, want nil", err)
+				}
+				return
+			}
+
+			var rpcErr *Error
+			if errors.As(err, &rpcErr) == false {
+				t.Fatalf("unmarshalError = %T, want *Error", err)
+			}
+			if rpcErr.Msg != tt.msg || rpcErr.Code != tt.code || bytes.Equal(rpcErr.Details, tt.details) == false {
+				t.Fatalf("unmarshalError = %+v", rpcErr)
+			}
+		})
+	}
+}
+
+// TestUnmarshalLegacyError 旧版本结点只有错误信息，没有错误码
+func TestUnmarshalLegacyError(t *testing.T) {
+	err := unmarshalError("old error", 0, nil)
+	if Code(err) != CodeUnknown || err.Error() != "old error" {
+		t.Fatalf("unmarshalError = %v(%d)", err, Code(err))
+	}
+}
+
+func TestResponseErrorEnvelope(t *testing.T) {
+	processors := []struct {
+		name      string
+		processor IRpcProcessor
+		newResp   func() IRpcResponseData
+	}{
+		{"pb", &PBProcessor{}, func() IRpcResponseData { return &PBRpcResponseData{} }},
+		{"json", &JsonProcessor{}, func() IRpcResponseData { return &JsonRpcResponseData{} }},
+	}
+
+	errs := []error{
+		nil,
+		errors.New("plain"),
+		NewError(CodeTimeout, "timeout"),
+		NewErrorWithDetails(CodeBusiness, "business", []byte("details")),
+	}
+
+	for _, p := range processors {
+		for _, sendErr := range errs {
+			t.Run(fmt.Sprintf("%s/%v", p.name, sendErr), func(t *testing.T) {
+				resp := p.processor.MakeRpcResponse(7, sendErr, []byte("reply"))
+				defer p.processor.ReleaseRpcResponse(resp)
+
+				data, err := p.processor.Marshal(resp)
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				recv := p.newResp()
+				if err = p.processor.Unmarshal(data, recv); err != nil {
+					t.Fatal(err)
+				}
+
+				if recv.GetSeq() != 7 || string(recv.GetReply()) != "reply" {
+					t.Fatalf("seq %d, reply %q", recv.GetSeq(), recv.GetReply())
+				}
+
+				recvErr := recv.GetErr()
+				if sendErr == nil {
+					if recvErr != nil {
+						t.Fatalf("GetErr = %v, want nil", recvErr)
+					}
+					return
+				}
+
+				wantMsg, wantCode, wantDetails := marshalError(sendErr)
+				var rpcErr *Error
+				if errors.As(recvErr, &rpcErr) == false || rpcErr.Msg != wantMsg || int32(rpcErr.Code) != wantCode || bytes.Equal(rpcErr.Details, wantDetails) == false {
+					t.Fatalf("GetErr = %#v, want (%q, %d, %v)", recvErr, wantMsg, wantCode, wantDetails)
+				}
+			})
+		}
+	}
+}
+
+// TestLegacyResponseEnvelope 旧版本结点的返回包没有错误码字段
+func TestLegacyResponseEnvelope(t *testing.T) {
+	pbProcessor := &PBProcessor{}
+	data, err := pbProcessor.Marshal(&PBRpcResponseData{Seq: 1, Error: "old pb error"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	pbResp := &PBRpcResponseData{}
+	if err = pbProcessor.Unmarshal(data, pbResp); err != nil {
+		t.Fatal(err)
+	}
+	if rErr := pbResp.GetErr(); Code(rErr) != CodeUnknown || rErr.Error() != "old pb error" {
+		t.Fatalf("pb GetErr = %v(%d)", rErr, Code(rErr))
+	}
+
+	jsonResp := &JsonRpcResponseData{}
+	if err = (&JsonProcessor{}).Unmarshal([]byte(`{"Seq":1,"Err":"old json error","Reply":null}`), jsonResp); err != nil {
+		t.Fatal(err)
+	}
+	if rErr := jsonResp.GetErr(); Code(rErr) != CodeUnknown || rErr.Error() != "old json error" {
+		t.Fatalf("json GetErr = %v(%d)", rErr, Code(rErr))
+	}
+}
@@ -0,0 +1,419 @@
+package rpc
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/duanhf2012/origin/v2/log"
+)
+
+// FaultRule 在结点之间注入网络故障，用于测试超时、重试与退休流程。故障在调用方注入，
+// 发出的请求按FromNodeId为本结点匹配，收到的返回按ToNodeId为本结点匹配，对RClient与NatsClient都生效，
+// 本结点内的调用不受影响
+type FaultRule struct {
+	FromNodeId    string        //发出帧的结点，为空时匹配所有结点
+	ToNodeId      string        //接收帧的结点，为空时匹配所有结点
+	Latency       time.Duration //每帧增加的延迟，同一方向的帧保持顺序
+	Jitter        time.Duration //在Latency之上随机增加0~Jitter的延迟
+	DropRate      float64       //丢弃帧的概率，0~1
+	DuplicateRate float64       //重复发送帧的概率，0~1
+	ReorderRate   float64       //帧被延后ReorderDelay发送，使之后的帧先到达的概率，0~1
+	ReorderDelay  time.Duration //乱序帧的额外延迟，默认10ms
+	Cut           bool          //丢弃所有帧，连接保持但调用超时
+}
+
+// FaultStats 规则生效的次数
+type FaultStats struct {
+	Matched    uint64 //匹配的帧数
+	Dropped    uint64
+	Duplicated uint64
+	Delayed    uint64
+	Reordered  uint64
+}
+
+type faultRule struct {
+	name string
+	rule FaultRule
+
+	matched    atomic.Uint64
+	dropped    atomic.Uint64
+	duplicated atomic.Uint64
+	delayed    atomic.Uint64
+	reordered  atomic.Uint64
+}
+
+func (fr *faultRule) match(fromNodeId string, toNodeId string) bool {
+	return (fr.rule.FromNodeId == "" || fr.rule.FromNodeId == fromNodeId) && (fr.rule.ToNodeId == "" || fr.rule.ToNodeId == toNodeId)
+}
+
+type faultKey struct {
+	fromNodeId string
+	toNodeId   string
+}
+
+type delayedFrame struct {
+	due     time.Time
+	data    []byte
+	times   int
+	deliver func(args ...[]byte) error
+}
+
+// faultQueue 同一方向延迟发送的帧，按进入的顺序发送
+type faultQueue struct {
+	locker  sync.Mutex
+	frames  []*delayedFrame
+	running bool
+}
+
+var faultLocker sync.Mutex
+var faultRules atomic.Pointer[[]*faultRule] //按设置的顺序，修改时整体替换
+var mapFaultQueue = map[faultKey]*faultQueue{}
+
+// SetFaultRule 设置名为name的故障规则，已存在时替换并清零统计，rule为nil时删除。一个帧匹配多条规则时，
+// 任一规则为Cut则丢弃，否则使用第一条匹配的规则
+func SetFaultRule(name string, rule *FaultRule) error {
+	if rule == nil {
+		DelFaultRule(name)
+		return nil
+	}
+
+	if rule.DropRate < 0 || rule.DropRate > 1 || rule.DuplicateRate < 0 || rule.DuplicateRate > 1 || rule.ReorderRate < 0 || rule.ReorderRate > 1 {
+		return errors.New("fault rule " + name + " rate must be between 0 and 1")
+	}
+	if rule.Latency < 0 || rule.Jitter < 0 || rule.ReorderDelay < 0 {
+		return errors.New("fault rule " + name + " delay must not be negative")
+	}
+
+	fr := &faultRule{name: name, rule: *rule}
+	if fr.rule.ReorderRate > 0 && fr.rule.ReorderDelay == 0 {
+		fr.rule.ReorderDelay = 10 * time.Millisecond
+	}
+
+	faultLocker.Lock()
+	defer faultLocker.Unlock()
+
+	var ruleList []*faultRule
+	replaced := false
+	if old := faultRules.Load(); old != nil {
+		for _, r := range *old {
+			if r.name == name {
+				ruleList = append(ruleList, fr)
+				replaced = true
+				continue
+			}
+			ruleList = append(ruleList, r)
+		}
+	}
+	if replaced == false {
+		ruleList = append(ruleList, fr)
+	}
+	faultRules.Store(&ruleList)
+
+	log.Warn("rpc fault rule is set", log.String("name", name), log.String("rule", fr.rule.String()))
+	return nil
+}
+
+// DelFaultRule 删除故障规则，已延迟的帧仍会发送
+func DelFaultRule(name string) {
+	faultLocker.Lock()
+	defer faultLocker.Unlock()
+
+	old := faultRules.Load()
+	if old == nil {
+		return
+	}
+
+	var ruleList []*faultRule
+	for _, r := range *old {
+		if r.name != name {
+			ruleList = append(ruleList, r)
+		}
+	}
+	if len(ruleList) == 0 {
+		faultRules.Store(nil)
+		return
+	}
+	faultRules.Store(&ruleList)
+}
+
+// ClearFaultRules 删除所有故障规则
+func ClearFaultRules() {
+	faultLocker.Lock()
+	faultRules.Store(nil)
+	faultLocker.Unlock()
+}
+
+// GetFaultRules 返回map[name]故障规则
+func GetFaultRules() map[string]FaultRule {
+	mapRule := map[string]FaultRule{}
+	if ruleList := faultRules.Load(); ruleList != nil {
+		for _, r := range *ruleList {
+			mapRule[r.name] = r.rule
+		}
+	}
+
+	return mapRule
+}
+
+// GetFaultStats 返回map[name]规则生效的次数
+func GetFaultStats() map[string]FaultStats {
+	mapStats := map[string]FaultStats{}
+	if ruleList := faultRules.Load(); ruleList != nil {
+		for _, r := range *ruleList {
+			mapStats[r.name] = FaultStats{
+				Matched:    r.matched.Load(),
+				Dropped:    r.dropped.Load(),
+				Duplicated: r.duplicated.Load(),
+				Delayed:    r.delayed.Load(),
+				Reordered:  r.reordered.Load(),
+			}
+		}
+	}
+
+	return mapStats
+}
+
+func cutLinkRuleName(nodeId1 string, nodeId2 string) string {
+	if nodeId1 > nodeId2 {
+		nodeId1, nodeId2 = nodeId2, nodeId1
+	}
+
+	return "cut:" + nodeId1 + "|" + nodeId2
+}
+
+// CutLink 丢弃两个结点之间双向的所有帧，直到RestoreLink
+func CutLink(nodeId1 string, nodeId2 string) {
+	name := cutLinkRuleName(nodeId1, nodeId2)
+	SetFaultRule(name+">", &FaultRule{FromNodeId: nodeId1, ToNodeId: nodeId2, Cut: true})
+	SetFaultRule(name+"<", &FaultRule{FromNodeId: nodeId2, ToNodeId: nodeId1, Cut: true})
+}
+
+// RestoreLink 恢复CutLink断开的两个结点
+func RestoreLink(nodeId1 string, nodeId2 string) {
+	name := cutLinkRuleName(nodeId1, nodeId2)
+	DelFaultRule(name + ">")
+	DelFaultRule(name + "<")
+}
+
+func (rule *FaultRule) String() string {
+	var sb strings.Builder
+	writeField := func(key string, val string) {
+		if sb.Len() > 0 {
+			sb.WriteByte(',')
+		}
+		sb.WriteString(key + "=" + val)
+	}
+
+	if rule.FromNodeId != "" {
+		writeField("from", rule.FromNodeId)
+	}
+	if rule.ToNodeId != "" {
+		writeField("to", rule.ToNodeId)
+	}
+	if rule.Latency > 0 {
+		writeField("latency", rule.Latency.String())
+	}
+	if rule.Jitter > 0 {
+		writeField("jitter", rule.Jitter.String())
+	}
+	if rule.DropRate > 0 {
+		writeField("drop", strconv.FormatFloat(rule.DropRate, 'g', -1, 64))
+	}
+	if rule.DuplicateRate > 0 {
+		writeField("dup", strconv.FormatFloat(rule.DuplicateRate, 'g', -1, 64))
+	}
+	if rule.ReorderRate > 0 {
+		writeField("reorder", strconv.FormatFloat(rule.ReorderRate, 'g', -1, 64))
+		writeField("reorderdelay", rule.ReorderDelay.String())
+	}
+	if rule.Cut == true {
+		writeField("cut", "true")
+	}
+
+	return sb.String()
+}
+
+// ParseFaultRule 解析"from=node_1,to=node_2,latency=50ms,jitter=10ms,drop=0.1,dup=0.05,reorder=0.1,reorderdelay=20ms,cut=true"，
+// 各项都可以缺省
+func ParseFaultRule(s string) (*FaultRule, error) {
+	rule := &FaultRule{}
+	for _, field := range strings.Split(s, ",") {
+		field = strings.TrimSpace(field)
+		if field == "" {
+			continue
+		}
+
+		key, val, ok := strings.Cut(field, "=")
+		if ok == false {
+			return nil, fmt.Errorf("invalid fault field %s", field)
+		}
+
+		var err error
+		switch strings.ToLower(strings.TrimSpace(key)) {
+		case "from":
+			rule.FromNodeId = strings.TrimSpace(val)
+		case "to":
+			rule.ToNodeId = strings.TrimSpace(val)
+		case "latency":
+			rule.Latency, err = time.ParseDuration(val)
+		case "jitter":
+			rule.Jitter, err = time.ParseDuration(val)
+		case "drop":
+			rule.DropRate, err = strconv.ParseFloat(val, 64)
+		case "dup":
+			rule.DuplicateRate, err = strconv.ParseFloat(val, 64)
+		case "reorder":
+			rule.ReorderRate, err = strconv.ParseFloat(val, 64)
+		case "reorderdelay":
+			rule.ReorderDelay, err = time.ParseDuration(val)
+		case "cut":
+			rule.Cut, err = strconv.ParseBool(val)
+		default:
+			return nil, fmt.Errorf("unknown fault field %s", key)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid fault field %s: %w", field, err)
+		}
+	}
+
+	return rule, nil
+}
+
+func hasFaultRule() bool {
+	return faultRules.Load() != nil
+}
+
+// injectFault 按匹配的规则发送帧，deliver在延迟后可能在其他协程中调用。没有匹配的规则时直接发送
+func injectFault(fromNodeId string, toNodeId string, deliver func(args ...[]byte) error, args ...[]byte) error {
+	ruleList := faultRules.Load()
+	if ruleList == nil {
+		return deliver(args...)
+	}
+
+	var fr *faultRule
+	for _, r := range *ruleList {
+		if r.match(fromNodeId, toNodeId) == false {
+			continue
+		}
+		if r.rule.Cut == true {
+			r.matched.Add(1)
+			r.dropped.Add(1)
+			return nil
+		}
+		if fr == nil {
+			fr = r
+		}
+	}
+	if fr == nil {
+		return deliver(args...)
+	}
+
+	fr.matched.Add(1)
+	rule := &fr.rule
+	if rule.DropRate > 0 && rand.Float64() < rule.DropRate {
+		fr.dropped.Add(1)
+		return nil
+	}
+
+	times := 1
+	if rule.DuplicateRate > 0 && rand.Float64() < rule.DuplicateRate {
+		fr.duplicated.Add(1)
+		times = 2
+	}
+
+	delay := rule.Latency
+	if rule.Jitter > 0 {
+		delay += time.Duration(rand.Int63n(int64(rule.Jitter) + 1))
+	}
+	reorder := rule.ReorderRate > 0 && rand.Float64() < rule.ReorderRate
+	if delay == 0 && reorder == false {
+		var err error
+		for i := 0; i < times; i++ {
+			err = deliver(args...)
+		}
+		return err
+	}
+
+	//调用方在返回后会回收args，延迟发送需要复制
+	var data []byte
+	for _, arg := range args {
+		data = append(data, arg...)
+	}
+	frame := &delayedFrame{due: time.Now().Add(delay), data: data, times: times, deliver: deliver}
+
+	//乱序帧不进入队列，之后的帧先发送
+	if reorder == true {
+		fr.reordered.Add(1)
+		time.AfterFunc(delay+rule.ReorderDelay, func() {
+			frame.send()
+		})
+		return nil
+	}
+
+	fr.delayed.Add(1)
+	pushDelayedFrame(faultKey{fromNodeId: fromNodeId, toNodeId: toNodeId}, frame)
+	return nil
+}
+
+func (frame *delayedFrame) send() {
+	for i := 0; i < frame.times; i++ {
+		err := frame.deliver(frame.data)
+		if err != nil {
+			log.Debug("deliver delayed rpc frame failed", log.ErrorField("error", err))
+			return
+		}
+	}
+}
+
+func pushDelayedFrame(key faultKey, frame *delayedFrame) {
+	faultLocker.Lock()
+	queue, ok := mapFaultQueue[key]
+	if ok == false {
+		queue = &faultQueue{}
+		mapFaultQueue[key] = queue
+	}
+	faultLocker.Unlock()
+
+	queue.locker.Lock()
+	queue.frames = append(queue.frames, frame)
+	if queue.running == true {
+		queue.locker.Unlock()
+		return
+	}
+	queue.running = true
+	queue.locker.Unlock()
+
+	go queue.run()
+}
+
+// run 按顺序在到期后发送帧，队列为空时退出
+func (queue *faultQueue) run() {
+	for {
+		queue.locker.Lock()
+		if len(queue.frames) == 0 {
+			queue.running = false
+			queue.locker.Unlock()
+			return
+		}
+		frame := queue.frames[0]
+		queue.frames[0] = nil
+		queue.frames = queue.frames[1:]
+		queue.locker.Unlock()
+
+		if d := time.Until(frame.due); d > 0 {
+			time.Sleep(d)
+		}
+		frame.send()
+	}
+}
+
+// deliverRpcResponse 处理注入故障后收到的返回，帧只有一段
+func (client *Client) deliverRpcResponse(args ...[]byte) error {
+	return client.processRpcResponse(args[0])
+}
@@ -0,0 +1,154 @@
+package rpc
+
+import (
+	"context"
+	"time"
+)
+
+// 以下为强类型的调用函数，参数与回调的类型在编译时检查，JsonProcessor与PBProcessor的参数都可以使用。
+// 由于rpc.Call已被占用，统一使用Typed前缀，异步回调同样在调用方服务的协程中执行
+
+// TypedCall 同步调用，Res需要显式指定，如rpc.TypedCall[rpc.UpsetRankData, rpc.RankResult](slf, "RankService.RPC_UpsetRank", req)
+func TypedCall[Req any, Res any](handler IRpcHandler, serviceMethod string, req *Req) (*Res, error) {
+	res := new(Res)
+	err := handler.Call(serviceMethod, req, res)
+	return res, err
+}
+
+// TypedCallNode 同步调用指定结点
+func TypedCallNode[Req any, Res any](handler IRpcHandler, nodeId string, serviceMethod string, req *Req) (*Res, error) {
+	res := new(Res)
+	err := handler.CallNode(nodeId, serviceMethod, req, res)
+	return res, err
+}
+
+// TypedCallWithTimeout 指定超时时间的同步调用
+func TypedCallWithTimeout[Req any, Res any](handler IRpcHandler, timeout time.Duration, serviceMethod string, req *Req) (*Res, error) {
+	res := new(Res)
+	err := handler.CallWithTimeout(timeout, serviceMethod, req, res)
+	return res, err
+}
+
+// TypedCallNodeWithTimeout 指定超时时间同步调用指定结点
+func TypedCallNodeWithTimeout[Req any, Res any](handler IRpcHandler, timeout time.Duration, nodeId string, serviceMethod string, req *Req) (*Res, error) {
+	res := new(Res)
+	err := handler.CallNodeWithTimeout(timeout, nodeId, serviceMethod, req, res)
+	return res, err
+}
+
+// TypedCallContext 同步调用，超时时间与取消由ctx决定
+func TypedCallContext[Req any, Res any](handler IRpcHandler, ctx context.Context, serviceMethod string, req *Req) (*Res, error) {
+	res := new(Res)
+	err := handler.CallContext(ctx, serviceMethod, req, res)
+	return res, err
+}
+
+// TypedCallNodeContext 同步调用指定结点，超时时间与取消由ctx决定
+func TypedCallNodeContext[Req any, Res any](handler IRpcHandler, ctx context.Context, nodeId string, serviceMethod string, req *Req) (*Res, error) {
+	res := new(Res)
+	err := handler.CallNodeContext(ctx, nodeId, serviceMethod, req, res)
+	return res, err
+}
+
+// TypedCallByKey 按Key路由的同步调用
+func TypedCallByKey[Req any, Res any](handler IRpcHandler, key string, serviceMethod string, req *Req) (*Res, error) {
+	res := new(Res)
+	err := handler.CallByKey(key, serviceMethod, req, res)
+	return res, err
+}
+
+// TypedAsyncCall 异步调用，Req与Res由参数推导
+func TypedAsyncCall[Req any, Res any](handler IRpcHandler, serviceMethod string, req *Req, callback func(res *Res, err error)) error {
+	registerCallbackType[Res]()
+	return handler.AsyncCall(serviceMethod, req, callback)
+}
+
+// TypedAsyncCallNode 异步调用指定结点
+func TypedAsyncCallNode[Req any, Res any](handler IRpcHandler, nodeId string, serviceMethod string, req *Req, callback func(res *Res, err error)) error {
+	registerCallbackType[Res]()
+	return handler.AsyncCallNode(nodeId, serviceMethod, req, callback)
+}
+
+// TypedAsyncCallWithTimeout 指定超时时间的异步调用，可通过返回的CancelRpc取消
+func TypedAsyncCallWithTimeout[Req any, Res any](handler IRpcHandler, timeout time.Duration, serviceMethod string, req *Req, callback func(res *Res, err error)) (CancelRpc, error) {
+	registerCallbackType[Res]()
+	return handler.AsyncCallWithTimeout(timeout, serviceMethod, req, callback)
+}
+
+// TypedAsyncCallNodeWithTimeout 指定超时时间异步调用指定结点
+func TypedAsyncCallNodeWithTimeout[Req any, Res any](handler IRpcHandler, timeout time.Duration, nodeId string, serviceMethod string, req *Req, callback func(res *Res, err error)) (CancelRpc, error) {
+	registerCallbackType[Res]()
+	return handler.AsyncCallNodeWithTimeout(timeout, nodeId, serviceMethod, req, callback)
+}
+
+// TypedAsyncCallContext 异步调用，超时时间与取消由ctx决定
+func TypedAsyncCallContext[Req any, Res any](handler IRpcHandler, ctx context.Context, serviceMethod string, req *Req, callback func(res *Res, err error)) (CancelRpc, error) {
+	registerCallbackType[Res]()
+	return handler.AsyncCallContext(ctx, serviceMethod, req, callback)
+}
+
+// TypedAsyncCallNodeContext 异步调用指定结点，超时时间与取消由ctx决定
+func TypedAsyncCallNodeContext[Req any, Res any](handler IRpcHandler, ctx context.Context, nodeId string, serviceMethod string, req *Req, callback func(res *Res, err error)) (CancelRpc, error) {
+	registerCallbackType[Res]()
+	return handler.AsyncCallNodeContext(ctx, nodeId, serviceMethod, req, callback)
+}
+
+// TypedAsyncCallByKey 按Key路由的异步调用
+func TypedAsyncCallByKey[Req any, Res any](handler IRpcHandler, key string, serviceMethod string, req *Req, callback func(res *Res, err error)) error {
+	registerCallbackType[Res]()
+	return handler.AsyncCallByKey(key, serviceMethod, req, callback)
+}
+
+// TypedGo 调用且不等待返回
+func TypedGo[Req any](handler IRpcHandler, serviceMethod string, req *Req) error {
+	return handler.Go(serviceMethod, req)
+}
+
+// TypedGoNode 调用指定结点且不等待返回
+func TypedGoNode[Req any](handler IRpcHandler, nodeId string, serviceMethod string, req *Req) error {
+	return handler.GoNode(nodeId, serviceMethod, req)
+}
+
+// TypedCastGo 调用所有结点的服务且不等待返回
+func TypedCastGo[Req any](handler IRpcHandler, serviceMethod string, req *Req) error {
+	return handler.CastGo(serviceMethod, req)
+}
+
+// TypedGoByKey 按Key路由调用且不等待返回
+func TypedGoByKey[Req any](handler IRpcHandler, key string, serviceMethod string, req *Req) error {
+	return handler.GoByKey(key, serviceMethod, req)
+}
+
+// TypedStreamCall 流式调用，callback按顺序收到每条消息，结束时err不为nil，io.EOF表示正常结束
+func TypedStreamCall[Req any, Res any](handler IRpcHandler, serviceMethod string, req *Req, callback func(msg *Res, err error)) (CancelRpc, error) {
+	registerCallbackType[Res]()
+	return handler.StreamCall(serviceMethod, req, callback)
+}
+
+// TypedStreamCallNode 流式调用指定结点
+func TypedStreamCallNode[Req any, Res any](handler IRpcHandler, nodeId string, serviceMethod string, req *Req, callback func(msg *Res, err error)) (CancelRpc, error) {
+	registerCallbackType[Res]()
+	return handler.StreamCallNode(nodeId, serviceMethod, req, callback)
+}
+
+// TypedStreamCallContext 流式调用，取消由ctx决定
+func TypedStreamCallContext[Req any, Res any](handler IRpcHandler, ctx context.Context, serviceMethod string, req *Req, callback func(msg *Res, err error)) (CancelRpc, error) {
+	registerCallbackType[Res]()
+	return handler.StreamCallContext(ctx, serviceMethod, req, callback)
+}
+
+// TypedStreamCallNodeContext 流式调用指定结点，取消由ctx决定
+func TypedStreamCallNodeContext[Req any, Res any](handler IRpcHandler, ctx context.Context, nodeId string, serviceMethod string, req *Req, callback func(msg *Res, err error)) (CancelRpc, error) {
+	registerCallbackType[Res]()
+	return handler.StreamCallNodeContext(ctx, nodeId, serviceMethod, req, callback)
+}
+
+// TypedCastCall 调用所有结点的服务并收集返回，CastReply.Reply的类型为*Res
+func TypedCastCall[Req any, Res any](handler IRpcHandler, serviceMethod string, req *Req) ([]CastReply, error) {
+	return handler.CastCall(serviceMethod, req, (*Res)(nil))
+}
+
+// TypedAsyncCastCall 异步调用所有结点的服务，全部返回或超时后回调一次
+func TypedAsyncCastCall[Req any, Res any](handler IRpcHandler, serviceMethod string, req *Req, callback func(replies []CastReply, err error)) (CancelRpc, error) {
+	return handler.AsyncCastCall(serviceMethod, req, (*Res)(nil), callback)
+}
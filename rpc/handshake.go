@@ -0,0 +1,258 @@
+package rpc
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"github.com/duanhf2012/origin/v2/network"
+	"google.golang.org/protobuf/proto"
+)
+
+// 握手帧的帧头，占用处理器类型的最大值，不与压缩标记组合
+const frameHandshake = frameProcessorMask
+
+const (
+	handshakeVersion  uint32 = 1
+	handshakeNonceLen        = 16
+)
+
+// DefaultHandshakeTimeout 握手的最长时间，超时后断开连接
+var DefaultHandshakeTimeout = 5 * time.Second
+
+// HandshakeConfig 结点间连接的认证配置，集群内所有结点需同时开启或关闭
+type HandshakeConfig struct {
+	NodeId      string //本结点，Server与Client设置了本结点Id时使用它们的Id，同一进程的多个结点可以共用一份配置
+	ClusterName string //集群名，不同集群的结点互相拒绝
+	Secret      string //集群共享密钥
+}
+
+var handshakeConfig atomic.Pointer[HandshakeConfig]
+
+// SetHandshakeConfig 设置结点间连接的认证，cfg为nil时不认证。需在Server启动与创建Client之前调用
+func SetHandshakeConfig(cfg *HandshakeConfig) error {
+	if cfg == nil {
+		handshakeConfig.Store(nil)
+		return nil
+	}
+
+	if cfg.Secret == "" {
+		return errors.New("handshake Secret must be configured")
+	}
+
+	c := *cfg
+	handshakeConfig.Store(&c)
+	return nil
+}
+
+// handshakeMac 计算握手的HMAC，role区分两个方向，防止将对端的Mac原样返回
+func handshakeMac(cfg *HandshakeConfig, role string, fromNodeId string, toNodeId string, nonces ...[]byte) []byte {
+	mac := hmac.New(sha256.New, []byte(cfg.Secret))
+	var lenBuf [4]byte
+	write := func(b []byte) {
+		binary.BigEndian.PutUint32(lenBuf[:], uint32(len(b)))
+		mac.Write(lenBuf[:])
+		mac.Write(b)
+	}
+
+	write([]byte(role))
+	binary.BigEndian.PutUint32(lenBuf[:], handshakeVersion)
+	mac.Write(lenBuf[:])
+	write([]byte(cfg.ClusterName))
+	write([]byte(fromNodeId))
+	write([]byte(toNodeId))
+	for _, nonce := range nonces {
+		write(nonce)
+	}
+
+	return mac.Sum(nil)
+}
+
+func newNonce() ([]byte, error) {
+	nonce := make([]byte, handshakeNonceLen)
+	_, err := rand.Read(nonce)
+	return nonce, err
+}
+
+func writeHandshake(conn network.Conn, msg *PBHandshake) error {
+	bytes, err := proto.Marshal(msg)
+	if err != nil {
+		return err
+	}
+
+	return conn.WriteMsg([]byte{frameHandshake}, bytes)
+}
+
+func readHandshake(conn network.Conn) (*PBHandshake, error) {
+	data, err := conn.ReadMsg()
+	if err != nil {
+		return nil, err
+	}
+	defer conn.ReleaseReadMsg(data)
+
+	msg, err := parseHandshake(data)
+	if err != nil {
+		return nil, err
+	}
+	if msg.Error != "" {
+		return nil, fmt.Errorf("rejected by peer:%s", msg.Error)
+	}
+
+	return msg, nil
+}
+
+func parseHandshake(data []byte) (*PBHandshake, error) {
+	if len(data) == 0 || data[0] != frameHandshake {
+		return nil, errors.New("handshake is required")
+	}
+
+	msg := &PBHandshake{}
+	err := proto.Unmarshal(data[1:], msg)
+	if err != nil {
+		return nil, err
+	}
+
+	return msg, nil
+}
+
+// checkPeer 校验对端的版本、集群与结点
+func checkPeer(cfg *HandshakeConfig, msg *PBHandshake, peerNodeId string) error {
+	if msg.Version != handshakeVersion {
+		return fmt.Errorf("handshake version %d is not supported", msg.Version)
+	}
+	if msg.ClusterName != cfg.ClusterName {
+		return fmt.Errorf("cluster %s is mismatched, local cluster is %s", msg.ClusterName, cfg.ClusterName)
+	}
+	if msg.TargetNodeId != cfg.NodeId {
+		return fmt.Errorf("target node %s is mismatched, local node is %s", msg.TargetNodeId, cfg.NodeId)
+	}
+	if peerNodeId != "" && msg.NodeId != peerNodeId {
+		return fmt.Errorf("peer node %s is mismatched, expected node is %s", msg.NodeId, peerNodeId)
+	}
+	if msg.NodeId == "" || len(msg.Nonce) != handshakeNonceLen {
+		return errors.New("handshake data is invalid")
+	}
+
+	return nil
+}
+
+// withHandshakeTimeout 握手超时时关闭连接，使阻塞的读取返回
+func withHandshakeTimeout(conn network.Conn, fn func() error) error {
+	timer := time.AfterFunc(DefaultHandshakeTimeout, conn.Close)
+	err := fn()
+	if timer.Stop() == false && err == nil {
+		err = errors.New("handshake timeout")
+	}
+
+	return err
+}
+
+// getHandshakeConfig 返回以localNodeId为本结点的认证配置，未开启认证时返回nil
+func getHandshakeConfig(localNodeId string) *HandshakeConfig {
+	cfg := handshakeConfig.Load()
+	if cfg == nil || localNodeId == "" || localNodeId == cfg.NodeId {
+		return cfg
+	}
+
+	c := *cfg
+	c.NodeId = localNodeId
+	return &c
+}
+
+// clientHandshake 作为调用方与目标结点握手：发送Hello，校验对端返回后发送自己的Mac。localNodeId为空时使用配置中的结点
+func clientHandshake(conn network.Conn, localNodeId string, targetNodeId string) error {
+	cfg := getHandshakeConfig(localNodeId)
+	if cfg == nil {
+		return nil
+	}
+
+	return doClientHandshake(cfg, conn, targetNodeId)
+}
+
+func doClientHandshake(cfg *HandshakeConfig, conn network.Conn, targetNodeId string) error {
+	return withHandshakeTimeout(conn, func() error {
+		nonce, err := newNonce()
+		if err != nil {
+			return err
+		}
+
+		err = writeHandshake(conn, &PBHandshake{Version: handshakeVersion, ClusterName: cfg.ClusterName, NodeId: cfg.NodeId, TargetNodeId: targetNodeId, Nonce: nonce})
+		if err != nil {
+			return err
+		}
+
+		reply, err := readHandshake(conn)
+		if err != nil {
+			return err
+		}
+
+		err = checkPeer(cfg, reply, targetNodeId)
+		if err == nil && hmac.Equal(reply.Mac, handshakeMac(cfg, "server", targetNodeId, cfg.NodeId, nonce, reply.Nonce)) == false {
+			err = errors.New("server mac is invalid, secret may be mismatched")
+		}
+		if err != nil {
+			writeHandshake(conn, &PBHandshake{Error: err.Error()})
+			return err
+		}
+
+		return writeHandshake(conn, &PBHandshake{Mac: handshakeMac(cfg, "client", cfg.NodeId, targetNodeId, reply.Nonce, nonce)})
+	})
+}
+
+// serverHandshake 作为被调用方校验连接，成功时返回对端结点。localNodeId为空时使用配置中的结点
+func serverHandshake(conn network.Conn, localNodeId string) (string, error) {
+	cfg := getHandshakeConfig(localNodeId)
+	if cfg == nil {
+		return "", nil
+	}
+
+	return doServerHandshake(cfg, conn)
+}
+
+func doServerHandshake(cfg *HandshakeConfig, conn network.Conn) (string, error) {
+	var peerNodeId string
+	err := withHandshakeTimeout(conn, func() error {
+		hello, err := readHandshake(conn)
+		if err != nil {
+			return err
+		}
+
+		err = checkPeer(cfg, hello, "")
+		if err != nil {
+			writeHandshake(conn, &PBHandshake{Error: err.Error()})
+			return err
+		}
+
+		nonce, err := newNonce()
+		if err != nil {
+			return err
+		}
+
+		mac := handshakeMac(cfg, "server", cfg.NodeId, hello.NodeId, hello.Nonce, nonce)
+		err = writeHandshake(conn, &PBHandshake{Version: handshakeVersion, ClusterName: cfg.ClusterName, NodeId: cfg.NodeId, TargetNodeId: hello.NodeId, Nonce: nonce, Mac: mac})
+		if err != nil {
+			return err
+		}
+
+		finish, err := readHandshake(conn)
+		if err != nil {
+			return err
+		}
+
+		if hmac.Equal(finish.Mac, handshakeMac(cfg, "client", hello.NodeId, cfg.NodeId, nonce, hello.Nonce)) == false {
+			err = errors.New("client mac is invalid, secret may be mismatched")
+			writeHandshake(conn, &PBHandshake{Error: err.Error()})
+			return err
+		}
+
+		peerNodeId = hello.NodeId
+		return nil
+	})
+
+	return peerNodeId, err
+}
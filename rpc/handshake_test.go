@@ -0,0 +1,284 @@
+package rpc
+
+import (
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/duanhf2012/origin/v2/network"
+)
+
+// pipeConn 基于net.Pipe的network.Conn，读写都是同步的
+type pipeConn struct {
+	conn   net.Conn
+	parser *network.MsgParser
+}
+
+func newPipeConns() (*pipeConn, *pipeConn) {
+	parser := &network.MsgParser{LenMsgLen: 4, MaxMsgLen: 1 << 16}
+	parser.Init()
+
+	c1, c2 := net.Pipe()
+	return &pipeConn{conn: c1, parser: parser}, &pipeConn{conn: c2, parser: parser}
+}
+
+func (pc *pipeConn) ReadMsg() ([]byte, error) {
+	return pc.parser.Read(pc.conn)
+}
+
+func (pc *pipeConn) WriteMsg(args ...[]byte) error {
+	return pc.parser.Write(pc.conn, args...)
+}
+
+func (pc *pipeConn) LocalAddr() net.Addr {
+	return pc.conn.LocalAddr()
+}
+
+func (pc *pipeConn) RemoteAddr() net.Addr {
+	return pc.conn.RemoteAddr()
+}
+
+func (pc *pipeConn) Close() {
+	pc.conn.Close()
+}
+
+func (pc *pipeConn) Destroy() {
+	pc.conn.Close()
+}
+
+func (pc *pipeConn) ReleaseReadMsg(byteBuff []byte) {
+	pc.parser.ReleaseBytes(byteBuff)
+}
+
+func checkHandshakeErr(t *testing.T, side string, err error, want string) {
+	t.Helper()
+
+	if want == "" {
+		if err != nil {
+			t.Fatalf("%s handshake fail:%v", side, err)
+		}
+		return
+	}
+
+	if err == nil || strings.Contains(err.Error(), want) == false {
+		t.Fatalf("%s handshake error = %v, want %q", side, err, want)
+	}
+}
+
+func setHandshakeTimeout(t *testing.T, timeout time.Duration) {
+	oldTimeout := DefaultHandshakeTimeout
+	DefaultHandshakeTimeout = timeout
+	t.Cleanup(func() {
+		DefaultHandshakeTimeout = oldTimeout
+	})
+}
+
+func TestHandshake(t *testing.T) {
+	node1 := &HandshakeConfig{NodeId: "node_1", ClusterName: "c1", Secret: "secret"}
+	node2 := &HandshakeConfig{NodeId: "node_2", ClusterName: "c1", Secret: "secret"}
+
+	tests := []struct {
+		name         string
+		clientCfg    *HandshakeConfig
+		serverCfg    *HandshakeConfig
+		targetNodeId string
+		clientErr    string
+		serverErr    string
+	}{
+		{"ok", node1, node2, "node_2", "", ""},
+		{"wrong secret", node1, &HandshakeConfig{NodeId: "node_2", ClusterName: "c1", Secret: "other"}, "node_2", "server mac is invalid", "rejected by peer"},
+		{"wrong cluster", node1, &HandshakeConfig{NodeId: "node_2", ClusterName: "c2", Secret: "secret"}, "node_2", "rejected by peer", "cluster c1 is mismatched"},
+		{"wrong target node", node1, node2, "node_3", "rejected by peer", "target node node_3 is mismatched"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clientConn, serverConn := newPipeConns()
+			defer clientConn.Close()
+			defer serverConn.Close()
+
+			clientErr := make(chan error, 1)
+			go func() {
+				clientErr <- doClientHandshake(tt.clientCfg, clientConn, tt.targetNodeId)
+			}()
+
+			peerNodeId, err := doServerHandshake(tt.serverCfg, serverConn)
+			checkHandshakeErr(t, "server", err, tt.serverErr)
+			checkHandshakeErr(t, "client", <-clientErr, tt.clientErr)
+			if tt.serverErr == "" && peerNodeId != tt.clientCfg.NodeId {
+				t.Fatalf("peer node = %s, want %s", peerNodeId, tt.clientCfg.NodeId)
+			}
+		})
+	}
+}
+
+// TestServerHandshakeAttack 不知道密钥或不按流程握手的调用方
+func TestServerHandshakeAttack(t *testing.T) {
+	cfg := &HandshakeConfig{NodeId: "node_2", ClusterName: "c1", Secret: "secret"}
+	attacker := &HandshakeConfig{NodeId: "node_1", ClusterName: "c1", Secret: "guess"}
+	hello := func(nonce []byte) *PBHandshake {
+		return &PBHandshake{Version: handshakeVersion, ClusterName: "c1", NodeId: "node_1", TargetNodeId: "node_2", Nonce: nonce}
+	}
+	fixedNonce := []byte("0123456789abcdef")
+
+	//上一次成功握手时调用方发送的Mac
+	var recordedMac []byte
+
+	tests := []struct {
+		name      string
+		client    func(conn network.Conn) error
+		serverErr string
+	}{
+		{"no handshake", func(conn network.Conn) error {
+			return conn.WriteMsg([]byte{uint8(RpcProcessorPB)}, []byte("request"))
+		}, "handshake is required"},
+		{"unsupported version", func(conn network.Conn) error {
+			msg := hello(fixedNonce)
+			msg.Version = handshakeVersion + 1
+			return writeHandshake(conn, msg)
+		}, "is not supported"},
+		{"invalid nonce", func(conn network.Conn) error {
+			return writeHandshake(conn, hello(nil))
+		}, "handshake data is invalid"},
+		{"wrong secret", func(conn network.Conn) error {
+			writeHandshake(conn, hello(fixedNonce))
+			reply, err := readHandshake(conn)
+			if err != nil {
+				return err
+			}
+			return writeHandshake(conn, &PBHandshake{Mac: handshakeMac(attacker, "client", "node_1", "node_2", reply.Nonce, fixedNonce)})
+		}, "client mac is invalid"},
+		{"reflected mac", func(conn network.Conn) error {
+			writeHandshake(conn, hello(fixedNonce))
+			reply, err := readHandshake(conn)
+			if err != nil {
+				return err
+			}
+			//将服务端的Mac原样返回
+			return writeHandshake(conn, &PBHandshake{Mac: reply.Mac})
+		}, "client mac is invalid"},
+		{"record", func(conn network.Conn) error {
+			writeHandshake(conn, hello(fixedNonce))
+			reply, err := readHandshake(conn)
+			if err != nil {
+				return err
+			}
+			recordedMac = handshakeMac(cfg, "client", "node_1", "node_2", reply.Nonce, fixedNonce)
+			return writeHandshake(conn, &PBHandshake{Mac: recordedMac})
+		}, ""},
+		{"replayed mac", func(conn network.Conn) error {
+			writeHandshake(conn, hello(fixedNonce))
+			if _, err := readHandshake(conn); err != nil {
+				return err
+			}
+			return writeHandshake(conn, &PBHandshake{Mac: recordedMac})
+		}, "client mac is invalid"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.name == "replayed mac" && len(recordedMac) == 0 {
+				t.Fatal("mac is not recorded")
+			}
+
+			clientConn, serverConn := newPipeConns()
+			defer serverConn.Close()
+
+			go func() {
+				defer clientConn.Close()
+				tt.client(clientConn)
+				//读取服务端的拒绝
+				clientConn.ReadMsg()
+			}()
+
+			_, err := doServerHandshake(cfg, serverConn)
+			checkHandshakeErr(t, "server", err, tt.serverErr)
+		})
+	}
+}
+
+// TestClientHandshakeAttack 冒充目标结点的服务端
+func TestClientHandshakeAttack(t *testing.T) {
+	cfg := &HandshakeConfig{NodeId: "node_1", ClusterName: "c1", Secret: "secret"}
+	reply := func(hello *PBHandshake, nodeId string, nonce []byte, mac []byte) *PBHandshake {
+		return &PBHandshake{Version: handshakeVersion, ClusterName: "c1", NodeId: nodeId, TargetNodeId: hello.NodeId, Nonce: nonce, Mac: mac}
+	}
+	nonce := []byte("fedcba9876543210")
+
+	tests := []struct {
+		name      string
+		server    func(conn network.Conn, hello *PBHandshake) error
+		clientErr string
+	}{
+		{"peer node mismatch", func(conn network.Conn, hello *PBHandshake) error {
+			other := &HandshakeConfig{NodeId: "node_3", ClusterName: "c1", Secret: "secret"}
+			return writeHandshake(conn, reply(hello, "node_3", nonce, handshakeMac(other, "server", "node_3", "node_1", hello.Nonce, nonce)))
+		}, "peer node node_3 is mismatched"},
+		{"wrong secret", func(conn network.Conn, hello *PBHandshake) error {
+			attacker := &HandshakeConfig{NodeId: "node_2", ClusterName: "c1", Secret: "guess"}
+			return writeHandshake(conn, reply(hello, "node_2", nonce, handshakeMac(attacker, "server", "node_2", "node_1", hello.Nonce, nonce)))
+		}, "server mac is invalid"},
+		{"reflected mac", func(conn network.Conn, hello *PBHandshake) error {
+			//调用方方向的Mac不能用作服务端的Mac
+			return writeHandshake(conn, reply(hello, "node_2", nonce, handshakeMac(cfg, "client", "node_1", "node_2", nonce, hello.Nonce)))
+		}, "server mac is invalid"},
+		{"replayed nonce", func(conn network.Conn, hello *PBHandshake) error {
+			//用其他握手中的调用方Nonce计算的Mac
+			return writeHandshake(conn, reply(hello, "node_2", nonce, handshakeMac(cfg, "server", "node_2", "node_1", []byte("0123456789abcdef"), nonce)))
+		}, "server mac is invalid"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clientConn, serverConn := newPipeConns()
+			defer clientConn.Close()
+
+			go func() {
+				defer serverConn.Close()
+				hello, err := readHandshake(serverConn)
+				if err != nil {
+					return
+				}
+				tt.server(serverConn, hello)
+				//读取调用方的拒绝
+				serverConn.ReadMsg()
+			}()
+
+			err := doClientHandshake(cfg, clientConn, "node_2")
+			checkHandshakeErr(t, "client", err, tt.clientErr)
+		})
+	}
+}
+
+func TestHandshakeTimeout(t *testing.T) {
+	setHandshakeTimeout(t, 50*time.Millisecond)
+	cfg := &HandshakeConfig{NodeId: "node_1", ClusterName: "c1", Secret: "secret"}
+
+	t.Run("server", func(t *testing.T) {
+		clientConn, serverConn := newPipeConns()
+		defer clientConn.Close()
+
+		//调用方连接后不发送握手
+		_, err := doServerHandshake(cfg, serverConn)
+		if err == nil {
+			t.Fatal("server handshake should be timeout")
+		}
+	})
+
+	t.Run("client", func(t *testing.T) {
+		clientConn, serverConn := newPipeConns()
+		defer serverConn.Close()
+
+		//服务端读取Hello后不回复
+		go serverConn.ReadMsg()
+		start := time.Now()
+		err := doClientHandshake(cfg, clientConn, "node_2")
+		if err == nil {
+			t.Fatal("client handshake should be timeout")
+		}
+		if time.Since(start) > time.Second {
+			t.Fatalf("handshake timeout takes %s", time.Since(start))
+		}
+	})
+}
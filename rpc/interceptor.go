@@ -0,0 +1,174 @@
+package rpc
+
+import (
+	"context"
+	"reflect"
+
+	"github.com/duanhf2012/origin/v2/log"
+)
+
+// CallInfo 客户端拦截器中本次调用的信息
+type CallInfo struct {
+	NodeId        string //被调用的结点
+	ServiceMethod string
+	NoReply       bool //Go、CastGo等不需要返回的调用
+	Async         bool //异步调用，invoker在请求发出后即返回，结果通过OnAsyncDone获得
+	Stream        bool //流式调用，OnAsyncDone只在结束时执行一次
+
+	asyncDone []func(reply interface{}, err error)
+}
+
+// OnAsyncDone 异步调用时注册结果回调，在调用方的回调函数之前执行，需在调用invoker之前注册
+func (info *CallInfo) OnAsyncDone(fn func(reply interface{}, err error)) {
+	info.asyncDone = append(info.asyncDone, fn)
+}
+
+// ClientInvoker 发起实际的调用
+type ClientInvoker func(ctx context.Context, info *CallInfo, args interface{}, reply interface{}) error
+
+// ClientInterceptor 客户端拦截器，不调用invoker并返回错误即可拦截本次调用。
+// 可以通过NewOutgoingContext等设置ctx后传给invoker来附加Metadata
+type ClientInterceptor func(ctx context.Context, info *CallInfo, args interface{}, reply interface{}, invoker ClientInvoker) error
+
+// ServerHandler 调用实际的RPC函数，带Responder的函数通过Responder返回结果，这里的reply为nil
+type ServerHandler func(ctx context.Context, args interface{}) (reply interface{}, err error)
+
+// ServerInterceptor 服务端拦截器，不调用handler并返回错误即可拦截本次请求，调用方的Metadata通过FromIncomingContext获取
+type ServerInterceptor func(ctx context.Context, serviceMethod string, args interface{}, handler ServerHandler) (reply interface{}, err error)
+
+// AddClientInterceptor 增加本服务发出调用时的拦截器，按添加顺序由外向内执行，需在OnInit中调用
+func (handler *RpcHandler) AddClientInterceptor(interceptor ...ClientInterceptor) {
+	handler.clientInterceptors = append(handler.clientInterceptors, interceptor...)
+}
+
+// AddServerInterceptor 增加本服务处理RPC请求时的拦截器，按添加顺序由外向内执行，需在OnInit中调用。
+// 服务调用自身的RPC在调用方协程中直接执行，不经过拦截器，也不做超时与限流检查
+func (handler *RpcHandler) AddServerInterceptor(interceptor ...ServerInterceptor) {
+	handler.serverInterceptors = append(handler.serverInterceptors, interceptor...)
+}
+
+func chainClientInterceptors(interceptors []ClientInterceptor, invoker ClientInvoker) ClientInvoker {
+	for i := len(interceptors) - 1; i >= 0; i-- {
+		interceptor := interceptors[i]
+		next := invoker
+		invoker = func(ctx context.Context, info *CallInfo, args interface{}, reply interface{}) error {
+			return interceptor(ctx, info, args, reply, next)
+		}
+	}
+
+	return invoker
+}
+
+func chainServerInterceptors(interceptors []ServerInterceptor, serviceMethod string, handler ServerHandler) ServerHandler {
+	for i := len(interceptors) - 1; i >= 0; i-- {
+		interceptor := interceptors[i]
+		next := handler
+		handler = func(ctx context.Context, args interface{}) (interface{}, error) {
+			return interceptor(ctx, serviceMethod, args, next)
+		}
+	}
+
+	return handler
+}
+
+// wrapAsyncCallback 异步调用返回时先执行拦截器注册的结果回调，流式调用只在结束时执行
+func wrapAsyncCallback(callback reflect.Value, asyncDone []func(reply interface{}, err error), stream bool) reflect.Value {
+	return reflect.MakeFunc(callback.Type(), func(args []reflect.Value) []reflect.Value {
+		var err error
+		if args[1].IsNil() == false {
+			err = args[1].Interface().(error)
+		}
+
+		if stream == true && err == nil {
+			return callback.Call(args)
+		}
+
+		for _, fn := range asyncDone {
+			fn(args[0].Interface(), err)
+		}
+
+		return callback.Call(args)
+	})
+}
+
+// interceptRpcRequest 经过服务端拦截器处理RPC请求
+func (handler *RpcHandler) interceptRpcRequest(request *RpcRequest, v *RpcMethodInfo) {
+	requestHandle := request.requestHandle
+	localReply := request.localReply
+	invoked := false
+
+	stream := request.stream
+	invoke := func(ctx context.Context, args interface{}) (interface{}, error) {
+		invoked = true
+		if v.hasStream == true {
+			return nil, handler.callStreamMethod(v, ctx, stream, args)
+		}
+		if v.invoker != nil {
+			return v.invoke(handler.GetRpcHandler(), ctx, args, localReply)
+		}
+
+		paramList := make([]reflect.Value, 0, 5)
+		paramList = append(paramList, reflect.ValueOf(handler.GetRpcHandler())) //接受者
+		if v.hasContext == true {
+			paramList = append(paramList, reflect.ValueOf(ctx))
+		}
+		if v.hasResponder == true {
+			if requestHandle != nil {
+				paramList = append(paramList, reflect.ValueOf(requestHandle))
+			} else {
+				paramList = append(paramList, requestHandlerNull)
+			}
+		}
+
+		paramList = append(paramList, reflect.ValueOf(args))
+		var oParam reflect.Value
+		if v.outParamValue.IsValid() {
+			if localReply != nil {
+				oParam = reflect.ValueOf(localReply)
+			} else {
+				oParam = reflect.New(v.outParamValue.Type().Elem())
+			}
+			paramList = append(paramList, oParam)
+		}
+
+		returnValues := v.method.Func.Call(paramList)
+		if v.hasResponder == true {
+			return nil, nil
+		}
+
+		var err error
+		if errInter := returnValues[0].Interface(); errInter != nil {
+			err = errInter.(error)
+		}
+
+		if oParam.IsValid() {
+			return oParam.Interface(), err
+		}
+
+		return nil, err
+	}
+
+	serviceMethod := request.RpcRequestData.GetServiceMethod()
+	reply, err := chainServerInterceptors(handler.serverInterceptors, serviceMethod, invoke)(request.getContext(), request.inParam)
+	if requestHandle == nil {
+		return
+	}
+
+	//带Responder的函数已被调用时，由函数自己返回结果
+	if v.hasResponder == true && invoked == true {
+		if err != nil {
+			log.Error("server interceptor return error after responder called", log.String("serviceMethod", serviceMethod), log.ErrorField("error", err))
+		}
+		return
+	}
+
+	//流式函数已被调用时，由函数自己结束，出错时以错误结束
+	if v.hasStream == true && invoked == true {
+		if err != nil {
+			stream.Close(err)
+		}
+		return
+	}
+
+	requestHandle(reply, err)
+}
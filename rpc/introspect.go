@@ -0,0 +1,67 @@
+package rpc
+
+import (
+	"slices"
+	"strings"
+)
+
+// RpcMethodDesc RPC函数的描述，用于工具查询结点提供的函数
+type RpcMethodDesc struct {
+	Method        string           //函数名，如RPC_Sum
+	InParam       string           //参数类型，如*rpc.InvokerReq
+	OutParam      string           //返回类型，Responder函数与流式函数为空
+	ProcessorType RpcProcessorType //参数的序列化方式，0为json，1为pb
+	Context       bool             //第一个参数为context.Context
+	Responder     bool             //通过RequestHandler异步返回
+	Stream        bool             //流式函数
+	NoReflect     bool             //通过RegisterRpcMethod注册，调用时不使用反射
+}
+
+// RpcServiceDesc 服务的RPC函数与RegRawRpc注册的原始RPC id
+type RpcServiceDesc struct {
+	Name      string
+	Methods   []RpcMethodDesc
+	RawRpcIds []uint32
+}
+
+func (processorType RpcProcessorType) String() string {
+	switch processorType {
+	case RpcProcessorJson:
+		return "json"
+	case RpcProcessorPB:
+		return "pb"
+	}
+
+	return "unknown"
+}
+
+// DescribeRpc 返回服务的RPC函数描述，按函数名排序
+func (handler *RpcHandler) DescribeRpc() RpcServiceDesc {
+	desc := RpcServiceDesc{Name: handler.GetName()}
+	prefix := desc.Name + "."
+	for serviceMethod, v := range handler.mapFunctions {
+		methodDesc := RpcMethodDesc{
+			Method:        strings.TrimPrefix(serviceMethod, prefix),
+			InParam:       v.inParamValue.Type().String(),
+			ProcessorType: v.rpcProcessorType,
+			Context:       v.hasContext,
+			Responder:     v.hasResponder,
+			Stream:        v.hasStream,
+			NoReflect:     v.invoker != nil,
+		}
+		if v.outParamValue.IsValid() {
+			methodDesc.OutParam = v.outParamValue.Type().String()
+		}
+		desc.Methods = append(desc.Methods, methodDesc)
+	}
+	slices.SortFunc(desc.Methods, func(a, b RpcMethodDesc) int {
+		return strings.Compare(a.Method, b.Method)
+	})
+
+	for rpcMethodId := range handler.mapRawFunctions {
+		desc.RawRpcIds = append(desc.RawRpcIds, rpcMethodId)
+	}
+	slices.Sort(desc.RawRpcIds)
+
+	return desc
+}
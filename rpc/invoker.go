@@ -0,0 +1,169 @@
+package rpc
+
+import (
+	"context"
+	"reflect"
+	"sync"
+)
+
+// methodInvoker 免反射调用RPC函数，recv为服务
+type methodInvoker func(recv interface{}, ctx context.Context, args interface{}, reply interface{}) error
+
+// callbackInvoker 免反射调用异步回调
+type callbackInvoker func(fn interface{}, reply interface{}, err error)
+
+type methodInvokerInfo struct {
+	recvType reflect.Type //接收者类型，可以是服务实现的接口
+	fnType   reflect.Type
+	invoker  methodInvoker
+}
+
+var invokerLocker sync.RWMutex
+var mapMethodInvoker = map[string][]methodInvokerInfo{}     //key为RPC函数名
+var mapCallbackInvoker = map[reflect.Type]callbackInvoker{} //key为回调函数类型
+var mapNewParam = map[reflect.Type]func() interface{}{}     //key为参数的指针类型
+
+// RegisterRpcMethod 注册免反射调用的RPC函数，method为方法表达式，如(*MyService).RPC_Sum或IMyServiceServer.RPC_Sum，
+// 接收者为接口时对所有实现了该接口的服务生效。需在服务初始化(RegisterRpc)之前调用，一般放在init中。
+// 同时注册的参数类型在创建参数与调用异步回调func(*Res, error)时也不再使用反射。
+// protoc-gen-origin生成的代码会自动注册，未注册的函数与带Responder的函数仍通过反射调用
+func RegisterRpcMethod[S any, Req any, Res any](methodName string, method func(S, *Req, *Res) error) {
+	registerRpcMethod[S, Req, Res](methodName, reflect.TypeOf(method), func(recv interface{}, _ context.Context, args interface{}, reply interface{}) error {
+		return method(recv.(S), args.(*Req), reply.(*Res))
+	})
+}
+
+// RegisterRpcContextMethod 注册第一个参数为context.Context的免反射调用RPC函数，用法同RegisterRpcMethod
+func RegisterRpcContextMethod[S any, Req any, Res any](methodName string, method func(S, context.Context, *Req, *Res) error) {
+	registerRpcMethod[S, Req, Res](methodName, reflect.TypeOf(method), func(recv interface{}, ctx context.Context, args interface{}, reply interface{}) error {
+		return method(recv.(S), ctx, args.(*Req), reply.(*Res))
+	})
+}
+
+func registerRpcMethod[S any, Req any, Res any](methodName string, fnType reflect.Type, invoker methodInvoker) {
+	invokerLocker.Lock()
+	defer invokerLocker.Unlock()
+
+	info := methodInvokerInfo{recvType: reflect.TypeOf((*S)(nil)).Elem(), fnType: fnType, invoker: invoker}
+	mapMethodInvoker[methodName] = append(mapMethodInvoker[methodName], info)
+	mapNewParam[reflect.TypeOf((*Req)(nil))] = func() interface{} { return new(Req) }
+	setCallbackType[Res]()
+}
+
+// setCallbackType 注册func(*Res, error)回调与*Res的创建函数，调用前需加锁
+func setCallbackType[Res any]() {
+	mapCallbackInvoker[reflect.TypeOf((func(*Res, error))(nil))] = func(fn interface{}, reply interface{}, err error) {
+		res, _ := reply.(*Res)
+		fn.(func(*Res, error))(res, err)
+	}
+	mapNewParam[reflect.TypeOf((*Res)(nil))] = func() interface{} { return new(Res) }
+}
+
+// registerCallbackType 回调类型未注册时注册
+func registerCallbackType[Res any]() {
+	cbType := reflect.TypeOf((func(*Res, error))(nil))
+	invokerLocker.RLock()
+	_, ok := mapCallbackInvoker[cbType]
+	invokerLocker.RUnlock()
+	if ok == true {
+		return
+	}
+
+	invokerLocker.Lock()
+	setCallbackType[Res]()
+	invokerLocker.Unlock()
+}
+
+// getMethodInvoker 查找与服务函数匹配的invoker，接收者与参数类型都一致才使用
+func getMethodInvoker(method reflect.Method) methodInvoker {
+	invokerLocker.RLock()
+	defer invokerLocker.RUnlock()
+
+	recvType := method.Type.In(0)
+	for _, info := range mapMethodInvoker[method.Name] {
+		if info.recvType != recvType && (info.recvType.Kind() != reflect.Interface || recvType.Implements(info.recvType) == false) {
+			continue
+		}
+
+		if sameFuncSignature(info.fnType, method.Type) == true {
+			return info.invoker
+		}
+	}
+
+	return nil
+}
+
+// sameFuncSignature 比较除接收者外的参数与返回值类型
+func sameFuncSignature(a reflect.Type, b reflect.Type) bool {
+	if a.NumIn() != b.NumIn() || a.NumOut() != b.NumOut() {
+		return false
+	}
+
+	for i := 1; i < a.NumIn(); i++ {
+		if a.In(i) != b.In(i) {
+			return false
+		}
+	}
+
+	for i := 0; i < a.NumOut(); i++ {
+		if a.Out(i) != b.Out(i) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// getNewParam 返回创建参数的函数，typ为参数的指针类型，未注册时通过反射创建
+func getNewParam(typ reflect.Type) func() interface{} {
+	invokerLocker.RLock()
+	newParam, ok := mapNewParam[typ]
+	invokerLocker.RUnlock()
+	if ok == true {
+		return newParam
+	}
+
+	elem := typ.Elem()
+	return func() interface{} {
+		return reflect.New(elem).Interface()
+	}
+}
+
+// newReplyParam 创建异步回调的返回参数
+func newReplyParam(callback reflect.Value) interface{} {
+	typ := callback.Type().In(0)
+	invokerLocker.RLock()
+	newParam, ok := mapNewParam[typ]
+	invokerLocker.RUnlock()
+	if ok == true {
+		return newParam()
+	}
+
+	return reflect.New(typ.Elem()).Interface()
+}
+
+// invokeCallback 调用异步回调，回调类型已注册时免反射调用
+func invokeCallback(callback reflect.Value, reply interface{}, err error) {
+	invokerLocker.RLock()
+	cbInvoker, ok := mapCallbackInvoker[callback.Type()]
+	invokerLocker.RUnlock()
+	if ok == true {
+		cbInvoker(callback.Interface(), reply, err)
+		return
+	}
+
+	if err == nil {
+		callback.Call([]reflect.Value{reflect.ValueOf(reply), nilError})
+	} else {
+		callback.Call([]reflect.Value{reflect.ValueOf(reply), reflect.ValueOf(err)})
+	}
+}
+
+// invoke 免反射调用RPC函数，reply为nil时创建返回参数
+func (v *RpcMethodInfo) invoke(recv interface{}, ctx context.Context, args interface{}, reply interface{}) (interface{}, error) {
+	if reply == nil {
+		reply = v.newOutParam()
+	}
+
+	return reply, v.invoker(recv, ctx, args, reply)
+}
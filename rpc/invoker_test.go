@@ -0,0 +1,172 @@
+package rpc
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+type InvokerReq struct {
+	A int
+	B int
+}
+
+type InvokerRes struct {
+	Sum int
+}
+
+// InvokerRawRes 未注册的类型，回调通过反射调用
+type InvokerRawRes struct {
+	Sum int
+}
+
+type invokerService struct {
+	RpcHandler
+}
+
+func (s *invokerService) GetName() string {
+	return "InvokerService"
+}
+
+func (s *invokerService) RPC_Sum(req *InvokerReq, res *InvokerRes) error {
+	res.Sum = req.A + req.B
+	return nil
+}
+
+func (s *invokerService) RPC_SumContext(ctx context.Context, req *InvokerReq, res *InvokerRes) error {
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+
+	res.Sum = req.A + req.B
+	return nil
+}
+
+type IInvokerServer interface {
+	RPC_SumContext(ctx context.Context, req *InvokerReq, res *InvokerRes) error
+}
+
+func init() {
+	RegisterRpcMethod("RPC_Sum", (*invokerService).RPC_Sum)
+	RegisterRpcContextMethod("RPC_SumContext", IInvokerServer.RPC_SumContext)
+}
+
+func newInvokerService() *invokerService {
+	s := &invokerService{}
+	s.InitRpcHandler(s, nil, nil, nil)
+
+	//复制一份不带invoker的函数，用于对比反射调用
+	for _, name := range []string{"Sum", "SumContext"} {
+		v := s.mapFunctions["InvokerService.RPC_"+name]
+		v.invoker = nil
+		s.mapFunctions["InvokerService.Reflect_"+name] = v
+	}
+
+	return s
+}
+
+func newInvokerRequest(serviceMethod string, requestHandle RequestHandler) *RpcRequest {
+	request := &RpcRequest{}
+	request.RpcRequestData = (&JsonProcessor{}).MakeRpcRequest(1, 0, serviceMethod, false, nil)
+	request.inParam = &InvokerReq{A: 1, B: 2}
+	request.requestHandle = requestHandle
+	request.parentCtx = context.Background()
+	return request
+}
+
+func TestRegisterRpcType(t *testing.T) {
+	s := newInvokerService()
+	for _, name := range []string{"Sum", "SumContext"} {
+		if s.mapFunctions["InvokerService.RPC_"+name].invoker == nil {
+			t.Fatalf("RPC_%s has no invoker", name)
+		}
+
+		for _, serviceMethod := range []string{"InvokerService.RPC_" + name, "InvokerService.Reflect_" + name} {
+			var reply interface{}
+			var err error = errors.New("not called")
+			s.HandlerRpcRequest(newInvokerRequest(serviceMethod, func(Returns interface{}, Err error) {
+				reply, err = Returns, Err
+			}))
+
+			res, ok := reply.(*InvokerRes)
+			if err != nil || ok == false || res.Sum != 3 {
+				t.Fatalf("%s return %v %v", serviceMethod, reply, err)
+			}
+		}
+	}
+
+	var res InvokerRes
+	if err := s.CallMethod(context.Background(), NewLClient("", &CallSet{}), "InvokerService.RPC_Sum", &InvokerReq{A: 2, B: 3}, requestHandlerNull, &res); err != nil || res.Sum != 5 {
+		t.Fatalf("CallMethod return %v %v", res, err)
+	}
+
+	param, err := s.UnmarshalInParam(&JsonProcessor{}, "InvokerService.RPC_Sum", 0, []byte(`{"A":1}`))
+	if req, ok := param.(*InvokerReq); err != nil || ok == false || req.A != 1 {
+		t.Fatalf("UnmarshalInParam return %v %v", param, err)
+	}
+
+	called := 0
+	typedCallback := func(res *InvokerRes, err error) {
+		if res.Sum == 3 && err == nil {
+			called++
+		}
+	}
+	rawCallback := func(res *InvokerRawRes, err error) {
+		if res.Sum == 3 && err == nil {
+			called++
+		}
+	}
+	invokeCallback(reflect.ValueOf(typedCallback), &InvokerRes{Sum: 3}, nil)
+	invokeCallback(reflect.ValueOf(rawCallback), &InvokerRawRes{Sum: 3}, nil)
+	if called != 2 {
+		t.Fatalf("callback called %d", called)
+	}
+}
+
+func benchmarkHandlerRpcRequest(b *testing.B, serviceMethod string) {
+	s := newInvokerService()
+	request := newInvokerRequest(serviceMethod, func(Returns interface{}, Err error) {})
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		s.HandlerRpcRequest(request)
+	}
+}
+
+func BenchmarkHandlerRpcRequestTyped(b *testing.B) {
+	benchmarkHandlerRpcRequest(b, "InvokerService.RPC_Sum")
+}
+
+func BenchmarkHandlerRpcRequestReflect(b *testing.B) {
+	benchmarkHandlerRpcRequest(b, "InvokerService.Reflect_Sum")
+}
+
+func BenchmarkHandlerRpcRequestContextTyped(b *testing.B) {
+	benchmarkHandlerRpcRequest(b, "InvokerService.RPC_SumContext")
+}
+
+func BenchmarkHandlerRpcRequestContextReflect(b *testing.B) {
+	benchmarkHandlerRpcRequest(b, "InvokerService.Reflect_SumContext")
+}
+
+func BenchmarkCallbackTyped(b *testing.B) {
+	callback := reflect.ValueOf(func(res *InvokerRes, err error) {})
+	reply := &InvokerRes{}
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		invokeCallback(callback, reply, nil)
+	}
+}
+
+func BenchmarkCallbackReflect(b *testing.B) {
+	callback := reflect.ValueOf(func(res *InvokerRawRes, err error) {})
+	reply := &InvokerRawRes{}
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		invokeCallback(callback, reply, nil)
+	}
+}
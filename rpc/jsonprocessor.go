@@ -17,6 +17,12 @@ type JsonRpcRequestData struct {
 	rpcMethodId   uint32
 	ServiceMethod string   // format: "Service.Method"
 	NoReply       bool           //是否需要返回
+	Timeout       int64          //调用方剩余的超时时间(毫秒)，0表示不限制
+	Cancel        bool           //调用方取消了Seq对应的请求
+	Metadata      map[string]string //附加信息，如TraceId、调用方服务名等
+	StreamWindow  uint32         //流式调用时调用方的初始接收窗口，大于0表示流式调用
+	StreamCredit  uint32         //调用方增加的接收窗口
+	CallerNodeId  string         //调用方结点Id
 	//packbody
 	InParam      []byte
 }
@@ -25,6 +31,10 @@ type JsonRpcResponseData struct {
 	//head
 	Seq           uint64   // sequence number chosen by client
 	Err string
+	ErrCode int32
+	ErrDetails []byte
+	Metadata map[string]string
+	StreamMsg bool //流式调用中的一条消息
 
 	//returns
 	Reply []byte
@@ -53,14 +63,22 @@ func (jsonProcessor *JsonProcessor) MakeRpcRequest(seq uint64,rpcMethodId uint32
 	jsonRpcRequestData.ServiceMethod = serviceMethod
 	jsonRpcRequestData.NoReply = noReply
 	jsonRpcRequestData.InParam = inParam
+	jsonRpcRequestData.Timeout = 0
+	jsonRpcRequestData.Cancel = false
+	jsonRpcRequestData.Metadata = nil
+	jsonRpcRequestData.StreamWindow = 0
+	jsonRpcRequestData.StreamCredit = 0
+	jsonRpcRequestData.CallerNodeId = ""
 	return jsonRpcRequestData
 }
 
-func (jsonProcessor *JsonProcessor) MakeRpcResponse(seq uint64,err RpcError,reply []byte) IRpcResponseData {
+func (jsonProcessor *JsonProcessor) MakeRpcResponse(seq uint64,err error,reply []byte) IRpcResponseData {
 	jsonRpcResponseData := rpcJsonResponseDataPool.Get().(*JsonRpcResponseData)
 	jsonRpcResponseData.Seq = seq
-	jsonRpcResponseData.Err = err.Error()
+	jsonRpcResponseData.Err, jsonRpcResponseData.ErrCode, jsonRpcResponseData.ErrDetails = marshalError(err)
 	jsonRpcResponseData.Reply = reply
+	jsonRpcResponseData.Metadata = nil
+	jsonRpcResponseData.StreamMsg = false
 
 	return jsonRpcResponseData
 }
@@ -86,6 +104,58 @@ func (jsonRpcRequestData *JsonRpcRequestData) IsNoReply() bool{
 	return jsonRpcRequestData.NoReply
 }
 
+func (jsonRpcRequestData *JsonRpcRequestData) GetTimeout() int64{
+	return jsonRpcRequestData.Timeout
+}
+
+func (jsonRpcRequestData *JsonRpcRequestData) IsCancel() bool{
+	return jsonRpcRequestData.Cancel
+}
+
+func (jsonRpcRequestData *JsonRpcRequestData) SetTimeout(timeout int64){
+	jsonRpcRequestData.Timeout = timeout
+}
+
+func (jsonRpcRequestData *JsonRpcRequestData) SetCancel(cancel bool){
+	jsonRpcRequestData.Cancel = cancel
+}
+
+func (jsonRpcRequestData *JsonRpcRequestData) GetMetadata() map[string]string{
+	return jsonRpcRequestData.Metadata
+}
+
+func (jsonRpcRequestData *JsonRpcRequestData) SetMetadata(metadata map[string]string){
+	jsonRpcRequestData.Metadata = metadata
+}
+
+func (jsonRpcRequestData *JsonRpcRequestData) GetStreamWindow() uint32{
+	return jsonRpcRequestData.StreamWindow
+}
+
+func (jsonRpcRequestData *JsonRpcRequestData) GetStreamCredit() uint32{
+	return jsonRpcRequestData.StreamCredit
+}
+
+func (jsonRpcRequestData *JsonRpcRequestData) SetStreamWindow(window uint32){
+	jsonRpcRequestData.StreamWindow = window
+}
+
+func (jsonRpcRequestData *JsonRpcRequestData) SetStreamCredit(credit uint32){
+	jsonRpcRequestData.StreamCredit = credit
+}
+
+func (jsonRpcRequestData *JsonRpcRequestData) GetCallerNodeId() string{
+	return jsonRpcRequestData.CallerNodeId
+}
+
+func (jsonRpcRequestData *JsonRpcRequestData) SetCallerNodeId(nodeId string){
+	jsonRpcRequestData.CallerNodeId = nodeId
+}
+
+func (jsonRpcRequestData *JsonRpcRequestData) SetSeq(seq uint64){
+	jsonRpcRequestData.Seq = seq
+}
+
 func (jsonRpcRequestData *JsonRpcRequestData) GetSeq() uint64{
 	return jsonRpcRequestData.Seq
 }
@@ -106,19 +176,30 @@ func (jsonRpcResponseData *JsonRpcResponseData)	GetSeq() uint64 {
 	return jsonRpcResponseData.Seq
 }
 
-func (jsonRpcResponseData *JsonRpcResponseData)		GetErr() *RpcError {
-	if jsonRpcResponseData.Err == ""{
-		return nil
-	}
-
-	err := RpcError(jsonRpcResponseData.Err)
-	return &err
+func (jsonRpcResponseData *JsonRpcResponseData)		GetErr() error {
+	return unmarshalError(jsonRpcResponseData.Err, jsonRpcResponseData.ErrCode, jsonRpcResponseData.ErrDetails)
 }
 
 func (jsonRpcResponseData *JsonRpcResponseData)		GetReply() []byte{
 	return jsonRpcResponseData.Reply
 }
 
+func (jsonRpcResponseData *JsonRpcResponseData) GetMetadata() map[string]string{
+	return jsonRpcResponseData.Metadata
+}
+
+func (jsonRpcResponseData *JsonRpcResponseData) SetMetadata(metadata map[string]string){
+	jsonRpcResponseData.Metadata = metadata
+}
+
+func (jsonRpcResponseData *JsonRpcResponseData) IsStreamMsg() bool{
+	return jsonRpcResponseData.StreamMsg
+}
+
+func (jsonRpcResponseData *JsonRpcResponseData) SetStreamMsg(streamMsg bool){
+	jsonRpcResponseData.StreamMsg = streamMsg
+}
+
 
 func (jsonProcessor *JsonProcessor) Clone(src interface{}) (interface{},error){
 	dstValue := reflect.New(reflect.ValueOf(src).Type().Elem())
@@ -1,9 +1,9 @@
 package rpc
 
 import (
+	"context"
 	"errors"
 	"github.com/duanhf2012/origin/v2/log"
-	"github.com/duanhf2012/origin/v2/network"
 	"reflect"
 	"strings"
 	"sync/atomic"
@@ -21,23 +21,14 @@ func (lc *LClient) Lock() {
 func (lc *LClient) Unlock() {
 }
 
-func (lc *LClient) Run() {
-}
-
-func (lc *LClient) OnClose() {
-}
-
 func (lc *LClient) IsConnected() bool {
 	return true
 }
 
-func (lc *LClient) SetConn(conn *network.NetConn) {
-}
-
 func (lc *LClient) Close(waitDone bool) {
 }
 
-func (lc *LClient) Go(nodeId string, timeout time.Duration, rpcHandler IRpcHandler, noReply bool, serviceMethod string, args interface{}, reply interface{}) *Call {
+func (lc *LClient) Go(ctx context.Context, nodeId string, timeout time.Duration, rpcHandler IRpcHandler, noReply bool, serviceMethod string, args interface{}, reply interface{}) *Call {
 	pLocalRpcServer := rpcHandler.GetRpcServer()()
 	//判断是否是同一服务
 	findIndex := strings.Index(serviceMethod, ".")
@@ -53,7 +44,7 @@ func (lc *LClient) Go(nodeId string, timeout time.Duration, rpcHandler IRpcHandl
 	serviceName := serviceMethod[:findIndex]
 	if serviceName == rpcHandler.GetName() { //自己服务调用
 		//调用自己rpcHandler处理器
-		err := pLocalRpcServer.myselfRpcHandlerGo(lc.selfClient, serviceName, serviceMethod, args, requestHandlerNull, reply)
+		err := pLocalRpcServer.myselfRpcHandlerGo(ctx, lc.selfClient, serviceName, serviceMethod, args, requestHandlerNull, reply)
 		call := MakeCall()
 
 		if err != nil {
@@ -66,7 +57,7 @@ func (lc *LClient) Go(nodeId string, timeout time.Duration, rpcHandler IRpcHandl
 	}
 
 	//其他的rpcHandler的处理器
-	return pLocalRpcServer.selfNodeRpcHandlerGo(timeout, nil, lc.selfClient, noReply, serviceName, 0, serviceMethod, args, reply, nil)
+	return pLocalRpcServer.selfNodeRpcHandlerGo(ctx, timeout, nil, lc.selfClient, noReply, serviceName, 0, serviceMethod, args, reply, nil)
 }
 
 func (lc *LClient) RawGo(nodeId string, timeout time.Duration, rpcHandler IRpcHandler, processor IRpcProcessor, noReply bool, rpcMethodId uint32, serviceName string, rawArgs []byte, reply interface{}) *Call {
@@ -79,7 +70,7 @@ func (lc *LClient) RawGo(nodeId string, timeout time.Duration, rpcHandler IRpcHa
 		call.Reply = reply
 		call.TimeOut = timeout
 
-		err := pLocalRpcServer.myselfRpcHandlerGo(lc.selfClient, serviceName, serviceName, rawArgs, requestHandlerNull, nil)
+		err := pLocalRpcServer.myselfRpcHandlerGo(nil, lc.selfClient, serviceName, serviceName, rawArgs, requestHandlerNull, nil)
 		call.Err = err
 		call.done <- call
 
@@ -87,10 +78,10 @@ func (lc *LClient) RawGo(nodeId string, timeout time.Duration, rpcHandler IRpcHa
 	}
 
 	//其他的rpcHandler的处理器
-	return pLocalRpcServer.selfNodeRpcHandlerGo(timeout, processor, lc.selfClient, true, serviceName, rpcMethodId, serviceName, nil, nil, rawArgs)
+	return pLocalRpcServer.selfNodeRpcHandlerGo(nil, timeout, processor, lc.selfClient, true, serviceName, rpcMethodId, serviceName, nil, nil, rawArgs)
 }
 
-func (lc *LClient) AsyncCall(nodeId string, timeout time.Duration, rpcHandler IRpcHandler, serviceMethod string, callback reflect.Value, args interface{}, reply interface{}) (CancelRpc, error) {
+func (lc *LClient) AsyncCall(ctx context.Context, nodeId string, timeout time.Duration, rpcHandler IRpcHandler, serviceMethod string, callback reflect.Value, args interface{}, reply interface{}) (CancelRpc, error) {
 	pLocalRpcServer := rpcHandler.GetRpcServer()()
 
 	//判断是否是同一服务
@@ -105,11 +96,11 @@ func (lc *LClient) AsyncCall(nodeId string, timeout time.Duration, rpcHandler IR
 	serviceName := serviceMethod[:findIndex]
 	//调用自己rpcHandler处理器
 	if serviceName == rpcHandler.GetName() { //自己服务调用
-		return emptyCancelRpc, pLocalRpcServer.myselfRpcHandlerGo(lc.selfClient, serviceName, serviceMethod, args, callback, reply)
+		return emptyCancelRpc, pLocalRpcServer.myselfRpcHandlerGo(ctx, lc.selfClient, serviceName, serviceMethod, args, callback, reply)
 	}
 
 	//其他的rpcHandler的处理器
-	cancelRpc, err := pLocalRpcServer.selfNodeRpcHandlerAsyncGo(timeout, lc.selfClient, rpcHandler, false, serviceName, serviceMethod, args, reply, callback)
+	cancelRpc, err := pLocalRpcServer.selfNodeRpcHandlerAsyncGo(ctx, timeout, lc.selfClient, rpcHandler, false, serviceName, serviceMethod, args, reply, callback)
 	if err != nil {
 		callback.Call([]reflect.Value{reflect.ValueOf(reply), reflect.ValueOf(err)})
 	}
@@ -117,6 +108,41 @@ func (lc *LClient) AsyncCall(nodeId string, timeout time.Duration, rpcHandler IR
 	return cancelRpc, nil
 }
 
+// StreamCall 本结点其他服务的流式调用，不支持服务自我调用
+func (lc *LClient) StreamCall(ctx context.Context, nodeId string, timeout time.Duration, rpcHandler IRpcHandler, serviceMethod string, callback reflect.Value, args interface{}, reply interface{}, window uint32) (CancelRpc, error) {
+	pLocalRpcServer := rpcHandler.GetRpcServer()()
+
+	findIndex := strings.Index(serviceMethod, ".")
+	if findIndex == -1 {
+		err := errors.New("Call serviceMethod " + serviceMethod + " is error!")
+		callback.Call([]reflect.Value{reflect.ValueOf(reply), reflect.ValueOf(err)})
+		log.Error("serviceMethod format is error", log.String("error", err.Error()))
+		return emptyCancelRpc, nil
+	}
+
+	serviceName := serviceMethod[:findIndex]
+	if serviceName == rpcHandler.GetName() {
+		err := NewError(CodeInvalidParam, "stream call "+serviceMethod+" from the same service is not supported")
+		callback.Call([]reflect.Value{reflect.ValueOf(reply), reflect.ValueOf(err)})
+		return emptyCancelRpc, nil
+	}
+
+	cancelRpc, err := pLocalRpcServer.selfNodeRpcHandlerStream(ctx, timeout, lc.selfClient, rpcHandler, serviceName, serviceMethod, args, reply, callback, window)
+	if err != nil {
+		callback.Call([]reflect.Value{reflect.ValueOf(reply), reflect.ValueOf(err)})
+	}
+
+	return cancelRpc, nil
+}
+
+// SendStreamCredit 本结点的流式调用直接增加被调用方的窗口，无需发送
+func (lc *LClient) SendStreamCredit(nodeId string, callSeq uint64, connId int, credit uint32) {
+}
+
+// SendCancel 本结点的请求在调用方取消后，通过等待队列判断是否丢弃，无需发送取消帧
+func (lc *LClient) SendCancel(nodeId string, callSeq uint64, connId int) {
+}
+
 func NewLClient(localNodeId string, callSet *CallSet) *Client {
 	client := &Client{}
 	client.clientId = atomic.AddUint32(&clientSeq, 1)
@@ -1,11 +1,14 @@
 package rpc
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"github.com/duanhf2012/origin/v2/log"
+	"io"
 	"reflect"
 	"strings"
+	"sync"
 	"time"
 )
 
@@ -15,6 +18,10 @@ type BaseServer struct {
 
 	rpcHandleFinder RpcHandleFinder
 	iServer         IServer
+	rateLimits      rateLimitSet //本结点服务的限流
+
+	requestLocker sync.Mutex
+	mapRequest    map[requestKey]*RpcRequest //等待处理的跨结点请求，用于处理取消帧
 }
 
 func (server *BaseServer) initBaseServer(compressBytesLen int, rpcHandleFinder RpcHandleFinder) {
@@ -22,26 +29,41 @@ func (server *BaseServer) initBaseServer(compressBytesLen int, rpcHandleFinder R
 	server.rpcHandleFinder = rpcHandleFinder
 }
 
-func (server *BaseServer) myselfRpcHandlerGo(client *Client, handlerName string, serviceMethod string, args interface{}, callBack reflect.Value, reply interface{}) error {
+// SetLocalNodeId 设置本结点Id，同一进程运行多个结点时每个Server单独设置，需在Start之前调用
+func (server *BaseServer) SetLocalNodeId(nodeId string) {
+	server.localNodeId = nodeId
+}
+
+// GetLocalNodeId 返回本结点Id，未设置时使用rpc.SetLocalNodeId设置的Id
+func (server *BaseServer) GetLocalNodeId() string {
+	if server.localNodeId != "" {
+		return server.localNodeId
+	}
+
+	return localNodeId
+}
+
+func (server *BaseServer) myselfRpcHandlerGo(ctx context.Context, client *Client, handlerName string, serviceMethod string, args interface{}, callBack reflect.Value, reply interface{}) error {
 	rpcHandler := server.rpcHandleFinder.FindRpcHandler(handlerName)
 	if rpcHandler == nil {
-		err := errors.New("service method " + serviceMethod + " not config!")
+		err := NewError(CodeMethodNotFound, "service method "+serviceMethod+" not config!")
 		log.Error("service method not config", log.String("serviceMethod", serviceMethod))
 		return err
 	}
 
-	return rpcHandler.CallMethod(client, serviceMethod, args, callBack, reply)
+	return rpcHandler.CallMethod(ctx, client, serviceMethod, args, callBack, reply)
 }
 
-func (server *BaseServer) selfNodeRpcHandlerGo(timeout time.Duration, processor IRpcProcessor, client *Client, noReply bool, handlerName string, rpcMethodId uint32, serviceMethod string, args interface{}, reply interface{}, rawArgs []byte) *Call {
+func (server *BaseServer) selfNodeRpcHandlerGo(ctx context.Context, timeout time.Duration, processor IRpcProcessor, client *Client, noReply bool, handlerName string, rpcMethodId uint32, serviceMethod string, args interface{}, reply interface{}, rawArgs []byte) *Call {
 	pCall := MakeCall()
 	pCall.Seq = client.generateSeq()
 	pCall.TimeOut = timeout
 	pCall.ServiceMethod = serviceMethod
+	pCall.nodeId = client.GetTargetNodeId()
 
 	rpcHandler := server.rpcHandleFinder.FindRpcHandler(handlerName)
 	if rpcHandler == nil {
-		err := errors.New("service method " + serviceMethod + " not config!")
+		err := NewError(CodeMethodNotFound, "service method "+serviceMethod+" not config!")
 		log.Error("service method not config", log.String("serviceMethod", serviceMethod), log.ErrorField("error", err))
 		pCall.Seq = 0
 		pCall.DoError(err)
@@ -68,6 +90,8 @@ func (server *BaseServer) selfNodeRpcHandlerGo(timeout time.Duration, processor
 	}
 
 	req := MakeRpcRequest(processor, 0, rpcMethodId, serviceMethod, noReply, nil)
+	req.RpcRequestData.SetMetadata(FromOutgoingContext(ctx).Copy())
+	req.RpcRequestData.SetCallerNodeId(client.getLocalNodeId())
 	req.inParam = iParam
 	req.localReply = reply
 	if rawArgs != nil {
@@ -84,22 +108,30 @@ func (server *BaseServer) selfNodeRpcHandlerGo(timeout time.Duration, processor
 
 	if noReply == false {
 		client.AddPending(pCall)
+		client.watchContext(ctx, pCall)
 		callSeq := pCall.Seq
-		req.requestHandle = func(Returns interface{}, Err RpcError) {
+		req.parentCtx = ctx
+		req.deadline = time.Now().Add(timeout)
+		req.callClient = client
+		req.callSeq = callSeq
+		pCall.replyMetadata = replyMetadataFromContext(ctx)
+		req.requestHandle = func(Returns interface{}, Err error) {
+			Err = normalizeError(Err)
 			if reply != nil && Returns != reply && Returns != nil {
 				byteReturns, err := req.rpcProcessor.Marshal(Returns)
 				if err != nil {
-					Err = ConvertError(err)
+					Err = err
 					log.Error("returns data cannot be marshal", log.Uint64("seq", callSeq), log.ErrorField("error", err))
 				} else {
 					err = req.rpcProcessor.Unmarshal(byteReturns, reply)
 					if err != nil {
-						Err = ConvertError(err)
+						Err = err
 						log.Error("returns data cannot be Unmarshal", log.Uint64("seq", callSeq), log.ErrorField("error", err))
 					}
 				}
 			}
 
+			replyMetadata := req.getReplyMetadata()
 			ReleaseRpcRequest(req)
 			v := client.RemovePending(callSeq)
 			if v == nil {
@@ -107,7 +139,11 @@ func (server *BaseServer) selfNodeRpcHandlerGo(timeout time.Duration, processor
 				return
 			}
 
-			if len(Err) == 0 {
+			if v.replyMetadata != nil {
+				*v.replyMetadata = replyMetadata
+			}
+
+			if Err == nil {
 				v.Err = nil
 				v.DoOK()
 			} else {
@@ -127,10 +163,10 @@ func (server *BaseServer) selfNodeRpcHandlerGo(timeout time.Duration, processor
 	return pCall
 }
 
-func (server *BaseServer) selfNodeRpcHandlerAsyncGo(timeout time.Duration, client *Client, callerRpcHandler IRpcHandler, noReply bool, handlerName string, serviceMethod string, args interface{}, reply interface{}, callback reflect.Value) (CancelRpc, error) {
+func (server *BaseServer) selfNodeRpcHandlerAsyncGo(ctx context.Context, timeout time.Duration, client *Client, callerRpcHandler IRpcHandler, noReply bool, handlerName string, serviceMethod string, args interface{}, reply interface{}, callback reflect.Value) (CancelRpc, error) {
 	rpcHandler := server.rpcHandleFinder.FindRpcHandler(handlerName)
 	if rpcHandler == nil {
-		err := errors.New("service method " + serviceMethod + " not config!")
+		err := NewError(CodeMethodNotFound, "service method "+serviceMethod+" not config!")
 		log.Error(err.Error())
 		return emptyCancelRpc, err
 	}
@@ -144,6 +180,8 @@ func (server *BaseServer) selfNodeRpcHandlerAsyncGo(timeout time.Duration, clien
 	}
 
 	req := MakeRpcRequest(processor, 0, 0, serviceMethod, noReply, nil)
+	req.RpcRequestData.SetMetadata(FromOutgoingContext(ctx).Copy())
+	req.RpcRequestData.SetCallerNodeId(client.getLocalNodeId())
 	req.inParam = iParam
 	req.localReply = reply
 
@@ -158,25 +196,32 @@ func (server *BaseServer) selfNodeRpcHandlerAsyncGo(timeout time.Duration, clien
 		pCall.Reply = reply
 		pCall.ServiceMethod = serviceMethod
 		pCall.TimeOut = timeout
+		pCall.nodeId = client.GetTargetNodeId()
+		pCall.replyMetadata = replyMetadataFromContext(ctx)
 		client.AddPending(pCall)
+		client.watchContext(ctx, pCall)
 		rpcCancel := RpcCancel{CallSeq: callSeq, Cli: client}
 		cancelRpc = rpcCancel.CancelRpc
+		req.parentCtx = ctx
+		req.deadline = time.Now().Add(timeout)
+		req.callClient = client
+		req.callSeq = callSeq
 
-		req.requestHandle = func(Returns interface{}, Err RpcError) {
+		req.requestHandle = func(Returns interface{}, Err error) {
 			v := client.RemovePending(callSeq)
 			if v == nil {
 				ReleaseRpcRequest(req)
 				return
 			}
-			if len(Err) == 0 {
-				v.Err = nil
-			} else {
-				v.Err = Err
-			}
+			v.Err = normalizeError(Err)
 
 			if Returns != nil {
 				v.Reply = Returns
 			}
+
+			if v.replyMetadata != nil {
+				*v.replyMetadata = req.getReplyMetadata()
+			}
 			v.rpcHandler.PushRpcResponse(v)
 			ReleaseRpcRequest(req)
 		}
@@ -194,38 +239,104 @@ func (server *BaseServer) selfNodeRpcHandlerAsyncGo(timeout time.Duration, clien
 	return cancelRpc, nil
 }
 
-func (server *BaseServer) processRpcRequest(data []byte, connTag string, wrResponse writeResponse) error {
-	bCompress := (data[0] >> 7) > 0
-	processor := GetProcessor(data[0] & 0x7f)
-	if processor == nil {
-		return errors.New("cannot find processor")
+// selfNodeRpcHandlerStream 本结点其他服务的流式调用，消息克隆后直接投递到调用方服务
+func (server *BaseServer) selfNodeRpcHandlerStream(ctx context.Context, timeout time.Duration, client *Client, callerRpcHandler IRpcHandler, handlerName string, serviceMethod string, args interface{}, reply interface{}, callback reflect.Value, window uint32) (CancelRpc, error) {
+	rpcHandler := server.rpcHandleFinder.FindRpcHandler(handlerName)
+	if rpcHandler == nil {
+		err := NewError(CodeMethodNotFound, "service method "+serviceMethod+" not config!")
+		log.Error(err.Error())
+		return emptyCancelRpc, err
 	}
 
-	//解析head
-	var compressBuff []byte
-	byteData := data[1:]
-	if bCompress == true {
-		var unCompressErr error
-
-		compressBuff, unCompressErr = compressor.UncompressBlock(byteData)
-		if unCompressErr != nil {
-			return errors.New("uncompressBlock failed")
+	_, processor := GetProcessorType(args)
+	iParam, err := processor.Clone(args)
+	if err != nil {
+		errM := errors.New("RpcHandler " + handlerName + "." + serviceMethod + " deep copy inParam is error:" + err.Error())
+		log.Error(errM.Error())
+		return emptyCancelRpc, errM
+	}
+
+	req := MakeRpcRequest(processor, 0, 0, serviceMethod, false, nil)
+	req.RpcRequestData.SetMetadata(FromOutgoingContext(ctx).Copy())
+	req.RpcRequestData.SetCallerNodeId(client.getLocalNodeId())
+	req.inParam = iParam
+
+	callSeq := client.generateSeq()
+	stream := &clientStream{client: client, nodeId: client.GetTargetNodeId(), seq: callSeq, window: window}
+	pCall := MakeCall()
+	pCall.Seq = callSeq
+	pCall.rpcHandler = callerRpcHandler
+	pCall.callback = &callback
+	pCall.Reply = reply
+	pCall.ServiceMethod = serviceMethod
+	pCall.TimeOut = timeout
+	pCall.nodeId = client.GetTargetNodeId()
+	pCall.replyMetadata = replyMetadataFromContext(ctx)
+	pCall.stream = stream
+
+	req.parentCtx = ctx
+	req.deadline = time.Now().Add(timeout)
+	req.callClient = client
+	req.callSeq = callSeq
+	req.requestHandle = func(Returns interface{}, Err error) {
+		v := client.RemovePending(callSeq)
+		if v == nil {
+			ReleaseRpcRequest(req)
+			return
+		}
+
+		v.Err = normalizeError(Err)
+		if v.Err == nil {
+			v.Err = io.EOF
+		}
+		if v.replyMetadata != nil {
+			*v.replyMetadata = req.getReplyMetadata()
 		}
+		v.rpcHandler.PushRpcResponse(v)
+		ReleaseRpcRequest(req)
+	}
+	req.stream = newServerStream(req, window, timeout, func(msg interface{}) error {
+		return client.deliverStreamMsg(callSeq, msg)
+	})
+	stream.serverStream = req.stream
+
+	client.AddPending(pCall)
+	client.watchContext(ctx, pCall)
+	err = rpcHandler.PushRpcRequest(req)
+	if err != nil {
+		ReleaseRpcRequest(req)
+		client.RemovePending(callSeq)
+		return emptyCancelRpc, err
+	}
 
-		byteData = compressBuff
+	rpcCancel := RpcCancel{CallSeq: callSeq, Cli: client}
+	return rpcCancel.CancelRpc, nil
+}
+
+// processRpcRequest authNodeId为握手认证的调用方结点，不为空时以它为准，不使用请求中调用方自报的结点
+func (server *BaseServer) processRpcRequest(data []byte, connTag string, authNodeId string, wrResponse writeResponse, wrStreamMsg writeStreamMsg) error {
+	//解析帧头并解压缩
+	processor, byteData, release, err := uncompressFrame(data)
+	if err != nil {
+		return err
 	}
 
+	//解析head
 	req := MakeRpcRequest(processor, 0, 0, "", false, nil)
-	err := processor.Unmarshal(byteData, req.RpcRequestData)
-	if cap(compressBuff) > 0 {
-		compressor.UnCompressBufferCollection(compressBuff)
+	err = processor.Unmarshal(byteData, req.RpcRequestData)
+	if authNodeId != "" {
+		req.RpcRequestData.SetCallerNodeId(authNodeId)
+	}
+	if err == nil {
+		captureFrame(CaptureServerRequest, processor.GetProcessorType(), req.RpcRequestData.GetSeq(), req.RpcRequestData.GetCallerNodeId(), connTag, req.RpcRequestData.GetServiceMethod(), byteData)
 	}
+	release()
 
 	if err != nil {
 		if req.RpcRequestData.GetSeq() > 0 {
-			rpcError := RpcError(err.Error())
+			rpcError := NewError(CodeInvalidParam, err.Error())
 			if req.RpcRequestData.IsNoReply() == false {
-				wrResponse(processor, connTag, req.RpcRequestData.GetServiceMethod(), req.RpcRequestData.GetSeq(), nil, rpcError)
+				wrResponse(processor, connTag, req.RpcRequestData.GetCallerNodeId(), req.RpcRequestData.GetServiceMethod(), req.RpcRequestData.GetSeq(), nil, rpcError, nil)
 			}
 		}
 
@@ -233,12 +344,30 @@ func (server *BaseServer) processRpcRequest(data []byte, connTag string, wrRespo
 		return err
 	}
 
+	//调用方取消请求
+	if req.RpcRequestData.IsCancel() {
+		server.cancelRequest(connTag, req.RpcRequestData.GetSeq())
+		ReleaseRpcRequest(req)
+		return nil
+	}
+
+	//调用方归还流式调用的接收窗口
+	if credit := req.RpcRequestData.GetStreamCredit(); credit > 0 {
+		server.addStreamCredit(connTag, req.RpcRequestData.GetSeq(), credit)
+		ReleaseRpcRequest(req)
+		return nil
+	}
+
+	if timeout := req.RpcRequestData.GetTimeout(); timeout > 0 {
+		req.deadline = time.Now().Add(time.Duration(timeout) * time.Millisecond)
+	}
+
 	//交给程序处理
 	serviceMethod := strings.Split(req.RpcRequestData.GetServiceMethod(), ".")
 	if len(serviceMethod) < 1 {
-		rpcError := RpcError("rpc request req.ServiceMethod is error")
+		rpcError := NewError(CodeMethodNotFound, "rpc request req.ServiceMethod is error")
 		if req.RpcRequestData.IsNoReply() == false {
-			wrResponse(processor, connTag, req.RpcRequestData.GetServiceMethod(), req.RpcRequestData.GetSeq(), nil, rpcError)
+			wrResponse(processor, connTag, req.RpcRequestData.GetCallerNodeId(), req.RpcRequestData.GetServiceMethod(), req.RpcRequestData.GetSeq(), nil, rpcError, nil)
 		}
 		ReleaseRpcRequest(req)
 		log.Error("rpc request req.ServiceMethod is error")
@@ -247,9 +376,9 @@ func (server *BaseServer) processRpcRequest(data []byte, connTag string, wrRespo
 
 	rpcHandler := server.rpcHandleFinder.FindRpcHandler(serviceMethod[0])
 	if rpcHandler == nil {
-		rpcError := RpcError(fmt.Sprintf("service method %s not config!", req.RpcRequestData.GetServiceMethod()))
+		rpcError := NewError(CodeMethodNotFound, fmt.Sprintf("service method %s not config!", req.RpcRequestData.GetServiceMethod()))
 		if req.RpcRequestData.IsNoReply() == false {
-			wrResponse(processor, connTag, req.RpcRequestData.GetServiceMethod(), req.RpcRequestData.GetSeq(), nil, rpcError)
+			wrResponse(processor, connTag, req.RpcRequestData.GetCallerNodeId(), req.RpcRequestData.GetServiceMethod(), req.RpcRequestData.GetSeq(), nil, rpcError, nil)
 		}
 		log.Error("serviceMethod not config", log.String("serviceMethod", req.RpcRequestData.GetServiceMethod()))
 		ReleaseRpcRequest(req)
@@ -257,10 +386,19 @@ func (server *BaseServer) processRpcRequest(data []byte, connTag string, wrRespo
 	}
 
 	if req.RpcRequestData.IsNoReply() == false {
-		req.requestHandle = func(Returns interface{}, Err RpcError) {
-			wrResponse(processor, connTag, req.RpcRequestData.GetServiceMethod(), req.RpcRequestData.GetSeq(), Returns, Err)
+		req.requestHandle = func(Returns interface{}, Err error) {
+			wrResponse(processor, connTag, req.RpcRequestData.GetCallerNodeId(), req.RpcRequestData.GetServiceMethod(), req.RpcRequestData.GetSeq(), Returns, Err, req.getReplyMetadata())
 			ReleaseRpcRequest(req)
 		}
+
+		//流式调用，Close后请求会被回收，这里不再引用req
+		if window := req.RpcRequestData.GetStreamWindow(); window > 0 {
+			callerNodeId, method, seq := req.RpcRequestData.GetCallerNodeId(), req.RpcRequestData.GetServiceMethod(), req.RpcRequestData.GetSeq()
+			req.stream = newServerStream(req, window, time.Duration(req.RpcRequestData.GetTimeout())*time.Millisecond, func(msg interface{}) error {
+				return wrStreamMsg(processor, connTag, callerNodeId, method, seq, msg)
+			})
+		}
+		server.addRequest(connTag, req)
 	}
 
 	req.inParam, err = rpcHandler.UnmarshalInParam(req.rpcProcessor, req.RpcRequestData.GetServiceMethod(), req.RpcRequestData.GetRpcMethodId(), req.RpcRequestData.GetInParam())
@@ -268,7 +406,7 @@ func (server *BaseServer) processRpcRequest(data []byte, connTag string, wrRespo
 		rErr := "Call Rpc " + req.RpcRequestData.GetServiceMethod() + " Param error " + err.Error()
 		log.Error("call rpc param error", log.String("serviceMethod", req.RpcRequestData.GetServiceMethod()), log.ErrorField("error", err))
 		if req.requestHandle != nil {
-			req.requestHandle(nil, RpcError(rErr))
+			req.requestHandle(nil, NewError(CodeInvalidParam, rErr))
 		} else {
 			ReleaseRpcRequest(req)
 		}
@@ -278,10 +416,8 @@ func (server *BaseServer) processRpcRequest(data []byte, connTag string, wrRespo
 
 	err = rpcHandler.PushRpcRequest(req)
 	if err != nil {
-		rpcError := RpcError(err.Error())
-
-		if req.RpcRequestData.IsNoReply() {
-			wrResponse(processor, connTag, req.RpcRequestData.GetServiceMethod(), req.RpcRequestData.GetSeq(), nil, rpcError)
+		if req.RpcRequestData.IsNoReply() == false {
+			wrResponse(processor, connTag, req.RpcRequestData.GetCallerNodeId(), req.RpcRequestData.GetServiceMethod(), req.RpcRequestData.GetSeq(), nil, err, nil)
 		}
 
 		ReleaseRpcRequest(req)
@@ -0,0 +1,104 @@
+package rpc
+
+import (
+	"context"
+)
+
+// Metadata 随RPC请求与返回传递的附加信息，如TraceId、调用方服务名、租户、鉴权Token等
+type Metadata map[string]string
+
+type outgoingMetadataKey struct{}
+type incomingMetadataKey struct{}
+type replyMetadataKey struct{}
+type setReplyMetadataKey struct{}
+
+func (md Metadata) Get(key string) string {
+	return md[key]
+}
+
+func (md Metadata) Set(key string, value string) {
+	md[key] = value
+}
+
+func (md Metadata) Copy() Metadata {
+	if md == nil {
+		return nil
+	}
+
+	newMd := make(Metadata, len(md))
+	for k, v := range md {
+		newMd[k] = v
+	}
+
+	return newMd
+}
+
+// NewOutgoingContext 设置发出调用时携带的Metadata，配合CallContext、AsyncCallContext等使用
+func NewOutgoingContext(ctx context.Context, md Metadata) context.Context {
+	return context.WithValue(ctx, outgoingMetadataKey{}, md)
+}
+
+// AppendToOutgoingContext 在已有的发出Metadata上追加键值对，kv按key,value,key,value...排列
+func AppendToOutgoingContext(ctx context.Context, kv ...string) context.Context {
+	if len(kv)%2 == 1 {
+		panic("AppendToOutgoingContext got an odd number of kv strings")
+	}
+
+	md := FromOutgoingContext(ctx).Copy()
+	if md == nil {
+		md = make(Metadata, len(kv)/2)
+	}
+
+	for i := 0; i < len(kv); i += 2 {
+		md[kv[i]] = kv[i+1]
+	}
+
+	return NewOutgoingContext(ctx, md)
+}
+
+// FromOutgoingContext 获取发出调用时携带的Metadata
+func FromOutgoingContext(ctx context.Context) Metadata {
+	if ctx == nil {
+		return nil
+	}
+
+	md, _ := ctx.Value(outgoingMetadataKey{}).(Metadata)
+	return md
+}
+
+// FromIncomingContext RPC函数中获取调用方传来的Metadata，RPC函数第一个参数需声明为context.Context
+func FromIncomingContext(ctx context.Context) Metadata {
+	if ctx == nil {
+		return nil
+	}
+
+	md, _ := ctx.Value(incomingMetadataKey{}).(Metadata)
+	return md
+}
+
+// WithReplyMetadata 调用方接收被调用方返回的Metadata，在调用返回(或回调)前写入md
+func WithReplyMetadata(ctx context.Context, md *Metadata) context.Context {
+	return context.WithValue(ctx, replyMetadataKey{}, md)
+}
+
+func replyMetadataFromContext(ctx context.Context) *Metadata {
+	if ctx == nil {
+		return nil
+	}
+
+	md, _ := ctx.Value(replyMetadataKey{}).(*Metadata)
+	return md
+}
+
+// SetReplyMetadata RPC函数中设置返回给调用方的Metadata，需在返回或调用Responder前设置
+func SetReplyMetadata(ctx context.Context, key string, value string) {
+	md, ok := ctx.Value(setReplyMetadataKey{}).(*Metadata)
+	if ok == false {
+		return
+	}
+
+	if *md == nil {
+		*md = Metadata{}
+	}
+	(*md)[key] = value
+}
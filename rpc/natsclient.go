@@ -1,8 +1,8 @@
 package rpc
 
 import (
+	"context"
 	"github.com/duanhf2012/origin/v2/log"
-	"github.com/duanhf2012/origin/v2/network"
 	"github.com/nats-io/nats.go"
 	"reflect"
 	"time"
@@ -26,27 +26,22 @@ func (nc *NatsClient) Start(natsConn *nats.Conn) error {
 
 func (nc *NatsClient) onSubscribe(msg *nats.Msg) {
 	//处理消息
+	if hasFaultRule() == true {
+		injectFault(msg.Header.Get("fnode"), nc.localNodeId, nc.client.deliverRpcResponse, msg.Data)
+		return
+	}
 	nc.client.processRpcResponse(msg.Data)
 }
 
-func (nc *NatsClient) SetConn(conn *network.NetConn) {
-}
-
 func (nc *NatsClient) Close(waitDone bool) {
 }
 
-func (nc *NatsClient) Run() {
-}
-
-func (nc *NatsClient) OnClose() {
-}
-
 func (nc *NatsClient) Bind(server IServer) {
 	s := server.(*NatsServer)
 	nc.natsConn = s.natsConn
 }
 
-func (nc *NatsClient) Go(nodeId string, timeout time.Duration, rpcHandler IRpcHandler, noReply bool, serviceMethod string, args interface{}, reply interface{}) *Call {
+func (nc *NatsClient) Go(ctx context.Context, nodeId string, timeout time.Duration, rpcHandler IRpcHandler, noReply bool, serviceMethod string, args interface{}, reply interface{}) *Call {
 	_, processor := GetProcessorType(args)
 	InParam, err := processor.Marshal(args)
 	if err != nil {
@@ -56,15 +51,24 @@ func (nc *NatsClient) Go(nodeId string, timeout time.Duration, rpcHandler IRpcHa
 		return call
 	}
 
-	return nc.client.rawGo(nodeId, nc, timeout, rpcHandler, processor, noReply, 0, serviceMethod, InParam, reply)
+	return nc.client.rawGo(ctx, nodeId, nc, timeout, rpcHandler, processor, noReply, 0, serviceMethod, InParam, reply)
 }
 
 func (nc *NatsClient) RawGo(nodeId string, timeout time.Duration, rpcHandler IRpcHandler, processor IRpcProcessor, noReply bool, rpcMethodId uint32, serviceMethod string, rawArgs []byte, reply interface{}) *Call {
-	return nc.client.rawGo(nodeId, nc, timeout, rpcHandler, processor, noReply, rpcMethodId, serviceMethod, rawArgs, reply)
+	return nc.client.rawGo(nil, nodeId, nc, timeout, rpcHandler, processor, noReply, rpcMethodId, serviceMethod, rawArgs, reply)
+}
+
+func (nc *NatsClient) AsyncCall(ctx context.Context, nodeId string, timeout time.Duration, rpcHandler IRpcHandler, serviceMethod string, callback reflect.Value, args interface{}, replyParam interface{}) (CancelRpc, error) {
+	cancelRpc, err := nc.client.asyncCall(ctx, nodeId, nc, timeout, rpcHandler, serviceMethod, callback, args, replyParam, 0)
+	if err != nil {
+		callback.Call([]reflect.Value{reflect.ValueOf(replyParam), reflect.ValueOf(err)})
+	}
+
+	return cancelRpc, nil
 }
 
-func (nc *NatsClient) AsyncCall(nodeId string, timeout time.Duration, rpcHandler IRpcHandler, serviceMethod string, callback reflect.Value, args interface{}, replyParam interface{}) (CancelRpc, error) {
-	cancelRpc, err := nc.client.asyncCall(nodeId, nc, timeout, rpcHandler, serviceMethod, callback, args, replyParam)
+func (nc *NatsClient) StreamCall(ctx context.Context, nodeId string, timeout time.Duration, rpcHandler IRpcHandler, serviceMethod string, callback reflect.Value, args interface{}, replyParam interface{}, window uint32) (CancelRpc, error) {
+	cancelRpc, err := nc.client.asyncCall(ctx, nodeId, nc, timeout, rpcHandler, serviceMethod, callback, args, replyParam, window)
 	if err != nil {
 		callback.Call([]reflect.Value{reflect.ValueOf(replyParam), reflect.ValueOf(err)})
 	}
@@ -72,7 +76,25 @@ func (nc *NatsClient) AsyncCall(nodeId string, timeout time.Duration, rpcHandler
 	return cancelRpc, nil
 }
 
+func (nc *NatsClient) SendCancel(nodeId string, callSeq uint64, connId int) {
+	nc.client.sendCancel(nodeId, nc, callSeq, connId)
+}
+
+func (nc *NatsClient) SendStreamCredit(nodeId string, callSeq uint64, connId int, credit uint32) {
+	nc.client.sendStreamCredit(nodeId, nc, callSeq, connId, credit)
+}
+
 func (nc *NatsClient) WriteMsg(nodeId string, args ...[]byte) error {
+	if hasFaultRule() == true {
+		return injectFault(nc.localNodeId, nodeId, func(args ...[]byte) error {
+			return nc.publish(nodeId, args...)
+		}, args...)
+	}
+
+	return nc.publish(nodeId, args...)
+}
+
+func (nc *NatsClient) publish(nodeId string, args ...[]byte) error {
 	buff := make([]byte, 0, 4096)
 	for _, ar := range args {
 		buff = append(buff, ar...)
@@ -54,60 +54,66 @@ func (ns *NatsServer) Start() error {
 
 	//开始订阅
 	_, err = ns.natsConn.QueueSubscribe(ns.nodeSubTopic, "os", func(msg *nats.Msg) {
-		ns.processRpcRequest(msg.Data, msg.Header.Get("fnode"), ns.WriteResponse)
+		ns.processRpcRequest(msg.Data, msg.Header.Get("fnode"), "", ns.WriteResponse, ns.WriteStreamMsg)
 	})
 
 	return err
 }
 
-func (ns *NatsServer) WriteResponse(processor IRpcProcessor, nodeId string, serviceMethod string, seq uint64, reply interface{}, rpcError RpcError) {
+func (ns *NatsServer) WriteResponse(processor IRpcProcessor, nodeId string, callerNodeId string, serviceMethod string, seq uint64, reply interface{}, rpcError error, metadata map[string]string) {
+	ns.writeResponse(processor, nodeId, serviceMethod, seq, reply, rpcError, metadata, false)
+}
+
+// WriteStreamMsg 发送流式调用的一条消息
+func (ns *NatsServer) WriteStreamMsg(processor IRpcProcessor, nodeId string, callerNodeId string, serviceMethod string, seq uint64, msg interface{}) error {
+	return ns.writeResponse(processor, nodeId, serviceMethod, seq, msg, nil, nil, true)
+}
+
+func (ns *NatsServer) writeResponse(processor IRpcProcessor, nodeId string, serviceMethod string, seq uint64, reply interface{}, rpcError error, metadata map[string]string, streamMsg bool) error {
 	var mReply []byte
 	var err error
 
 	if reply != nil {
 		mReply, err = processor.Marshal(reply)
+		if err != nil && streamMsg == true {
+			return err
+		}
 		if err != nil {
-			rpcError = ConvertError(err)
+			rpcError = err
 		}
 	}
 
 	var rpcResponse RpcResponse
 	rpcResponse.RpcResponseData = processor.MakeRpcResponse(seq, rpcError, mReply)
+	rpcResponse.RpcResponseData.SetMetadata(metadata)
+	rpcResponse.RpcResponseData.SetStreamMsg(streamMsg)
 	bytes, err := processor.Marshal(rpcResponse.RpcResponseData)
 	defer processor.ReleaseRpcResponse(rpcResponse.RpcResponseData)
 
 	if err != nil {
 		log.Error("marshal RpcResponseData failed", log.String("serviceMethod", serviceMethod), log.ErrorField("error", err))
-		return
+		return err
 	}
 
-	var compressBuff []byte
-	bCompress := uint8(0)
-	if ns.compressBytesLen > 0 && len(bytes) >= ns.compressBytesLen {
-		compressBuff, err = compressor.CompressBlock(bytes)
-		if err != nil {
-			log.Error("CompressBlock failed", log.String("serviceMethod", serviceMethod), log.ErrorField("error", err))
-			return
-		}
-		if len(compressBuff) < len(bytes) {
-			bytes = compressBuff
-			bCompress = 1 << 7
-		}
+	captureFrame(CaptureServerResponse, processor.GetProcessorType(), seq, nodeId, nodeId, serviceMethod, bytes)
+	head, bytes, release, err := compressFrame(uint8(processor.GetProcessorType()), getCompressPolicy(nodeId, serviceMethod, ns.compressBytesLen), bytes)
+	if err != nil {
+		log.Error("CompressBlock failed", log.String("serviceMethod", serviceMethod), log.ErrorField("error", err))
+		return err
 	}
 
 	sendData := make([]byte, 0, 4096)
-	byteTypeAndCompress := []byte{uint8(processor.GetProcessorType()) | bCompress}
-	sendData = append(sendData, byteTypeAndCompress...)
+	sendData = append(sendData, head...)
 	sendData = append(sendData, bytes...)
-	err = ns.natsConn.PublishMsg(&nats.Msg{Subject: "oc." + nodeId, Data: sendData})
-
-	if cap(compressBuff) > 0 {
-		compressor.CompressBufferCollection(compressBuff)
-	}
+	//fnode用于调用方按结点注入故障
+	err = ns.natsConn.PublishMsg(&nats.Msg{Subject: "oc." + nodeId, Data: sendData, Header: nats.Header{"fnode": []string{ns.localNodeId}}})
+	release()
 
 	if err != nil {
 		log.Error("WriteMsg error,Rpc return is fail", log.String("nodeId", nodeId), log.String("serviceMethod", serviceMethod), log.ErrorField("error", err))
 	}
+
+	return err
 }
 
 func (ns *NatsServer) Stop() {
@@ -0,0 +1,83 @@
+package rpc
+
+import (
+	"errors"
+	"sync"
+
+	"github.com/duanhf2012/origin/v2/event"
+)
+
+// OverloadConfig 服务事件队列的过载保护，队列长度达到HighWater时进入过载，新的Rpc请求直接返回CodeOverload，
+// DropEventTypes中的事件被丢弃。队列长度降到LowWater时恢复，两个水位之间保持当前状态，避免反复切换
+type OverloadConfig struct {
+	HighWater      int               //进入过载的队列长度
+	LowWater       int               //恢复的队列长度，不配置时为HighWater的一半
+	DropEventTypes []event.EventType //过载时丢弃的低优先级事件，Rpc返回与系统事件不应丢弃
+}
+
+var overloadLocker sync.RWMutex
+var defaultOverloadConfig *OverloadConfig
+var mapOverloadConfig = map[string]*OverloadConfig{}
+
+// CheckOverloadConfig 检查过载保护配置，返回补全LowWater后的副本
+func CheckOverloadConfig(cfg *OverloadConfig) (*OverloadConfig, error) {
+	c := *cfg
+	if c.HighWater <= 0 {
+		return nil, errors.New("overload HighWater must be greater than 0")
+	}
+	if c.LowWater <= 0 {
+		c.LowWater = c.HighWater / 2
+	}
+	if c.LowWater >= c.HighWater {
+		return nil, errors.New("overload LowWater must be less than HighWater")
+	}
+
+	return &c, nil
+}
+
+// SetDefaultOverloadConfig 设置所有服务的过载保护，cfg为nil时关闭，需在服务初始化之前调用
+func SetDefaultOverloadConfig(cfg *OverloadConfig) error {
+	var c *OverloadConfig
+	if cfg != nil {
+		var err error
+		c, err = CheckOverloadConfig(cfg)
+		if err != nil {
+			return err
+		}
+	}
+
+	overloadLocker.Lock()
+	defaultOverloadConfig = c
+	overloadLocker.Unlock()
+	return nil
+}
+
+// SetOverloadConfig 单独设置服务的过载保护，优先于默认配置，cfg为nil时删除
+func SetOverloadConfig(serviceName string, cfg *OverloadConfig) error {
+	overloadLocker.Lock()
+	defer overloadLocker.Unlock()
+
+	if cfg == nil {
+		delete(mapOverloadConfig, serviceName)
+		return nil
+	}
+
+	c, err := CheckOverloadConfig(cfg)
+	if err != nil {
+		return err
+	}
+	mapOverloadConfig[serviceName] = c
+	return nil
+}
+
+// GetOverloadConfig 获取服务的过载保护配置，未开启时返回nil
+func GetOverloadConfig(serviceName string) *OverloadConfig {
+	overloadLocker.RLock()
+	defer overloadLocker.RUnlock()
+
+	if cfg, ok := mapOverloadConfig[serviceName]; ok == true {
+		return cfg
+	}
+
+	return defaultOverloadConfig
+}
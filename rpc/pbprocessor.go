@@ -23,14 +23,22 @@ func (slf *PBRpcRequestData) MakeRequest(seq uint64, rpcMethodId uint32, service
 	slf.ServiceMethod = serviceMethod
 	slf.NoReply = noReply
 	slf.InParam = inParam
+	slf.Timeout = 0
+	slf.Cancel = false
+	slf.Metadata = nil
+	slf.StreamWindow = 0
+	slf.StreamCredit = 0
+	slf.CallerNodeId = ""
 
 	return slf
 }
 
-func (slf *PBRpcResponseData) MakeResponse(seq uint64, err RpcError, reply []byte) *PBRpcResponseData {
+func (slf *PBRpcResponseData) MakeResponse(seq uint64, err error, reply []byte) *PBRpcResponseData {
 	slf.Seq = seq
-	slf.Error = err.Error()
+	slf.Error, slf.ErrCode, slf.ErrDetails = marshalError(err)
 	slf.Reply = reply
+	slf.Metadata = nil
+	slf.StreamMsg = false
 
 	return slf
 }
@@ -53,7 +61,7 @@ func (slf *PBProcessor) MakeRpcRequest(seq uint64, rpcMethodId uint32, serviceMe
 	return pGogoPbRpcRequestData
 }
 
-func (slf *PBProcessor) MakeRpcResponse(seq uint64, err RpcError, reply []byte) IRpcResponseData {
+func (slf *PBProcessor) MakeRpcResponse(seq uint64, err error, reply []byte) IRpcResponseData {
 	pPBRpcResponseData := rpcPbResponseDataPool.Get().(*PBRpcResponseData)
 	pPBRpcResponseData.MakeResponse(seq, err, reply)
 	return pPBRpcResponseData
@@ -89,11 +97,50 @@ func (slf *PBRpcRequestData) IsNoReply() bool {
 	return slf.GetNoReply()
 }
 
-func (slf *PBRpcResponseData) GetErr() *RpcError {
-	if slf.GetError() == "" {
-		return nil
-	}
+func (slf *PBRpcRequestData) IsCancel() bool {
+	return slf.GetCancel()
+}
+
+func (slf *PBRpcRequestData) SetTimeout(timeout int64) {
+	slf.Timeout = timeout
+}
+
+func (slf *PBRpcRequestData) SetCancel(cancel bool) {
+	slf.Cancel = cancel
+}
+
+func (slf *PBRpcRequestData) SetMetadata(metadata map[string]string) {
+	slf.Metadata = metadata
+}
+
+func (slf *PBRpcRequestData) SetStreamWindow(window uint32) {
+	slf.StreamWindow = window
+}
+
+func (slf *PBRpcRequestData) SetCallerNodeId(nodeId string) {
+	slf.CallerNodeId = nodeId
+}
+
+func (slf *PBRpcRequestData) SetSeq(seq uint64) {
+	slf.Seq = seq
+}
+
+func (slf *PBRpcRequestData) SetStreamCredit(credit uint32) {
+	slf.StreamCredit = credit
+}
+
+func (slf *PBRpcResponseData) SetMetadata(metadata map[string]string) {
+	slf.Metadata = metadata
+}
+
+func (slf *PBRpcResponseData) IsStreamMsg() bool {
+	return slf.GetStreamMsg()
+}
+
+func (slf *PBRpcResponseData) SetStreamMsg(streamMsg bool) {
+	slf.StreamMsg = streamMsg
+}
 
-	err := RpcError(slf.GetError())
-	return &err
+func (slf *PBRpcResponseData) GetErr() error {
+	return unmarshalError(slf.GetError(), slf.GetErrCode(), slf.GetErrDetails())
 }
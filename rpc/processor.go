@@ -5,7 +5,7 @@ type IRpcProcessor interface {
 	Marshal(v interface{}) ([]byte, error) //b表示自定义缓冲区，可以填nil，由系统自动分配
 	Unmarshal(data []byte, v interface{}) error
 	MakeRpcRequest(seq uint64,rpcMethodId uint32,serviceMethod string,noReply bool,inParam []byte) IRpcRequestData
-	MakeRpcResponse(seq uint64,err RpcError,reply []byte) IRpcResponseData
+	MakeRpcResponse(seq uint64,err error,reply []byte) IRpcResponseData
 
 	ReleaseRpcRequest(rpcRequestData IRpcRequestData)
 	ReleaseRpcResponse(rpcRequestData IRpcResponseData)
@@ -1323,8 +1323,39 @@ var file_test_rpc_rank_proto_rawDesc = []byte{
 	0x0b, 0x52, 0x65, 0x6d, 0x6f, 0x76, 0x65, 0x43, 0x6f, 0x75, 0x6e, 0x74, 0x12, 0x27, 0x0a, 0x07,
 	0x4e, 0x65, 0x77, 0x52, 0x61, 0x6e, 0x6b, 0x18, 0x04, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x0d, 0x2e,
 	0x72, 0x70, 0x63, 0x2e, 0x52, 0x61, 0x6e, 0x6b, 0x49, 0x6e, 0x66, 0x6f, 0x52, 0x07, 0x4e, 0x65,
-	0x77, 0x52, 0x61, 0x6e, 0x6b, 0x42, 0x07, 0x5a, 0x05, 0x2e, 0x3b, 0x72, 0x70, 0x63, 0x62, 0x06,
-	0x70, 0x72, 0x6f, 0x74, 0x6f, 0x33,
+	0x77, 0x52, 0x61, 0x6e, 0x6b, 0x32, 0xf3, 0x03, 0x0a, 0x0b, 0x52, 0x61, 0x6e, 0x6b, 0x53, 0x65,
+	0x72, 0x76, 0x69, 0x63, 0x65, 0x12, 0x36, 0x0a, 0x11, 0x4d, 0x61, 0x6e, 0x75, 0x61, 0x6c, 0x41,
+	0x64, 0x64, 0x52, 0x61, 0x6e, 0x6b, 0x53, 0x6b, 0x69, 0x70, 0x12, 0x10, 0x2e, 0x72, 0x70, 0x63,
+	0x2e, 0x41, 0x64, 0x64, 0x52, 0x61, 0x6e, 0x6b, 0x4c, 0x69, 0x73, 0x74, 0x1a, 0x0f, 0x2e, 0x72,
+	0x70, 0x63, 0x2e, 0x52, 0x61, 0x6e, 0x6b, 0x52, 0x65, 0x73, 0x75, 0x6c, 0x74, 0x12, 0x30, 0x0a,
+	0x09, 0x55, 0x70, 0x73, 0x65, 0x74, 0x52, 0x61, 0x6e, 0x6b, 0x12, 0x12, 0x2e, 0x72, 0x70, 0x63,
+	0x2e, 0x55, 0x70, 0x73, 0x65, 0x74, 0x52, 0x61, 0x6e, 0x6b, 0x44, 0x61, 0x74, 0x61, 0x1a, 0x0f,
+	0x2e, 0x72, 0x70, 0x63, 0x2e, 0x52, 0x61, 0x6e, 0x6b, 0x52, 0x65, 0x73, 0x75, 0x6c, 0x74, 0x12,
+	0x43, 0x0a, 0x10, 0x49, 0x6e, 0x63, 0x72, 0x65, 0x61, 0x73, 0x65, 0x52, 0x61, 0x6e, 0x6b, 0x44,
+	0x61, 0x74, 0x61, 0x12, 0x15, 0x2e, 0x72, 0x70, 0x63, 0x2e, 0x49, 0x6e, 0x63, 0x72, 0x65, 0x61,
+	0x73, 0x65, 0x52, 0x61, 0x6e, 0x6b, 0x44, 0x61, 0x74, 0x61, 0x1a, 0x18, 0x2e, 0x72, 0x70, 0x63,
+	0x2e, 0x49, 0x6e, 0x63, 0x72, 0x65, 0x61, 0x73, 0x65, 0x52, 0x61, 0x6e, 0x6b, 0x44, 0x61, 0x74,
+	0x61, 0x52, 0x65, 0x74, 0x12, 0x3d, 0x0a, 0x0e, 0x55, 0x70, 0x64, 0x61, 0x74, 0x65, 0x52, 0x61,
+	0x6e, 0x6b, 0x44, 0x61, 0x74, 0x61, 0x12, 0x13, 0x2e, 0x72, 0x70, 0x63, 0x2e, 0x55, 0x70, 0x64,
+	0x61, 0x74, 0x65, 0x52, 0x61, 0x6e, 0x6b, 0x44, 0x61, 0x74, 0x61, 0x1a, 0x16, 0x2e, 0x72, 0x70,
+	0x63, 0x2e, 0x55, 0x70, 0x64, 0x61, 0x74, 0x65, 0x52, 0x61, 0x6e, 0x6b, 0x44, 0x61, 0x74, 0x61,
+	0x52, 0x65, 0x74, 0x12, 0x38, 0x0a, 0x13, 0x44, 0x65, 0x6c, 0x65, 0x74, 0x65, 0x52, 0x61, 0x6e,
+	0x6b, 0x44, 0x61, 0x74, 0x61, 0x42, 0x79, 0x4b, 0x65, 0x79, 0x12, 0x10, 0x2e, 0x72, 0x70, 0x63,
+	0x2e, 0x44, 0x65, 0x6c, 0x65, 0x74, 0x65, 0x42, 0x79, 0x4b, 0x65, 0x79, 0x1a, 0x0f, 0x2e, 0x72,
+	0x70, 0x63, 0x2e, 0x52, 0x61, 0x6e, 0x6b, 0x52, 0x65, 0x73, 0x75, 0x6c, 0x74, 0x12, 0x3d, 0x0a,
+	0x11, 0x46, 0x69, 0x6e, 0x64, 0x52, 0x61, 0x6e, 0x6b, 0x44, 0x61, 0x74, 0x61, 0x42, 0x79, 0x4b,
+	0x65, 0x79, 0x12, 0x16, 0x2e, 0x72, 0x70, 0x63, 0x2e, 0x46, 0x69, 0x6e, 0x64, 0x52, 0x61, 0x6e,
+	0x6b, 0x44, 0x61, 0x74, 0x61, 0x42, 0x79, 0x4b, 0x65, 0x79, 0x1a, 0x10, 0x2e, 0x72, 0x70, 0x63,
+	0x2e, 0x52, 0x61, 0x6e, 0x6b, 0x50, 0x6f, 0x73, 0x44, 0x61, 0x74, 0x61, 0x12, 0x3f, 0x0a, 0x12,
+	0x46, 0x69, 0x6e, 0x64, 0x52, 0x61, 0x6e, 0x6b, 0x44, 0x61, 0x74, 0x61, 0x42, 0x79, 0x52, 0x61,
+	0x6e, 0x6b, 0x12, 0x17, 0x2e, 0x72, 0x70, 0x63, 0x2e, 0x46, 0x69, 0x6e, 0x64, 0x52, 0x61, 0x6e,
+	0x6b, 0x44, 0x61, 0x74, 0x61, 0x42, 0x79, 0x52, 0x61, 0x6e, 0x6b, 0x1a, 0x10, 0x2e, 0x72, 0x70,
+	0x63, 0x2e, 0x52, 0x61, 0x6e, 0x6b, 0x50, 0x6f, 0x73, 0x44, 0x61, 0x74, 0x61, 0x12, 0x3c, 0x0a,
+	0x10, 0x46, 0x69, 0x6e, 0x64, 0x52, 0x61, 0x6e, 0x6b, 0x44, 0x61, 0x74, 0x61, 0x4c, 0x69, 0x73,
+	0x74, 0x12, 0x15, 0x2e, 0x72, 0x70, 0x63, 0x2e, 0x46, 0x69, 0x6e, 0x64, 0x52, 0x61, 0x6e, 0x6b,
+	0x44, 0x61, 0x74, 0x61, 0x4c, 0x69, 0x73, 0x74, 0x1a, 0x11, 0x2e, 0x72, 0x70, 0x63, 0x2e, 0x52,
+	0x61, 0x6e, 0x6b, 0x44, 0x61, 0x74, 0x61, 0x4c, 0x69, 0x73, 0x74, 0x42, 0x07, 0x5a, 0x05, 0x2e,
+	0x3b, 0x72, 0x70, 0x63, 0x62, 0x06, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x33,
 }
 
 var (
@@ -1370,8 +1401,24 @@ var file_test_rpc_rank_proto_depIdxs = []int32{
 	5,  // 6: rpc.RankDataList.RankPosDataList:type_name -> rpc.RankPosData
 	5,  // 7: rpc.RankDataList.KeyRank:type_name -> rpc.RankPosData
 	16, // 8: rpc.RankResult.NewRank:type_name -> rpc.RankInfo
-	9,  // [9:9] is the sub-list for method output_type
-	9,  // [9:9] is the sub-list for method input_type
+	11, // 9: rpc.RankService.ManualAddRankSkip:input_type -> rpc.AddRankList
+	7,  // 10: rpc.RankService.UpsetRank:input_type -> rpc.UpsetRankData
+	1,  // 11: rpc.RankService.IncreaseRankData:input_type -> rpc.IncreaseRankData
+	3,  // 12: rpc.RankService.UpdateRankData:input_type -> rpc.UpdateRankData
+	10, // 13: rpc.RankService.DeleteRankDataByKey:input_type -> rpc.DeleteByKey
+	12, // 14: rpc.RankService.FindRankDataByKey:input_type -> rpc.FindRankDataByKey
+	13, // 15: rpc.RankService.FindRankDataByRank:input_type -> rpc.FindRankDataByRank
+	14, // 16: rpc.RankService.FindRankDataList:input_type -> rpc.FindRankDataList
+	17, // 17: rpc.RankService.ManualAddRankSkip:output_type -> rpc.RankResult
+	17, // 18: rpc.RankService.UpsetRank:output_type -> rpc.RankResult
+	2,  // 19: rpc.RankService.IncreaseRankData:output_type -> rpc.IncreaseRankDataRet
+	4,  // 20: rpc.RankService.UpdateRankData:output_type -> rpc.UpdateRankDataRet
+	17, // 21: rpc.RankService.DeleteRankDataByKey:output_type -> rpc.RankResult
+	5,  // 22: rpc.RankService.FindRankDataByKey:output_type -> rpc.RankPosData
+	5,  // 23: rpc.RankService.FindRankDataByRank:output_type -> rpc.RankPosData
+	15, // 24: rpc.RankService.FindRankDataList:output_type -> rpc.RankDataList
+	17, // [17:25] is the sub-list for method output_type
+	9,  // [9:17] is the sub-list for method input_type
 	9,  // [9:9] is the sub-list for extension type_name
 	9,  // [9:9] is the sub-list for extension extendee
 	0,  // [0:9] is the sub-list for field type_name
@@ -1608,7 +1655,7 @@ func file_test_rpc_rank_proto_init() {
 			NumEnums:      0,
 			NumMessages:   18,
 			NumExtensions: 0,
-			NumServices:   0,
+			NumServices:   1,
 		},
 		GoTypes:           file_test_rpc_rank_proto_goTypes,
 		DependencyIndexes: file_test_rpc_rank_proto_depIdxs,
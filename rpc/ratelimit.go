@@ -0,0 +1,213 @@
+package rpc
+
+import (
+	"errors"
+	"math"
+	"strings"
+	"sync"
+	"time"
+)
+
+// RateLimit 被调用方的令牌桶限流，超过限制的请求在RPC函数执行前返回CodeRateLimited
+type RateLimit struct {
+	Rate      float64 //每秒产生的令牌数
+	Burst     int     //桶容量，允许的瞬时请求数，不配置时为Rate向上取整
+	PerCaller bool    //每个调用方结点单独一个桶，否则所有调用方共用
+}
+
+// maxCallerBuckets PerCaller时最多保留的调用方令牌桶数，超过后新的调用方共用一个桶
+const maxCallerBuckets = 4096
+
+type tokenBucket struct {
+	tokens   float64
+	lastTime time.Time
+}
+
+// refill 按经过的时间补充令牌
+func (bucket *tokenBucket) refill(limit *RateLimit, now time.Time) {
+	if bucket.lastTime.IsZero() {
+		bucket.tokens = float64(limit.Burst)
+	} else if elapsed := now.Sub(bucket.lastTime); elapsed > 0 {
+		bucket.tokens = math.Min(float64(limit.Burst), bucket.tokens+elapsed.Seconds()*limit.Rate)
+	}
+	bucket.lastTime = now
+}
+
+type rateLimiter struct {
+	limit RateLimit
+
+	locker        sync.Mutex
+	bucket        tokenBucket             //所有调用方共用，PerCaller时用于超过maxCallerBuckets的调用方
+	callerBuckets map[string]*tokenBucket //PerCaller时map[callerNodeId]
+	pruneTime     time.Time               //下次可以清理空闲令牌桶的时间
+}
+
+// getBucket 返回调用方的令牌桶并补充令牌，调用方需持有locker
+func (limiter *rateLimiter) getBucket(callerNodeId string, now time.Time) *tokenBucket {
+	bucket := &limiter.bucket
+	if limiter.limit.PerCaller == true {
+		bucket = limiter.getCallerBucket(callerNodeId, now)
+	}
+
+	bucket.refill(&limiter.limit, now)
+	return bucket
+}
+
+func (limiter *rateLimiter) getCallerBucket(callerNodeId string, now time.Time) *tokenBucket {
+	if bucket, ok := limiter.callerBuckets[callerNodeId]; ok == true {
+		return bucket
+	}
+
+	if len(limiter.callerBuckets) >= maxCallerBuckets {
+		limiter.removeIdleBuckets(now)
+		if len(limiter.callerBuckets) >= maxCallerBuckets {
+			return &limiter.bucket
+		}
+	}
+
+	bucket := &tokenBucket{}
+	limiter.callerBuckets[callerNodeId] = bucket
+	return bucket
+}
+
+// removeIdleBuckets 空闲到令牌补满的桶与新建的桶相同，可以删除。每个补满周期最多清理一次
+func (limiter *rateLimiter) removeIdleBuckets(now time.Time) {
+	if now.Before(limiter.pruneTime) {
+		return
+	}
+
+	fullTime := time.Duration(float64(limiter.limit.Burst) / limiter.limit.Rate * float64(time.Second))
+	limiter.pruneTime = now.Add(fullTime)
+	for callerNodeId, bucket := range limiter.callerBuckets {
+		if now.Sub(bucket.lastTime) >= fullTime {
+			delete(limiter.callerBuckets, callerNodeId)
+		}
+	}
+}
+
+// rateLimitNow 限流使用的时钟，测试中可以替换
+var rateLimitNow = time.Now
+
+// rateLimitSet map["Service.RPC_Method"或"Service"]限流，零值可用
+type rateLimitSet struct {
+	locker       sync.RWMutex
+	mapRateLimit map[string]*rateLimiter
+}
+
+// setRateLimit limit为nil时删除配置，重新设置会清空已有的令牌桶
+func (set *rateLimitSet) setRateLimit(serviceMethod string, limit *RateLimit) error {
+	set.locker.Lock()
+	defer set.locker.Unlock()
+
+	if limit == nil {
+		delete(set.mapRateLimit, serviceMethod)
+		return nil
+	}
+
+	if limit.Rate <= 0 || limit.Burst < 0 {
+		return errors.New("rate limit of " + serviceMethod + " is invalid")
+	}
+
+	limiter := &rateLimiter{limit: *limit, callerBuckets: map[string]*tokenBucket{}}
+	if limiter.limit.Burst == 0 {
+		limiter.limit.Burst = int(math.Ceil(limit.Rate))
+	}
+	if set.mapRateLimit == nil {
+		set.mapRateLimit = map[string]*rateLimiter{}
+	}
+	set.mapRateLimit[serviceMethod] = limiter
+	return nil
+}
+
+func (set *rateLimitSet) getRateLimiter(serviceMethod string) *rateLimiter {
+	set.locker.RLock()
+	defer set.locker.RUnlock()
+
+	return set.mapRateLimit[serviceMethod]
+}
+
+// checkRateLimit 检查函数与所在服务的限流，两者都有令牌时才各取一个令牌
+func checkRateLimit(methodLimiter *rateLimiter, serviceLimiter *rateLimiter, serviceMethod string, callerNodeId string) error {
+	if methodLimiter == nil && serviceLimiter == nil {
+		return nil
+	}
+
+	//总是先锁函数的限流再锁服务的限流
+	now := rateLimitNow()
+	var methodBucket, serviceBucket *tokenBucket
+	if methodLimiter != nil {
+		methodLimiter.locker.Lock()
+		defer methodLimiter.locker.Unlock()
+
+		methodBucket = methodLimiter.getBucket(callerNodeId, now)
+		if methodBucket.tokens < 1 {
+			return NewError(CodeRateLimited, "call "+serviceMethod+" is rate limited")
+		}
+	}
+
+	if serviceLimiter != nil {
+		serviceLimiter.locker.Lock()
+		defer serviceLimiter.locker.Unlock()
+
+		serviceBucket = serviceLimiter.getBucket(callerNodeId, now)
+		if serviceBucket.tokens < 1 {
+			return NewError(CodeRateLimited, "call "+serviceMethod+" is rate limited by service")
+		}
+	}
+
+	if methodBucket != nil {
+		methodBucket.tokens--
+	}
+	if serviceBucket != nil {
+		serviceBucket.tokens--
+	}
+
+	return nil
+}
+
+// SetRateLimit 设置本服务RPC函数的限流，method为空时对服务的所有函数生效，如handler.SetRateLimit("RPC_Rank", &limit)。
+// 只对本服务生效，优先于所在结点Server的配置，limit为nil时删除
+func (handler *RpcHandler) SetRateLimit(method string, limit *RateLimit) error {
+	if method == "" {
+		return handler.rateLimits.setRateLimit(handler.GetName(), limit)
+	}
+
+	return handler.rateLimits.setRateLimit(handler.GetName()+"."+method, limit)
+}
+
+// getRateLimiter 优先使用本服务设置的限流，其次为所在结点Server的配置
+func (handler *RpcHandler) getRateLimiter(server IServer, serviceMethod string) *rateLimiter {
+	if limiter := handler.rateLimits.getRateLimiter(serviceMethod); limiter != nil {
+		return limiter
+	}
+	if server == nil {
+		return nil
+	}
+
+	return server.getRateLimiter(serviceMethod)
+}
+
+func (handler *RpcHandler) checkRateLimit(serviceMethod string, callerNodeId string) error {
+	var server IServer
+	if handler.funcRpcServer != nil {
+		server = handler.funcRpcServer()
+	}
+
+	methodLimiter := handler.getRateLimiter(server, serviceMethod)
+	var serviceLimiter *rateLimiter
+	if findIndex := strings.Index(serviceMethod, "."); findIndex != -1 {
+		serviceLimiter = handler.getRateLimiter(server, serviceMethod[:findIndex])
+	}
+
+	return checkRateLimit(methodLimiter, serviceLimiter, serviceMethod, callerNodeId)
+}
+
+// SetRateLimit 设置本结点服务的限流，serviceMethod为"Service.RPC_Method"时对单个函数生效，为"Service"时服务的所有函数共用，
+// 两者都配置时需同时满足。limit为nil时删除配置，重新设置会清空已有的令牌桶
+func (server *BaseServer) SetRateLimit(serviceMethod string, limit *RateLimit) error {
+	return server.rateLimits.setRateLimit(serviceMethod, limit)
+}
+
+func (server *BaseServer) getRateLimiter(serviceMethod string) *rateLimiter {
+	return server.rateLimits.getRateLimiter(serviceMethod)
+}
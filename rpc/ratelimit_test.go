@@ -0,0 +1,167 @@
+package rpc
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+// rateLimitClock 替换限流的时钟，测试结束后恢复
+func rateLimitClock(t *testing.T) *time.Time {
+	now := time.Unix(1700000000, 0)
+	oldNow := rateLimitNow
+	rateLimitNow = func() time.Time { return now }
+	t.Cleanup(func() {
+		rateLimitNow = oldNow
+	})
+
+	return &now
+}
+
+type rateLimitCall struct {
+	advance time.Duration //调用前时钟前进的时间
+	caller  string
+	allowed bool
+}
+
+func TestRateLimit(t *testing.T) {
+	tests := []struct {
+		name  string
+		limit RateLimit
+		calls []rateLimitCall
+	}{
+		{"burst", RateLimit{Rate: 1, Burst: 3}, []rateLimitCall{
+			{0, "node_1", true}, {0, "node_1", true}, {0, "node_1", true}, {0, "node_1", false},
+		}},
+		{"default burst", RateLimit{Rate: 1.5}, []rateLimitCall{
+			{0, "node_1", true}, {0, "node_1", true}, {0, "node_1", false},
+		}},
+		{"refill", RateLimit{Rate: 10, Burst: 1}, []rateLimitCall{
+			{0, "node_1", true}, {50 * time.Millisecond, "node_1", false}, {50 * time.Millisecond, "node_1", true}, {0, "node_1", false},
+		}},
+		{"refill up to burst", RateLimit{Rate: 10, Burst: 2}, []rateLimitCall{
+			{0, "node_1", true}, {0, "node_1", true}, {time.Hour, "node_1", true}, {0, "node_1", true}, {0, "node_1", false},
+		}},
+		{"shared by callers", RateLimit{Rate: 1, Burst: 1}, []rateLimitCall{
+			{0, "node_1", true}, {0, "node_2", false},
+		}},
+		{"per caller", RateLimit{Rate: 1, Burst: 1, PerCaller: true}, []rateLimitCall{
+			{0, "node_1", true}, {0, "node_2", true}, {0, "node_1", false}, {0, "node_2", false},
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			now := rateLimitClock(t)
+			var set rateLimitSet
+			if err := set.setRateLimit("RateService.RPC_Test", &tt.limit); err != nil {
+				t.Fatal(err)
+			}
+
+			for i, call := range tt.calls {
+				*now = now.Add(call.advance)
+				err := checkRateLimit(set.getRateLimiter("RateService.RPC_Test"), nil, "RateService.RPC_Test", call.caller)
+				if (err == nil) != call.allowed {
+					t.Fatalf("call %d allowed = %v, want %v", i, err == nil, call.allowed)
+				}
+				if err != nil && Code(err) != CodeRateLimited {
+					t.Fatalf("call %d error = %v", i, err)
+				}
+			}
+		})
+	}
+}
+
+// TestRateLimitMethodAndService 函数与服务的限流都有令牌时才各取一个
+func TestRateLimitMethodAndService(t *testing.T) {
+	rateLimitClock(t)
+
+	var set rateLimitSet
+	set.setRateLimit("RateService.RPC_Test", &RateLimit{Rate: 1, Burst: 5})
+	set.setRateLimit("RateService", &RateLimit{Rate: 1, Burst: 2})
+	methodLimiter, serviceLimiter := set.getRateLimiter("RateService.RPC_Test"), set.getRateLimiter("RateService")
+
+	for i := 0; i < 3; i++ {
+		err := checkRateLimit(methodLimiter, serviceLimiter, "RateService.RPC_Test", "node_1")
+		if (err == nil) != (i < 2) {
+			t.Fatalf("call %d error = %v", i, err)
+		}
+	}
+
+	//服务限流拒绝的请求不消耗函数的令牌
+	if tokens := methodLimiter.bucket.tokens; tokens != 3 {
+		t.Fatalf("method tokens = %v, want 3", tokens)
+	}
+}
+
+func TestRateLimitCallerBuckets(t *testing.T) {
+	now := rateLimitClock(t)
+
+	var set rateLimitSet
+	set.setRateLimit("RateService", &RateLimit{Rate: 1, Burst: 1, PerCaller: true})
+	limiter := set.getRateLimiter("RateService")
+	for i := 0; i < maxCallerBuckets; i++ {
+		checkRateLimit(nil, limiter, "RateService.RPC_Test", fmt.Sprintf("node_%d", i))
+	}
+
+	//桶数达到上限后新的调用方共用一个桶
+	if err := checkRateLimit(nil, limiter, "RateService.RPC_Test", "new_1"); err != nil {
+		t.Fatal(err)
+	}
+	if err := checkRateLimit(nil, limiter, "RateService.RPC_Test", "new_2"); err == nil {
+		t.Fatal("new callers should share one bucket")
+	}
+	if len(limiter.callerBuckets) != maxCallerBuckets {
+		t.Fatalf("caller buckets = %d", len(limiter.callerBuckets))
+	}
+
+	//令牌补满后空闲的桶被清理
+	*now = now.Add(2 * time.Second)
+	if err := checkRateLimit(nil, limiter, "RateService.RPC_Test", "new_3"); err != nil {
+		t.Fatal(err)
+	}
+	if len(limiter.callerBuckets) != 1 {
+		t.Fatalf("idle buckets are not removed, caller buckets = %d", len(limiter.callerBuckets))
+	}
+}
+
+func TestRateLimitSetInvalid(t *testing.T) {
+	var set rateLimitSet
+	for _, limit := range []RateLimit{{Rate: 0}, {Rate: -1}, {Rate: 1, Burst: -1}} {
+		if err := set.setRateLimit("RateService", &limit); err == nil {
+			t.Fatalf("limit %+v should be invalid", limit)
+		}
+	}
+
+	set.setRateLimit("RateService", &RateLimit{Rate: 1})
+	set.setRateLimit("RateService", nil)
+	if set.getRateLimiter("RateService") != nil {
+		t.Fatal("rate limit is not removed")
+	}
+}
+
+// TestRateLimitIsolation 不同结点的Server与服务的限流互不影响，服务的配置优先
+func TestRateLimitIsolation(t *testing.T) {
+	rateLimitClock(t)
+
+	server1, server2 := &Server{}, &Server{}
+	server1.SetRateLimit("RateService", &RateLimit{Rate: 1, Burst: 1})
+	server2.SetRateLimit("RateService", &RateLimit{Rate: 1, Burst: 1})
+
+	handler1 := &RpcHandler{funcRpcServer: func() IServer { return server1 }}
+	handler2 := &RpcHandler{funcRpcServer: func() IServer { return server2 }}
+	for _, handler := range []*RpcHandler{handler1, handler2} {
+		if err := handler.checkRateLimit("RateService.RPC_Test", "node_1"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := handler1.checkRateLimit("RateService.RPC_Test", "node_1"); err == nil {
+		t.Fatal("server rate limit is not applied")
+	}
+
+	//服务设置的限流优先于Server的配置
+	handler1.rateLimits.setRateLimit("RateService", &RateLimit{Rate: 1, Burst: 2})
+	if err := handler1.checkRateLimit("RateService.RPC_Test", "node_1"); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -1,51 +1,62 @@
 package rpc
 
 import (
-	"fmt"
+	"context"
 	"github.com/duanhf2012/origin/v2/log"
 	"github.com/duanhf2012/origin/v2/network"
 	"math"
 	"reflect"
-
+	"sync"
 	"sync/atomic"
 	"time"
 )
 
-// RClient 跨结点连接的Client
+// RClient 跨结点连接的Client，与目标结点之间有ConnNum条连接，调用按序号或Key分散到各连接
 type RClient struct {
 	selfClient *Client
 	network.TCPClient
-	conn *network.NetConn
+
+	linkLocker sync.RWMutex
+	links      []*rpcLink //每个位置一条连接，断开后由TCPClient重连
+	readyNum   int        //握手完成的连接数
+	linkSeq    int
+	writeSeq   uint32
 
 	notifyEventFun NotifyEventFun
 }
 
 func (rc *RClient) IsConnected() bool {
-	rc.Lock()
-	defer rc.Unlock()
+	rc.linkLocker.RLock()
+	defer rc.linkLocker.RUnlock()
 
-	return rc.conn != nil && rc.conn.IsConnected() == true
+	return rc.readyNum > 0
 }
 
+// GetConn 返回第一条可用的连接
 func (rc *RClient) GetConn() *network.NetConn {
-	rc.Lock()
-	conn := rc.conn
-	rc.Unlock()
+	rc.linkLocker.RLock()
+	defer rc.linkLocker.RUnlock()
 
-	return conn
-}
+	for _, link := range rc.links {
+		if link != nil && link.isReady() == true {
+			return link.conn
+		}
+	}
 
-func (rc *RClient) SetConn(conn *network.NetConn) {
-	rc.Lock()
-	rc.conn = conn
-	rc.Unlock()
+	return nil
 }
 
+// WriteMsg 不指定连接时轮流使用可用的连接
 func (rc *RClient) WriteMsg(nodeId string, args ...[]byte) error {
-	return rc.conn.WriteMsg(args...)
+	link := rc.getLink(rc.selectLink(uint64(atomic.AddUint32(&rc.writeSeq, 1))))
+	if link == nil {
+		return NewError(CodeDisconnected, "rpc client is disconnect")
+	}
+
+	return link.writeMsg(args...)
 }
 
-func (rc *RClient) Go(nodeId string, timeout time.Duration, rpcHandler IRpcHandler, noReply bool, serviceMethod string, args interface{}, reply interface{}) *Call {
+func (rc *RClient) Go(ctx context.Context, nodeId string, timeout time.Duration, rpcHandler IRpcHandler, noReply bool, serviceMethod string, args interface{}, reply interface{}) *Call {
 	_, processor := GetProcessorType(args)
 	InParam, err := processor.Marshal(args)
 	if err != nil {
@@ -55,15 +66,15 @@ func (rc *RClient) Go(nodeId string, timeout time.Duration, rpcHandler IRpcHandl
 		return call
 	}
 
-	return rc.selfClient.rawGo(nodeId, rc, timeout, rpcHandler, processor, noReply, 0, serviceMethod, InParam, reply)
+	return rc.selfClient.rawGo(ctx, nodeId, rc, timeout, rpcHandler, processor, noReply, 0, serviceMethod, InParam, reply)
 }
 
 func (rc *RClient) RawGo(nodeId string, timeout time.Duration, rpcHandler IRpcHandler, processor IRpcProcessor, noReply bool, rpcMethodId uint32, serviceMethod string, rawArgs []byte, reply interface{}) *Call {
-	return rc.selfClient.rawGo(nodeId, rc, timeout, rpcHandler, processor, noReply, rpcMethodId, serviceMethod, rawArgs, reply)
+	return rc.selfClient.rawGo(nil, nodeId, rc, timeout, rpcHandler, processor, noReply, rpcMethodId, serviceMethod, rawArgs, reply)
 }
 
-func (rc *RClient) AsyncCall(nodeId string, timeout time.Duration, rpcHandler IRpcHandler, serviceMethod string, callback reflect.Value, args interface{}, replyParam interface{}) (CancelRpc, error) {
-	cancelRpc, err := rc.selfClient.asyncCall(nodeId, rc, timeout, rpcHandler, serviceMethod, callback, args, replyParam)
+func (rc *RClient) AsyncCall(ctx context.Context, nodeId string, timeout time.Duration, rpcHandler IRpcHandler, serviceMethod string, callback reflect.Value, args interface{}, replyParam interface{}) (CancelRpc, error) {
+	cancelRpc, err := rc.selfClient.asyncCall(ctx, nodeId, rc, timeout, rpcHandler, serviceMethod, callback, args, replyParam, 0)
 	if err != nil {
 		callback.Call([]reflect.Value{reflect.ValueOf(replyParam), reflect.ValueOf(err)})
 	}
@@ -71,38 +82,21 @@ func (rc *RClient) AsyncCall(nodeId string, timeout time.Duration, rpcHandler IR
 	return cancelRpc, nil
 }
 
-func (rc *RClient) Run() {
-	defer func() {
-		if r := recover(); r != nil {
-			log.StackError(fmt.Sprint(r))
-		}
-	}()
-
-	var eventData RpcConnEvent
-	eventData.IsConnect = true
-	eventData.NodeId = rc.selfClient.GetTargetNodeId()
-	rc.notifyEventFun(&eventData)
-
-	for {
-		bytes, err := rc.conn.ReadMsg()
-		if err != nil {
-			log.Error("RClient read msg is failed", log.ErrorField("error", err))
-			return
-		}
-
-		err = rc.selfClient.processRpcResponse(bytes)
-		rc.conn.ReleaseReadMsg(bytes)
-		if err != nil {
-			return
-		}
+func (rc *RClient) StreamCall(ctx context.Context, nodeId string, timeout time.Duration, rpcHandler IRpcHandler, serviceMethod string, callback reflect.Value, args interface{}, replyParam interface{}, window uint32) (CancelRpc, error) {
+	cancelRpc, err := rc.selfClient.asyncCall(ctx, nodeId, rc, timeout, rpcHandler, serviceMethod, callback, args, replyParam, window)
+	if err != nil {
+		callback.Call([]reflect.Value{reflect.ValueOf(replyParam), reflect.ValueOf(err)})
 	}
+
+	return cancelRpc, nil
+}
+
+func (rc *RClient) SendCancel(nodeId string, callSeq uint64, connId int) {
+	rc.selfClient.sendCancel(nodeId, rc, callSeq, connId)
 }
 
-func (rc *RClient) OnClose() {
-	var connEvent RpcConnEvent
-	connEvent.IsConnect = false
-	connEvent.NodeId = rc.selfClient.GetTargetNodeId()
-	rc.notifyEventFun(&connEvent)
+func (rc *RClient) SendStreamCredit(nodeId string, callSeq uint64, connId int, credit uint32) {
+	rc.selfClient.sendStreamCredit(nodeId, rc, callSeq, connId, credit)
 }
 
 func NewRClient(targetNodeId string, addr string, maxRpcParamLen uint32, compressBytesLen int, callSet *CallSet, notifyEventFun NotifyEventFun) *Client {
@@ -110,6 +104,7 @@ func NewRClient(targetNodeId string, addr string, maxRpcParamLen uint32, compres
 	client.clientId = atomic.AddUint32(&clientSeq, 1)
 	client.targetNodeId = targetNodeId
 	client.compressBytesLen = compressBytesLen
+	client.breaker = &CircuitBreaker{}
 
 	c := &RClient{}
 	c.selfClient = client
@@ -118,13 +113,15 @@ func NewRClient(targetNodeId string, addr string, maxRpcParamLen uint32, compres
 	c.PendingWriteNum = DefaultMaxPendingWriteNum
 	c.AutoReconnect = true
 	c.notifyEventFun = notifyEventFun
-	c.ConnNum = DefaultRpcConnNum
+	c.ConnNum = getConnNum(targetNodeId)
+	c.links = make([]*rpcLink, c.ConnNum)
 	c.LenMsgLen = DefaultRpcLenMsgLen
 	c.MinMsgLen = DefaultRpcMinMsgLen
 	c.ReadDeadline = Default_ReadWriteDeadline
 	c.WriteDeadline = Default_ReadWriteDeadline
 	c.LittleEndian = LittleEndian
-	c.NewAgent = client.NewClientAgent
+	c.NewAgent = c.newLink
+	c.TLSConfig = getClientTLSConfig(targetNodeId)
 
 	if maxRpcParamLen > 0 {
 		c.MaxMsgLen = maxRpcParamLen
@@ -139,7 +136,7 @@ func NewRClient(targetNodeId string, addr string, maxRpcParamLen uint32, compres
 
 func (rc *RClient) Close(waitDone bool) {
 	rc.TCPClient.Close(waitDone)
-	rc.selfClient.cleanPending()
+	rc.selfClient.cleanPending(rc.selfClient.GetTargetNodeId())
 }
 
 func (rc *RClient) Bind(server IServer) {
@@ -0,0 +1,353 @@
+package rpc
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/duanhf2012/origin/v2/log"
+	"github.com/duanhf2012/origin/v2/network"
+)
+
+// ReplayConfig 回放配置，把抓包中结点收到的请求重新发给本地运行的结点
+type ReplayConfig struct {
+	Addr           string        //被回放结点的Rpc监听地址
+	TargetNodeId   string        //被回放结点的Id，开启握手认证或TLS时用于校验
+	Speed          float64       //回放速度，1为按抓包时的间隔，2为两倍速，0为不等待直接发送
+	Services       []string      //只回放这些服务的请求，为空时回放所有请求
+	ConnectTimeout time.Duration //连接超时，默认10秒
+	WaitTimeout    time.Duration //发送完成后等待返回的最长时间，默认10秒
+}
+
+// ReplayStats 回放结果
+type ReplayStats struct {
+	RequestNum   int //发出的请求数，不包括控制帧
+	ControlNum   int //发出的取消与流式窗口控制帧
+	SkippedNum   int //被过滤或无法对应到请求的记录
+	ResponseNum  int //收到的返回数
+	StreamMsgNum int //收到的流式消息数
+	PendingNum   int //等待超时仍未返回的请求数
+	ErrCodes     map[ErrCode]int
+}
+
+func (stats *ReplayStats) String() string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "request:%d control:%d skipped:%d response:%d streamMsg:%d pending:%d", stats.RequestNum, stats.ControlNum, stats.SkippedNum, stats.ResponseNum, stats.StreamMsgNum, stats.PendingNum)
+	for code, num := range stats.ErrCodes {
+		fmt.Fprintf(&sb, " code%d:%d", code, num)
+	}
+
+	return sb.String()
+}
+
+type replayKey struct {
+	connTag string
+	seq     uint64
+}
+
+// Replayer 通过一条连接回放抓包中的请求。抓包中不同连接的请求可能使用相同的Seq，发送时重新分配Seq
+type Replayer struct {
+	network.TCPClient
+	cfg      ReplayConfig
+	services map[string]struct{}
+
+	conn    *network.NetConn
+	readyCh chan error
+	closeCh chan struct{}
+
+	locker    sync.Mutex
+	seq       uint64
+	mapSeq    map[replayKey]uint64 //抓包中的连接与Seq对应回放时的Seq
+	pending   map[uint64]struct{}
+	stats     ReplayStats
+	emptyCond *sync.Cond
+}
+
+func NewReplayer(cfg *ReplayConfig) *Replayer {
+	replayer := &Replayer{cfg: *cfg}
+	if replayer.cfg.ConnectTimeout <= 0 {
+		replayer.cfg.ConnectTimeout = 10 * time.Second
+	}
+	if replayer.cfg.WaitTimeout <= 0 {
+		replayer.cfg.WaitTimeout = 10 * time.Second
+	}
+	if len(cfg.Services) > 0 {
+		replayer.services = make(map[string]struct{}, len(cfg.Services))
+		for _, serviceName := range cfg.Services {
+			replayer.services[serviceName] = struct{}{}
+		}
+	}
+
+	replayer.mapSeq = map[replayKey]uint64{}
+	replayer.pending = map[uint64]struct{}{}
+	replayer.stats.ErrCodes = map[ErrCode]int{}
+	replayer.emptyCond = sync.NewCond(&replayer.locker)
+	return replayer
+}
+
+// Replay 依次回放抓包文件中结点收到的请求，等待返回后断开连接
+func (replayer *Replayer) Replay(fileNames ...string) (ReplayStats, error) {
+	err := replayer.connect()
+	if err != nil {
+		return replayer.getStats(), err
+	}
+	defer replayer.Close(true)
+
+	var firstTime, startTime time.Time
+	for _, fileName := range fileNames {
+		reader, oErr := OpenCapture(fileName)
+		if oErr != nil {
+			return replayer.getStats(), oErr
+		}
+
+		for {
+			record, rErr := reader.Next()
+			if rErr == io.EOF {
+				break
+			}
+			if rErr != nil {
+				reader.Close()
+				return replayer.getStats(), fmt.Errorf("read %s is failed: %w", fileName, rErr)
+			}
+			if record.Direction != CaptureServerRequest {
+				continue
+			}
+
+			//按抓包时的间隔发送
+			if firstTime.IsZero() {
+				firstTime, startTime = record.Time, time.Now()
+			} else if replayer.cfg.Speed > 0 {
+				offset := time.Duration(float64(record.Time.Sub(firstTime)) / replayer.cfg.Speed)
+				if d := time.Until(startTime.Add(offset)); d > 0 {
+					time.Sleep(d)
+				}
+			}
+
+			rErr = replayer.send(record)
+			if rErr != nil {
+				reader.Close()
+				return replayer.getStats(), rErr
+			}
+		}
+		reader.Close()
+	}
+
+	replayer.waitPending()
+	return replayer.getStats(), nil
+}
+
+func (replayer *Replayer) connect() error {
+	replayer.Addr = replayer.cfg.Addr
+	replayer.ConnNum = 1
+	replayer.ConnectInterval = DefaultConnectInterval
+	replayer.PendingWriteNum = DefaultMaxPendingWriteNum
+	replayer.AutoReconnect = false
+	replayer.LenMsgLen = DefaultRpcLenMsgLen
+	replayer.MinMsgLen = DefaultRpcMinMsgLen
+	replayer.MaxMsgLen = math.MaxUint32
+	replayer.ReadDeadline = Default_ReadWriteDeadline
+	replayer.WriteDeadline = Default_ReadWriteDeadline
+	replayer.LittleEndian = LittleEndian
+	replayer.TLSConfig = getClientTLSConfig(replayer.cfg.TargetNodeId)
+	replayer.readyCh = make(chan error, 1)
+	replayer.closeCh = make(chan struct{})
+	replayer.NewAgent = func(conn *network.NetConn) network.Agent {
+		replayer.conn = conn
+		return replayer
+	}
+	replayer.Start()
+
+	select {
+	case err := <-replayer.readyCh:
+		if err != nil {
+			replayer.Close(false)
+		}
+		return err
+	case <-time.After(replayer.cfg.ConnectTimeout):
+		replayer.Close(false)
+		return errors.New("connect " + replayer.cfg.Addr + " is timeout")
+	}
+}
+
+func (replayer *Replayer) match(serviceMethod string) bool {
+	if replayer.services == nil {
+		return true
+	}
+
+	serviceName, _, _ := strings.Cut(serviceMethod, ".")
+	_, ok := replayer.services[serviceName]
+	return ok
+}
+
+// send 重新分配Seq后发送请求，控制帧按原连接与Seq找到对应的请求
+func (replayer *Replayer) send(record *CaptureRecord) error {
+	processor := GetProcessor(uint8(record.ProcessorType))
+	if processor == nil {
+		return fmt.Errorf("processor type %d is not supported", record.ProcessorType)
+	}
+
+	request := MakeRpcRequest(processor, 0, 0, "", false, nil)
+	defer ReleaseRpcRequest(request)
+	err := processor.Unmarshal(record.Payload, request.RpcRequestData)
+	if err != nil {
+		return err
+	}
+
+	isControl := request.RpcRequestData.IsCancel() || request.RpcRequestData.GetStreamCredit() > 0
+	key := replayKey{connTag: record.ConnTag, seq: request.RpcRequestData.GetSeq()}
+
+	replayer.locker.Lock()
+	seq, ok := replayer.mapSeq[key]
+	if isControl == true {
+		if ok == false {
+			replayer.stats.SkippedNum++
+			replayer.locker.Unlock()
+			return nil
+		}
+		replayer.stats.ControlNum++
+	} else {
+		if replayer.match(request.RpcRequestData.GetServiceMethod()) == false {
+			replayer.stats.SkippedNum++
+			replayer.locker.Unlock()
+			return nil
+		}
+
+		replayer.seq++
+		seq = replayer.seq
+		replayer.mapSeq[key] = seq
+		if request.RpcRequestData.IsNoReply() == false {
+			replayer.pending[seq] = struct{}{}
+		}
+		replayer.stats.RequestNum++
+	}
+	replayer.locker.Unlock()
+
+	request.RpcRequestData.SetSeq(seq)
+	bytes, err := processor.Marshal(request.RpcRequestData)
+	if err != nil {
+		return err
+	}
+
+	return replayer.conn.WriteMsg([]byte{uint8(processor.GetProcessorType())}, bytes)
+}
+
+func (replayer *Replayer) processResponse(data []byte) error {
+	processor, byteData, release, err := uncompressFrame(data)
+	if err != nil {
+		return err
+	}
+
+	response := processor.MakeRpcResponse(0, nil, nil)
+	defer processor.ReleaseRpcResponse(response)
+	err = processor.Unmarshal(byteData, response)
+	release()
+	if err != nil {
+		return err
+	}
+
+	replayer.locker.Lock()
+	defer replayer.locker.Unlock()
+
+	if response.IsStreamMsg() == true {
+		replayer.stats.StreamMsgNum++
+		return nil
+	}
+
+	replayer.stats.ResponseNum++
+	if rErr := response.GetErr(); rErr != nil {
+		replayer.stats.ErrCodes[Code(rErr)]++
+	}
+
+	delete(replayer.pending, response.GetSeq())
+	if len(replayer.pending) == 0 {
+		replayer.emptyCond.Broadcast()
+	}
+
+	return nil
+}
+
+// waitPending 等待所有请求返回，超时或连接断开时返回
+func (replayer *Replayer) waitPending() {
+	timer := time.AfterFunc(replayer.cfg.WaitTimeout, func() {
+		replayer.locker.Lock()
+		replayer.emptyCond.Broadcast()
+		replayer.locker.Unlock()
+	})
+	defer timer.Stop()
+
+	deadline := time.Now().Add(replayer.cfg.WaitTimeout)
+	replayer.locker.Lock()
+	for len(replayer.pending) > 0 && time.Now().Before(deadline) && replayer.isClosed() == false {
+		replayer.emptyCond.Wait()
+	}
+	replayer.locker.Unlock()
+}
+
+func (replayer *Replayer) isClosed() bool {
+	select {
+	case <-replayer.closeCh:
+		return true
+	default:
+		return false
+	}
+}
+
+func (replayer *Replayer) getStats() ReplayStats {
+	replayer.locker.Lock()
+	defer replayer.locker.Unlock()
+
+	stats := replayer.stats
+	stats.PendingNum = len(replayer.pending)
+	stats.ErrCodes = make(map[ErrCode]int, len(replayer.stats.ErrCodes))
+	for code, num := range replayer.stats.ErrCodes {
+		stats.ErrCodes[code] = num
+	}
+
+	return stats
+}
+
+func (replayer *Replayer) Run() {
+	defer func() {
+		if r := recover(); r != nil {
+			log.StackError(fmt.Sprint(r))
+		}
+	}()
+
+	err := clientHandshake(replayer.conn, "", replayer.cfg.TargetNodeId)
+	replayer.readyCh <- err
+	if err != nil {
+		return
+	}
+
+	for {
+		bytes, err := replayer.conn.ReadMsg()
+		if err != nil {
+			return
+		}
+
+		if len(bytes) > 0 && bytes[0] == frameHandshake {
+			replayer.conn.ReleaseReadMsg(bytes)
+			log.Error("replayer is rejected by peer", log.String("addr", replayer.cfg.Addr))
+			return
+		}
+
+		err = replayer.processResponse(bytes)
+		replayer.conn.ReleaseReadMsg(bytes)
+		if err != nil {
+			log.Error("replayer process response is failed", log.ErrorField("error", err))
+			return
+		}
+	}
+}
+
+func (replayer *Replayer) OnClose() {
+	close(replayer.closeCh)
+
+	replayer.locker.Lock()
+	replayer.emptyCond.Broadcast()
+	replayer.locker.Unlock()
+}
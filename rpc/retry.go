@@ -0,0 +1,286 @@
+package rpc
+
+import (
+	"context"
+	"math/rand"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/duanhf2012/origin/v2/log"
+)
+
+const (
+	DefaultRetryInitialBackoff = 100 * time.Millisecond
+	DefaultRetryMaxBackoff     = 2 * time.Second
+	DefaultRetryMultiplier     = 2.0
+)
+
+// DefaultRetryableCodes 未配置RetryableCodes时可重试的错误码
+var DefaultRetryableCodes = []ErrCode{CodeDisconnected, CodeNoNode, CodeOverload, CodeTimeout, CodeCircuitOpen}
+
+// RetryPolicy 调用失败时的重试策略，只应配置给幂等的RPC函数，未配置的函数永远不会重试
+type RetryPolicy struct {
+	MaxAttempts    int           //最大调用次数(包括第一次)，小于等于1时不重试
+	InitialBackoff time.Duration //第一次重试前的等待时间
+	MaxBackoff     time.Duration //最大等待时间
+	Multiplier     float64       //每次重试等待时间的增长倍数
+	RetryableCodes []ErrCode     //可重试的错误码
+}
+
+var retryLocker sync.RWMutex
+var mapRetryPolicy = map[string]*RetryPolicy{}
+
+// SetRetryPolicy 设置重试策略，serviceMethod为"Service.RPC_Method"时对单个函数生效，为"Service"时对服务的所有函数生效，
+// 函数的配置优先。policy为nil时删除配置，MaxAttempts小于等于1可用于排除服务中不幂等的函数
+func SetRetryPolicy(serviceMethod string, policy *RetryPolicy) {
+	retryLocker.Lock()
+	defer retryLocker.Unlock()
+
+	if policy == nil {
+		delete(mapRetryPolicy, serviceMethod)
+		return
+	}
+
+	p := *policy
+	if p.InitialBackoff <= 0 {
+		p.InitialBackoff = DefaultRetryInitialBackoff
+	}
+	if p.MaxBackoff <= 0 {
+		p.MaxBackoff = DefaultRetryMaxBackoff
+	}
+	if p.Multiplier < 1 {
+		p.Multiplier = DefaultRetryMultiplier
+	}
+	if len(p.RetryableCodes) == 0 {
+		p.RetryableCodes = DefaultRetryableCodes
+	}
+	mapRetryPolicy[serviceMethod] = &p
+}
+
+// GetRetryPolicy 获取函数的重试策略，不重试时返回nil
+func GetRetryPolicy(serviceMethod string) *RetryPolicy {
+	retryLocker.RLock()
+	defer retryLocker.RUnlock()
+
+	if len(mapRetryPolicy) == 0 {
+		return nil
+	}
+
+	policy, ok := mapRetryPolicy[serviceMethod]
+	if ok == false {
+		findIndex := strings.Index(serviceMethod, ".")
+		if findIndex == -1 {
+			return nil
+		}
+
+		policy, ok = mapRetryPolicy[serviceMethod[:findIndex]]
+		if ok == false {
+			return nil
+		}
+	}
+
+	if policy.MaxAttempts <= 1 {
+		return nil
+	}
+
+	return policy
+}
+
+func (policy *RetryPolicy) isRetryable(err error) bool {
+	if err == nil {
+		return false
+	}
+
+	code := Code(err)
+	for _, c := range policy.RetryableCodes {
+		if c == code {
+			return true
+		}
+	}
+
+	return false
+}
+
+// backoff 第attempt次重试前的等待时间，加入随机抖动避免同时重试
+func (policy *RetryPolicy) backoff(attempt int) time.Duration {
+	backoff := float64(policy.InitialBackoff)
+	for i := 1; i < attempt; i++ {
+		backoff *= policy.Multiplier
+		if backoff >= float64(policy.MaxBackoff) {
+			backoff = float64(policy.MaxBackoff)
+			break
+		}
+	}
+
+	return time.Duration(backoff/2 + rand.Float64()*backoff/2)
+}
+
+// canRetry 判断是否还能进行第attempt次重试，ctx的截止时间不足以等待时不再重试
+func (policy *RetryPolicy) canRetry(ctx context.Context, attempt int, err error) (time.Duration, bool) {
+	if attempt >= policy.MaxAttempts || policy.isRetryable(err) == false || ctx.Err() != nil {
+		return 0, false
+	}
+
+	backoff := policy.backoff(attempt)
+	if deadline, ok := ctx.Deadline(); ok == true && time.Until(deadline) <= backoff {
+		return 0, false
+	}
+
+	return backoff, true
+}
+
+// retryTimeout 重试时的超时时间，ctx有截止时间时取剩余时间
+func retryTimeout(ctx context.Context, timeout time.Duration) (time.Duration, error) {
+	if _, ok := ctx.Deadline(); ok == false {
+		return timeout, nil
+	}
+
+	return getContextTimeout(ctx)
+}
+
+// callRpcWithRetry 同步调用失败时按策略重试，重试时优先选择其他结点
+func (handler *RpcHandler) callRpcWithRetry(ctx context.Context, policy *RetryPolicy, timeout time.Duration, nodeId string, serviceMethod string, args interface{}, reply interface{}) error {
+	var excludeNodes []string
+	for attempt := 1; ; attempt++ {
+		pClient, err := handler.selectRpcClient(nodeId, serviceMethod, excludeNodes...)
+		if err == nil && pClient == nil {
+			err = NewError(CodeNoNode, "Call serviceMethod is error:cannot find "+serviceMethod)
+		}
+
+		if err == nil {
+			err = handler.callClient(ctx, timeout, pClient, serviceMethod, args, reply)
+			if err == nil {
+				return nil
+			}
+			excludeNodes = append(excludeNodes, pClient.GetTargetNodeId())
+		}
+
+		backoff, ok := policy.canRetry(ctx, attempt, err)
+		if ok == false {
+			return err
+		}
+
+		time.Sleep(backoff)
+		if timeout, err = retryTimeout(ctx, timeout); err != nil {
+			return err
+		}
+	}
+}
+
+// asyncRetry 异步调用的重试状态，所有重试都在调用方服务的协程中发起
+type asyncRetry struct {
+	handler       *RpcHandler
+	ctx           context.Context
+	policy        *RetryPolicy
+	timeout       time.Duration
+	nodeId        string
+	serviceMethod string
+	args          interface{}
+	callback      reflect.Value
+
+	attempt      int
+	excludeNodes []string
+	canceled     bool
+	cancelRpc    CancelRpc
+	retryCB      reflect.Value
+}
+
+func (handler *RpcHandler) asyncCallRpcWithRetry(ctx context.Context, policy *RetryPolicy, timeout time.Duration, nodeId string, serviceMethod string, args interface{}, fVal reflect.Value) (CancelRpc, error) {
+	r := &asyncRetry{handler: handler, ctx: ctx, policy: policy, timeout: timeout, nodeId: nodeId, serviceMethod: serviceMethod, args: args, callback: fVal}
+	r.cancelRpc = emptyCancelRpc
+	r.retryCB = reflect.ValueOf(r.retry)
+	if err := r.call(); err != nil {
+		return emptyCancelRpc, err
+	}
+
+	return r.cancel, nil
+}
+
+func (r *asyncRetry) cancel() {
+	r.canceled = true
+	r.cancelRpc()
+}
+
+// call 发起一次调用，第一次调用发送失败且不可重试时返回错误，与不重试时保持一致
+func (r *asyncRetry) call() error {
+	r.attempt++
+	r.cancelRpc = emptyCancelRpc
+	reply := newReplyParam(r.callback)
+
+	pClient, err := r.handler.selectRpcClient(r.nodeId, r.serviceMethod, r.excludeNodes...)
+	if err == nil && pClient == nil {
+		err = NewError(CodeNoNode, "no "+r.serviceMethod+" service found in the origin network")
+	}
+	if err != nil {
+		r.onResult([]reflect.Value{reflect.ValueOf(reply), reflect.ValueOf(err)})
+		return nil
+	}
+
+	nodeId := pClient.GetTargetNodeId()
+	callback := reflect.MakeFunc(r.callback.Type(), func(args []reflect.Value) []reflect.Value {
+		if args[1].IsNil() == false {
+			r.excludeNodes = append(r.excludeNodes, nodeId)
+		}
+		r.onResult(args)
+		return nil
+	})
+
+	cancelRpc, err := r.handler.asyncCallClient(r.ctx, r.timeout, pClient, r.serviceMethod, callback, r.args, reply, 0)
+	if err != nil {
+		r.excludeNodes = append(r.excludeNodes, nodeId)
+		if _, ok := r.policy.canRetry(r.ctx, r.attempt, err); ok == false && r.attempt == 1 {
+			return err
+		}
+
+		r.onResult([]reflect.Value{reflect.ValueOf(reply), reflect.ValueOf(err)})
+		return nil
+	}
+
+	r.cancelRpc = cancelRpc
+	return nil
+}
+
+func (r *asyncRetry) onResult(args []reflect.Value) {
+	var err error
+	if args[1].IsNil() == false {
+		err = args[1].Interface().(error)
+	}
+
+	backoff, ok := r.policy.canRetry(r.ctx, r.attempt, err)
+	if r.canceled == true || ok == false {
+		r.callback.Call(args)
+		return
+	}
+
+	//等待后通过调用方服务的队列发起重试，保证回调在服务协程中执行
+	time.AfterFunc(backoff, func() {
+		call := MakeCall()
+		call.Reply = r
+		call.callback = &r.retryCB
+		call.rpcHandler = r.handler.rpcHandler
+		if pushErr := r.handler.rpcHandler.PushRpcResponse(call); pushErr != nil {
+			log.Error("push retry call is fail", log.String("serviceMethod", r.serviceMethod), log.ErrorField("error", pushErr))
+			ReleaseCall(call)
+		}
+	})
+}
+
+// retry 在服务协程中发起下一次重试
+func (r *asyncRetry) retry(_ *asyncRetry, _ error) {
+	if r.canceled == true {
+		return
+	}
+
+	timeout, err := retryTimeout(r.ctx, r.timeout)
+	if err != nil {
+		r.callback.Call([]reflect.Value{reflect.New(r.callback.Type().In(0).Elem()), reflect.ValueOf(err)})
+		return
+	}
+
+	r.timeout = timeout
+	if err = r.call(); err != nil {
+		r.callback.Call([]reflect.Value{reflect.New(r.callback.Type().In(0).Elem()), reflect.ValueOf(err)})
+	}
+}
@@ -1,6 +1,7 @@
 package rpc
 
 import (
+	"context"
 	"github.com/duanhf2012/origin/v2/util/sync"
 	"reflect"
 	"time"
@@ -16,6 +17,19 @@ type RpcRequest struct {
 	requestHandle RequestHandler
 	callback *reflect.Value
 	rpcProcessor IRpcProcessor
+
+	deadline   time.Time       //调用方的截止时间，超过后不再处理
+	parentCtx  context.Context //本结点调用时调用方传入的Context
+	ctx        context.Context
+	ctxCancel  context.CancelFunc
+	canceled   bool        //调用方发送了取消帧
+	server     *BaseServer //跨结点请求所在的Server,用于接收取消帧
+	connTag    string
+	callClient *Client //本结点调用时调用方的Client与Seq，调用方取消或超时后不再处理
+	callSeq    uint64
+
+	replyMetadata *Metadata //RPC函数设置的返回Metadata
+	stream        *ServerStream //流式调用的发送端
 }
 
 type RpcResponse struct {
@@ -43,19 +57,39 @@ type IRpcRequestData interface {
 	GetInParam() []byte
 	IsNoReply() bool
 	GetRpcMethodId() uint32
+	GetTimeout() int64
+	IsCancel() bool
+	GetMetadata() map[string]string
+	GetStreamWindow() uint32
+	GetStreamCredit() uint32
+	GetCallerNodeId() string
+
+	SetTimeout(timeout int64)
+	SetCancel(cancel bool)
+	SetMetadata(metadata map[string]string)
+	SetStreamWindow(window uint32)
+	SetStreamCredit(credit uint32)
+	SetCallerNodeId(nodeId string)
+	SetSeq(seq uint64)
 }
 
 type IRpcResponseData interface {
 	GetSeq() uint64
-	GetErr() *RpcError
+	GetErr() error
 	GetReply() []byte
+	GetMetadata() map[string]string
+	IsStreamMsg() bool
+
+	SetMetadata(metadata map[string]string)
+	SetStreamMsg(streamMsg bool)
 }
 
 type RpcHandleFinder interface {
 	FindRpcHandler(serviceMethod string) IRpcHandler
 }
 
-type RequestHandler func(Returns interface{},Err RpcError)
+// RequestHandler Err可以传入RpcError(NilError表示成功)、*Error或其他error
+type RequestHandler func(Returns interface{},Err error)
 
 type Call struct {
 	ref           bool
@@ -66,9 +100,15 @@ type Call struct {
 	Err           error
 	done          chan *Call  // Strobes when call is complete.
 	connId        int
+	nodeId        string
 	callback      *reflect.Value
 	rpcHandler    IRpcHandler
 	TimeOut       time.Duration
+	stopCtx       func() bool //停止监听调用方的Context
+	replyMetadata *Metadata   //接收被调用方返回的Metadata
+	breaker       *CircuitBreaker //调用结果需要计入的熔断器
+	breakerGen    uint64
+	stream        *clientStream //流式调用的接收状态，Err为nil时表示一条消息
 }
 
 type RpcCancel struct {
@@ -77,7 +117,17 @@ type RpcCancel struct {
 }
 
 func (rc *RpcCancel) CancelRpc(){
-	rc.Cli.RemovePending(rc.CallSeq)
+	call := rc.Cli.RemovePending(rc.CallSeq)
+	if call == nil {
+		return
+	}
+
+	call.reportBreaker(nil, true)
+	if call.stream != nil {
+		call.stream.cancel()
+	} else {
+		rc.Cli.SendCancel(call.nodeId, rc.CallSeq, call.connId)
+	}
 }
 
 func (slf *RpcRequest) Clear() *RpcRequest{
@@ -87,6 +137,20 @@ func (slf *RpcRequest) Clear() *RpcRequest{
 	slf.requestHandle = nil
 	slf.callback = nil
 	slf.rpcProcessor = nil
+	if slf.ctxCancel != nil {
+		slf.ctxCancel()
+	}
+	slf.deadline = time.Time{}
+	slf.parentCtx = nil
+	slf.ctx = nil
+	slf.ctxCancel = nil
+	slf.canceled = false
+	slf.server = nil
+	slf.connTag = ""
+	slf.callClient = nil
+	slf.callSeq = 0
+	slf.replyMetadata = nil
+	slf.stream = nil
 	return slf
 }
 
@@ -131,9 +195,17 @@ func (call *Call) Clear() *Call{
 
 	call.Err = nil
 	call.connId = 0
+	call.nodeId = ""
 	call.callback = nil
 	call.rpcHandler = nil
 	call.TimeOut = 0
+	if call.stopCtx != nil {
+		call.stopCtx()
+		call.stopCtx = nil
+	}
+	call.replyMetadata = nil
+	call.reportBreaker(nil, true)
+	call.stream = nil
 
 	return call
 }
@@ -167,6 +239,9 @@ func MakeRpcRequest(rpcProcessor IRpcProcessor,seq uint64,rpcMethodId uint32,ser
 }
 
 func ReleaseRpcRequest(rpcRequest *RpcRequest){
+	if rpcRequest.server != nil {
+		rpcRequest.server.removeRequest(rpcRequest)
+	}
 	rpcRequest.rpcProcessor.ReleaseRpcRequest(rpcRequest.RpcRequestData)
 	rpcRequestPool.Put(rpcRequest)
 }
@@ -0,0 +1,232 @@
+package rpc
+
+import (
+	"context"
+	"reflect"
+	"time"
+
+	"github.com/duanhf2012/origin/v2/log"
+)
+
+var contextType = reflect.TypeOf((*context.Context)(nil)).Elem()
+
+// requestKey 跨结点请求的唯一标识，connTag区分不同连接(或NATS下的调用结点)，Seq由调用方生成
+type requestKey struct {
+	connTag string
+	seq     uint64
+}
+
+// getContextTimeout 根据ctx的截止时间计算超时时间，无截止时间时使用默认超时
+func getContextTimeout(ctx context.Context) (time.Duration, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, contextError(err)
+	}
+
+	deadline, ok := ctx.Deadline()
+	if ok == false {
+		return DefaultRpcTimeout, nil
+	}
+
+	timeout := time.Until(deadline)
+	if timeout <= 0 {
+		return 0, contextError(context.DeadlineExceeded)
+	}
+
+	return timeout, nil
+}
+
+func (server *BaseServer) addRequest(connTag string, req *RpcRequest) {
+	server.requestLocker.Lock()
+	if server.mapRequest == nil {
+		server.mapRequest = make(map[requestKey]*RpcRequest, 1024)
+	}
+
+	req.server = server
+	req.connTag = connTag
+	server.mapRequest[requestKey{connTag: connTag, seq: req.RpcRequestData.GetSeq()}] = req
+	server.requestLocker.Unlock()
+}
+
+func (server *BaseServer) removeRequest(req *RpcRequest) {
+	server.requestLocker.Lock()
+	key := requestKey{connTag: req.connTag, seq: req.RpcRequestData.GetSeq()}
+	if server.mapRequest[key] == req {
+		delete(server.mapRequest, key)
+	}
+	server.requestLocker.Unlock()
+}
+
+// cancelRequest 收到调用方的取消帧，还在队列中的请求将被丢弃，已在处理的请求通过ctx通知
+func (server *BaseServer) cancelRequest(connTag string, seq uint64) {
+	server.requestLocker.Lock()
+	req, ok := server.mapRequest[requestKey{connTag: connTag, seq: seq}]
+	if ok == true {
+		req.canceled = true
+		if req.ctxCancel != nil {
+			req.ctxCancel()
+		}
+		if req.stream != nil {
+			req.stream.cancel()
+		}
+	}
+	server.requestLocker.Unlock()
+}
+
+// addStreamCredit 调用方归还流式调用的接收窗口
+func (server *BaseServer) addStreamCredit(connTag string, seq uint64, credit uint32) {
+	server.requestLocker.Lock()
+	req, ok := server.mapRequest[requestKey{connTag: connTag, seq: seq}]
+	if ok == true && req.stream != nil {
+		req.stream.addCredit(credit)
+	}
+	server.requestLocker.Unlock()
+}
+
+// isExpired 调用方已超时或取消
+func (slf *RpcRequest) isExpired() bool {
+	if slf.deadline.IsZero() == false && time.Now().After(slf.deadline) {
+		return true
+	}
+
+	if slf.parentCtx != nil && slf.parentCtx.Err() != nil {
+		return true
+	}
+
+	if slf.callClient != nil && slf.callClient.FindPending(slf.callSeq) == nil {
+		return true
+	}
+
+	if slf.server != nil {
+		slf.server.requestLocker.Lock()
+		canceled := slf.canceled
+		slf.server.requestLocker.Unlock()
+		return canceled
+	}
+
+	return false
+}
+
+// getContext 获取传给RPC函数的Context，带有调用方的截止时间与Metadata，调用方取消时Done。
+// 本结点调用时不继承调用方ctx中的值，与跨结点调用保持一致
+func (slf *RpcRequest) getContext() context.Context {
+	if slf.server != nil {
+		slf.server.requestLocker.Lock()
+		defer slf.server.requestLocker.Unlock()
+	}
+
+	if slf.ctx != nil {
+		return slf.ctx
+	}
+
+	ctx := context.Background()
+	if md := slf.RpcRequestData.GetMetadata(); len(md) > 0 {
+		ctx = context.WithValue(ctx, incomingMetadataKey{}, Metadata(md))
+	}
+	slf.replyMetadata = &Metadata{}
+	ctx = context.WithValue(ctx, setReplyMetadataKey{}, slf.replyMetadata)
+
+	var cancel context.CancelFunc
+	if slf.deadline.IsZero() == false {
+		slf.ctx, cancel = context.WithDeadline(ctx, slf.deadline)
+	} else {
+		slf.ctx, cancel = context.WithCancel(ctx)
+	}
+
+	slf.ctxCancel = cancel
+	if slf.parentCtx != nil {
+		stop := context.AfterFunc(slf.parentCtx, cancel)
+		slf.ctxCancel = func() {
+			stop()
+			cancel()
+		}
+	}
+
+	if slf.canceled == true {
+		slf.ctxCancel()
+	}
+
+	return slf.ctx
+}
+
+// getReplyMetadata 获取RPC函数设置的返回Metadata
+func (slf *RpcRequest) getReplyMetadata() Metadata {
+	if slf.replyMetadata == nil {
+		return nil
+	}
+
+	return *slf.replyMetadata
+}
+
+// watchContext 调用方的ctx结束时，移除等待中的调用，并通知被调用方取消
+func (client *Client) watchContext(ctx context.Context, call *Call) {
+	if ctx == nil || ctx.Done() == nil {
+		return
+	}
+
+	seq := call.Seq
+	stop := context.AfterFunc(ctx, func() {
+		client.cancelPending(seq, ctx.Err())
+	})
+
+	//call可能已经返回并被回收，只有还在等待中时才记录
+	client.pendingLock.Lock()
+	if client.pending[seq] == call {
+		call.stopCtx = stop
+	} else {
+		stop()
+	}
+	client.pendingLock.Unlock()
+}
+
+func (client *Client) cancelPending(seq uint64, err error) {
+	call := client.RemovePending(seq)
+	if call == nil {
+		return
+	}
+
+	if call.stream != nil {
+		call.stream.cancel()
+	} else {
+		client.SendCancel(call.nodeId, seq, call.connId)
+	}
+	call.Err = contextError(err)
+	call.reportBreaker(call.Err, Code(call.Err) == CodeCanceled)
+	client.makeCallFail(call)
+}
+
+// sendCancel 发送取消帧，NoReply置为true，旧版本结点收到后不会返回
+func (client *Client) sendCancel(nodeId string, w IWriter, seq uint64, connId int) {
+	client.sendControl(nodeId, w, seq, connId, 0)
+}
+
+// sendStreamCredit 流式调用中归还已处理消息的接收窗口
+func (client *Client) sendStreamCredit(nodeId string, w IWriter, seq uint64, connId int, credit uint32) {
+	client.sendControl(nodeId, w, seq, connId, credit)
+}
+
+// sendControl 发送控制帧，credit为0时为取消帧。被调用方按连接查找请求，需与请求使用同一连接
+func (client *Client) sendControl(nodeId string, w IWriter, seq uint64, connId int, credit uint32) {
+	if w == nil || w.IsConnected() == false {
+		return
+	}
+
+	processor := GetProcessor(uint8(RpcProcessorPB))
+	request := MakeRpcRequest(processor, seq, 0, "", true, nil)
+	if credit > 0 {
+		request.RpcRequestData.SetStreamCredit(credit)
+	} else {
+		request.RpcRequestData.SetCancel(true)
+	}
+	bytes, err := processor.Marshal(request.RpcRequestData)
+	ReleaseRpcRequest(request)
+	if err != nil {
+		log.Error("marshal control request is fail", log.Uint64("seq", seq), log.ErrorField("error", err))
+		return
+	}
+
+	captureFrame(CaptureClientRequest, processor.GetProcessorType(), seq, nodeId, "", "", bytes)
+	err = writeLinkMsg(w, nodeId, connId, []byte{uint8(processor.GetProcessorType())}, bytes)
+	if err != nil {
+		log.Error("write control request is fail", log.String("nodeId", nodeId), log.Uint64("seq", seq), log.ErrorField("error", err))
+	}
+}
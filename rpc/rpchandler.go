@@ -1,12 +1,13 @@
 package rpc
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"github.com/duanhf2012/origin/v2/event"
 	"github.com/duanhf2012/origin/v2/log"
 	"reflect"
-
+	"slices"
 	"strings"
 	"time"
 	"unicode"
@@ -17,11 +18,19 @@ const maxClusterNode int = 32
 
 type FuncRpcClient func(nodeId string, serviceMethod string, filterRetire bool, client []*Client) (error, []*Client)
 type FuncRpcServer func() IServer
+type FuncNodeIdByKey func(serviceName string, key string) (string, error)
 
 const NodeIdNull = ""
 
 var nilError = reflect.Zero(reflect.TypeOf((*error)(nil)).Elem())
 
+var funcNodeIdByKey FuncNodeIdByKey
+
+// SetNodeIdByKeyFun 设置通过Key找出服务所属结点的函数，由cluster在初始化时设置
+func SetNodeIdByKeyFun(fun FuncNodeIdByKey) {
+	funcNodeIdByKey = fun
+}
+
 type RpcError string
 
 var NilError RpcError
@@ -45,7 +54,13 @@ type RpcMethodInfo struct {
 	inParam          interface{}
 	outParamValue    reflect.Value
 	hasResponder     bool
+	hasContext       bool //第一个参数为context.Context
+	hasStream        bool //流式调用，参数中有*ServerStream
 	rpcProcessorType RpcProcessorType
+
+	invoker     methodInvoker //通过RegisterRpcMethod注册时免反射调用
+	newInParam  func() interface{}
+	newOutParam func() interface{}
 }
 
 type RawRpcCallBack func(rawData []byte)
@@ -63,6 +78,11 @@ type RpcHandler struct {
 	mapRawFunctions map[uint32]RawRpcCallBack
 	funcRpcClient   FuncRpcClient
 	funcRpcServer   FuncRpcServer
+	funcNodeIdByKey FuncNodeIdByKey //为nil时使用SetNodeIdByKeyFun设置的函数
+
+	clientInterceptors []ClientInterceptor
+	serverInterceptors []ServerInterceptor
+	rateLimits         rateLimitSet //本服务设置的限流
 
 	//pClientList []*Client
 }
@@ -85,6 +105,11 @@ type IDiscoveryServiceListener interface {
 	OnUnDiscoveryService(nodeId string, serviceName []string)
 }
 
+// IHashRingListener 服务的一致性哈希环发生变化时通知，可用于迁移不再属于本结点的Key
+type IHashRingListener interface {
+	OnHashRingChange(serviceName []string)
+}
+
 type CancelRpc func()
 
 func emptyCancelRpc() {}
@@ -94,9 +119,11 @@ type IRpcHandler interface {
 	GetName() string
 	InitRpcHandler(rpcHandler IRpcHandler, getClientFun FuncRpcClient, getServerFun FuncRpcServer, rpcHandlerChannel IRpcHandlerChannel)
 	GetRpcHandler() IRpcHandler
+	SetNodeIdByKeyFun(fun FuncNodeIdByKey)
+	DescribeRpc() RpcServiceDesc
 	HandlerRpcRequest(request *RpcRequest)
 	HandlerRpcResponseCB(call *Call)
-	CallMethod(client *Client, ServiceMethod string, param interface{}, callBack reflect.Value, reply interface{}) error
+	CallMethod(ctx context.Context, client *Client, ServiceMethod string, param interface{}, callBack reflect.Value, reply interface{}) error
 
 	Call(serviceMethod string, args interface{}, reply interface{}) error
 	CallNode(nodeId string, serviceMethod string, args interface{}, reply interface{}) error
@@ -108,15 +135,33 @@ type IRpcHandler interface {
 	AsyncCallWithTimeout(timeout time.Duration, serviceMethod string, args interface{}, callback interface{}) (CancelRpc, error)
 	AsyncCallNodeWithTimeout(timeout time.Duration, nodeId string, serviceMethod string, args interface{}, callback interface{}) (CancelRpc, error)
 
+	CallContext(ctx context.Context, serviceMethod string, args interface{}, reply interface{}) error
+	CallNodeContext(ctx context.Context, nodeId string, serviceMethod string, args interface{}, reply interface{}) error
+	AsyncCallContext(ctx context.Context, serviceMethod string, args interface{}, callback interface{}) (CancelRpc, error)
+	AsyncCallNodeContext(ctx context.Context, nodeId string, serviceMethod string, args interface{}, callback interface{}) (CancelRpc, error)
+
+	StreamCall(serviceMethod string, args interface{}, callback interface{}) (CancelRpc, error)
+	StreamCallNode(nodeId string, serviceMethod string, args interface{}, callback interface{}) (CancelRpc, error)
+	StreamCallContext(ctx context.Context, serviceMethod string, args interface{}, callback interface{}) (CancelRpc, error)
+	StreamCallNodeContext(ctx context.Context, nodeId string, serviceMethod string, args interface{}, callback interface{}) (CancelRpc, error)
+
 	Go(serviceMethod string, args interface{}) error
 	GoNode(nodeId string, serviceMethod string, args interface{}) error
 	RawGoNode(rpcProcessorType RpcProcessorType, nodeId string, rpcMethodId uint32, serviceName string, rawArgs []byte) error
 	CastGo(serviceMethod string, args interface{}) error
+	CastCall(serviceMethod string, args interface{}, reply interface{}) ([]CastReply, error)
+	CastCallContext(ctx context.Context, filter CastFilter, serviceMethod string, args interface{}, reply interface{}) ([]CastReply, error)
+	AsyncCastCall(serviceMethod string, args interface{}, reply interface{}, callback func(replies []CastReply, err error)) (CancelRpc, error)
+	AsyncCastCallContext(ctx context.Context, filter CastFilter, serviceMethod string, args interface{}, reply interface{}, callback func(replies []CastReply, err error)) (CancelRpc, error)
+
+	CallByKey(key string, serviceMethod string, args interface{}, reply interface{}) error
+	AsyncCallByKey(key string, serviceMethod string, args interface{}, callback interface{}) error
+	GoByKey(key string, serviceMethod string, args interface{}) error
 	UnmarshalInParam(rpcProcessor IRpcProcessor, serviceMethod string, rawRpcMethodId uint32, inParam []byte) (interface{}, error)
 	GetRpcServer() FuncRpcServer
 }
 
-func reqHandlerNull(Returns interface{}, Err RpcError) {
+func reqHandlerNull(Returns interface{}, Err error) {
 }
 
 var requestHandlerNull reflect.Value
@@ -139,6 +184,11 @@ func (handler *RpcHandler) InitRpcHandler(rpcHandler IRpcHandler, getClientFun F
 	handler.RegisterRpc(rpcHandler)
 }
 
+// SetNodeIdByKeyFun 设置本服务通过Key找出结点的函数，同一进程运行多个结点时使用所在结点的一致性哈希环
+func (handler *RpcHandler) SetNodeIdByKeyFun(fun FuncNodeIdByKey) {
+	handler.funcNodeIdByKey = fun
+}
+
 // Is this an exported - upper case - name?
 func isExported(name string) bool {
 	r, _ := utf8.DecodeRuneInString(name)
@@ -165,15 +215,30 @@ func (handler *RpcHandler) suitableMethods(method reflect.Method) error {
 	var rpcMethodInfo RpcMethodInfo
 	typ := method.Type
 
-	if typ.NumIn() < 2 || typ.NumIn() > 4 {
+	if typ.NumIn() < 2 || typ.NumIn() > 5 {
 		return fmt.Errorf("%s Unsupported parameter format", method.Name)
 	}
 
 	//1.判断第一个参数
 	var parIdx = 1
+	if typ.In(parIdx) == contextType {
+		parIdx += 1
+		rpcMethodInfo.hasContext = true
+	}
+
+	if (rpcMethodInfo.hasContext == false && typ.NumIn() > 4) || parIdx >= typ.NumIn() {
+		return fmt.Errorf("%s Unsupported parameter format", method.Name)
+	}
+
 	if typ.In(parIdx).String() == "rpc.RequestHandler" {
 		parIdx += 1
 		rpcMethodInfo.hasResponder = true
+	} else if typ.In(parIdx) == serverStreamType {
+		parIdx += 1
+		rpcMethodInfo.hasStream = true
+		if parIdx+1 != typ.NumIn() {
+			return fmt.Errorf("%s stream method must have only one input parameter", method.Name)
+		}
 	}
 
 	if rpcMethodInfo.hasResponder && typ.NumOut() > 0  {
@@ -196,12 +261,18 @@ func (handler *RpcHandler) suitableMethods(method reflect.Method) error {
 
 	rpcMethodInfo.inParamValue = reflect.New(typ.In(parIdx).Elem())
 	rpcMethodInfo.inParam = reflect.New(typ.In(parIdx).Elem()).Interface()
+	rpcMethodInfo.newInParam = getNewParam(typ.In(parIdx))
 	pt, _ := GetProcessorType(rpcMethodInfo.inParamValue.Interface())
 	rpcMethodInfo.rpcProcessorType = pt
 
 	parIdx++
 	if parIdx < typ.NumIn() {
 		rpcMethodInfo.outParamValue = reflect.New(typ.In(parIdx).Elem())
+		rpcMethodInfo.newOutParam = getNewParam(typ.In(parIdx))
+	}
+
+	if rpcMethodInfo.hasResponder == false && rpcMethodInfo.outParamValue.IsValid() {
+		rpcMethodInfo.invoker = getMethodInvoker(method)
 	}
 
 	rpcMethodInfo.method = method
@@ -229,10 +300,10 @@ func (handler *RpcHandler) HandlerRpcResponseCB(call *Call) {
 		}
 	}()
 
-	if call.Err == nil {
-		call.callback.Call([]reflect.Value{reflect.ValueOf(call.Reply), nilError})
+	if call.stream != nil && call.Err == nil {
+		call.stream.onMsg(*call.callback, call.Reply)
 	} else {
-		call.callback.Call([]reflect.Value{reflect.ValueOf(call.Reply), reflect.ValueOf(call.Err)})
+		invokeCallback(*call.callback, call.Reply, call.Err)
 	}
 	ReleaseCall(call)
 }
@@ -245,7 +316,7 @@ func (handler *RpcHandler) HandlerRpcRequest(request *RpcRequest) {
 	defer func() {
 		if r := recover(); r != nil {
 			log.StackError(fmt.Sprint(r))
-			rpcErr := RpcError("call error : core dumps")
+			rpcErr := NewError(CodePanic, "call error : core dumps")
 			if request.requestHandle != nil {
 				request.requestHandle(nil, rpcErr)
 			}
@@ -270,13 +341,82 @@ func (handler *RpcHandler) HandlerRpcRequest(request *RpcRequest) {
 		return
 	}
 
+	//调用方已超时或取消，不再处理
+	if request.isExpired() {
+		log.Warn("rpc request is expired or canceled", log.String("serviceMethod", request.RpcRequestData.GetServiceMethod()))
+		if request.requestHandle != nil {
+			ReleaseRpcRequest(request)
+		}
+		return
+	}
+
 	//普通的rpc请求
 	v, ok := handler.mapFunctions[request.RpcRequestData.GetServiceMethod()]
 	if ok == false {
 		err := "RpcHandler " + handler.rpcHandler.GetName() + " cannot find " + request.RpcRequestData.GetServiceMethod()
 		log.Error("HandlerRpcRequest cannot find serviceMethod", log.String("RpcHandlerName", handler.rpcHandler.GetName()), log.String("serviceMethod", request.RpcRequestData.GetServiceMethod()))
 		if request.requestHandle != nil {
-			request.requestHandle(nil, RpcError(err))
+			request.requestHandle(nil, NewError(CodeMethodNotFound, err))
+		}
+		return
+	}
+
+	//流式调用与RPC函数不匹配
+	if v.hasStream != (request.stream != nil) {
+		rErr := "Call Rpc " + request.RpcRequestData.GetServiceMethod() + " stream mismatch!"
+		log.Error("call serviceMethod stream mismatch", log.String("serviceMethod", request.RpcRequestData.GetServiceMethod()))
+		if request.requestHandle != nil {
+			request.requestHandle(nil, NewError(CodeInvalidParam, rErr))
+		}
+		return
+	}
+
+	//调用方有返回值，但被调用函数没有返回参数
+	if v.outParamValue.IsValid() == false && request.requestHandle != nil && v.hasResponder == false && v.hasStream == false {
+		rErr := "Call Rpc " + request.RpcRequestData.GetServiceMethod() + " without return parameter!"
+		log.Error("call serviceMethod without return parameter", log.String("serviceMethod", request.RpcRequestData.GetServiceMethod()))
+		request.requestHandle(nil, NewError(CodeInvalidParam, rErr))
+		return
+	}
+
+	//超过限流的请求不执行
+	if err := handler.checkRateLimit(request.RpcRequestData.GetServiceMethod(), request.RpcRequestData.GetCallerNodeId()); err != nil {
+		if request.requestHandle != nil {
+			request.requestHandle(nil, err)
+		}
+		return
+	}
+
+	//流式调用的截止时间只用于排队，开始发送后由窗口等待时间控制
+	if v.hasStream == true {
+		request.deadline = time.Time{}
+	}
+
+	if len(handler.serverInterceptors) > 0 {
+		methodInfo := v //避免v逃逸到堆上
+		handler.interceptRpcRequest(request, &methodInfo)
+		return
+	}
+
+	if v.invoker != nil {
+		var ctx context.Context
+		if v.hasContext == true {
+			ctx = request.getContext()
+		}
+
+		requestHandle := request.requestHandle
+		reply, err := v.invoke(handler.GetRpcHandler(), ctx, request.inParam, request.localReply)
+		if requestHandle != nil {
+			requestHandle(reply, err)
+		}
+		return
+	}
+
+	if v.hasStream == true {
+		stream := request.stream
+		err := handler.callStreamMethod(&v, request.getContext(), stream, request.inParam)
+		if err != nil {
+			stream.Close(err)
 		}
 		return
 	}
@@ -285,6 +425,9 @@ func (handler *RpcHandler) HandlerRpcRequest(request *RpcRequest) {
 	var err error
 	//生成Call参数
 	paramList = append(paramList, reflect.ValueOf(handler.GetRpcHandler())) //接受者
+	if v.hasContext == true {
+		paramList = append(paramList, reflect.ValueOf(request.getContext()))
+	}
 	if v.hasResponder == true {
 		if request.requestHandle != nil {
 			responder := reflect.ValueOf(request.requestHandle)
@@ -303,11 +446,6 @@ func (handler *RpcHandler) HandlerRpcRequest(request *RpcRequest) {
 			oParam = reflect.New(v.outParamValue.Type().Elem())
 		}
 		paramList = append(paramList, oParam) //输出参数
-	} else if request.requestHandle != nil && v.hasResponder == false { //调用方有返回值，但被调用函数没有返回参数
-		rErr := "Call Rpc " + request.RpcRequestData.GetServiceMethod() + " without return parameter!"
-		log.Error("call serviceMethod without return parameter", log.String("serviceMethod", request.RpcRequestData.GetServiceMethod()))
-		request.requestHandle(nil, RpcError(rErr))
-		return
 	}
 
 	requestHandle := request.requestHandle
@@ -320,37 +458,55 @@ func (handler *RpcHandler) HandlerRpcRequest(request *RpcRequest) {
 	}
 
 	if v.hasResponder == false && requestHandle != nil {
-		requestHandle(oParam.Interface(), ConvertError(err))
+		requestHandle(oParam.Interface(), err)
 	}
 }
 
-func (handler *RpcHandler) CallMethod(client *Client, ServiceMethod string, param interface{}, callBack reflect.Value, reply interface{}) error {
+// CallMethod 服务调用自身的RPC，直接执行RPC函数，不经过服务端拦截器、超时与限流检查
+func (handler *RpcHandler) CallMethod(ctx context.Context, client *Client, ServiceMethod string, param interface{}, callBack reflect.Value, reply interface{}) error {
 	var err error
 	v, ok := handler.mapFunctions[ServiceMethod]
 	if ok == false {
-		err = errors.New("RpcHandler " + handler.rpcHandler.GetName() + " cannot find" + ServiceMethod)
+		err = NewError(CodeMethodNotFound, "RpcHandler "+handler.rpcHandler.GetName()+" cannot find"+ServiceMethod)
 		log.Error("CallMethod cannot find serviceMethod", log.String("rpcHandlerName", handler.rpcHandler.GetName()), log.String("serviceMethod", ServiceMethod))
 		return err
 	}
 
+	if v.hasStream == true {
+		return NewError(CodeInvalidParam, "stream method "+ServiceMethod+" cannot be called by the same service")
+	}
+
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	//服务自我调用，调用方发出的Metadata即为RPC函数收到的Metadata
+	if md := FromOutgoingContext(ctx); v.hasContext == true && md != nil {
+		ctx = context.WithValue(ctx, incomingMetadataKey{}, md.Copy())
+	}
+
 	var paramList []reflect.Value
 	var returnValues []reflect.Value
 	var pCall *Call
 	var callSeq uint64
 	if v.hasResponder == true {
 		paramList = append(paramList, reflect.ValueOf(handler.GetRpcHandler())) //接受者
+		if v.hasContext == true {
+			paramList = append(paramList, reflect.ValueOf(ctx))
+		}
 		pCall = MakeCall()
 		pCall.callback = &callBack
 		pCall.Seq = client.generateSeq()
 		callSeq = pCall.Seq
 		pCall.TimeOut = DefaultRpcTimeout
 		pCall.ServiceMethod = ServiceMethod
+		pCall.nodeId = client.GetTargetNodeId()
 		client.AddPending(pCall)
 
 		//有返回值时
 		if reply != nil {
 			//如果是Call同步调用
-			hd := func(Returns interface{}, Err RpcError) {
+			hd := func(Returns interface{}, Err error) {
 				rpcCall := client.RemovePending(callSeq)
 				if rpcCall == nil {
 					log.Error("cannot find call seq", log.Uint64("seq", callSeq))
@@ -358,7 +514,7 @@ func (handler *RpcHandler) CallMethod(client *Client, ServiceMethod string, para
 				}
 
 				//解析数据
-				if len(Err) != 0 {
+				if Err = normalizeError(Err); Err != nil {
 					rpcCall.Err = Err
 				} else if Returns != nil {
 					_, processor := GetProcessorType(Returns)
@@ -388,8 +544,17 @@ func (handler *RpcHandler) CallMethod(client *Client, ServiceMethod string, para
 			err = errInter.(error)
 			callBack.Call([]reflect.Value{reflect.ValueOf(reply), reflect.ValueOf(err)})
 		}
+	} else if v.invoker != nil {
+		var oParam interface{}
+		oParam, err = v.invoke(handler.GetRpcHandler(), ctx, param, reply)
+		if callBack != requestHandlerNull {
+			invokeCallback(callBack, oParam, err)
+		}
 	} else {
 		paramList = append(paramList, reflect.ValueOf(handler.GetRpcHandler())) //接受者
+		if v.hasContext == true {
+			paramList = append(paramList, reflect.ValueOf(ctx))
+		}
 		paramList = append(paramList, reflect.ValueOf(param))
 
 		//被调用RPC函数有返回值时
@@ -435,9 +600,116 @@ func (handler *RpcHandler) CallMethod(client *Client, ServiceMethod string, para
 	return err
 }
 
-func (handler *RpcHandler) goRpc(processor IRpcProcessor, bCast bool, nodeId string, serviceMethod string, args interface{}) error {
+// selectRpcClient 找出调用serviceMethod的Client。未指定结点且服务部署在多个结点时，优先排除退休结点、未连接的结点、熔断中的结点与excludeNodes(重试时已失败的结点)，再按负载均衡策略选出一个
+func (handler *RpcHandler) selectRpcClient(nodeId string, serviceMethod string, excludeNodes ...string) (*Client, error) {
 	pClientList := make([]*Client, 0, maxClusterNode)
-	err, pClientList := handler.funcRpcClient(nodeId, serviceMethod, false, pClientList)
+	if nodeId != NodeIdNull {
+		err, pClientList := handler.funcRpcClient(nodeId, serviceMethod, false, pClientList)
+		if err != nil || len(pClientList) == 0 {
+			return nil, err
+		}
+
+		return pClientList[0], nil
+	}
+
+	err, pClientList := handler.funcRpcClient(nodeId, serviceMethod, true, pClientList)
+	if err != nil {
+		return nil, err
+	}
+
+	//只剩退休结点时，仍然允许调用
+	if len(pClientList) == 0 {
+		err, pClientList = handler.funcRpcClient(nodeId, serviceMethod, false, pClientList)
+		if err != nil || len(pClientList) == 0 {
+			return nil, err
+		}
+	}
+
+	pClientList = excludeRpcClient(pClientList, excludeNodes)
+	pClientList = filterRpcClient(pClientList, isClientConnected)
+	pClientList = filterRpcClient(pClientList, isBreakerAvailable)
+	if len(pClientList) == 1 {
+		return pClientList[0], nil
+	}
+
+	serviceName := serviceMethod[:strings.Index(serviceMethod, ".")]
+	pClient := GetServiceSelector(serviceName).Select(serviceName, pClientList)
+	if pClient == nil {
+		return nil, NewError(CodeNoNode, fmt.Sprintf("no node of service %s can be selected", serviceName))
+	}
+
+	return pClient, nil
+}
+
+// excludeRpcClient 排除已失败的结点，全部被排除时仍使用原列表
+func excludeRpcClient(pClientList []*Client, excludeNodes []string) []*Client {
+	if len(excludeNodes) == 0 || len(pClientList) <= 1 {
+		return pClientList
+	}
+
+	clientList := make([]*Client, 0, len(pClientList))
+	for _, pClient := range pClientList {
+		if slices.Contains(excludeNodes, pClient.GetTargetNodeId()) == false {
+			clientList = append(clientList, pClient)
+		}
+	}
+
+	if len(clientList) == 0 {
+		return pClientList
+	}
+
+	return clientList
+}
+
+func isClientConnected(pClient *Client) bool {
+	return pClient.IsConnected()
+}
+
+func isBreakerAvailable(pClient *Client) bool {
+	return pClient.breaker.available()
+}
+
+// filterRpcClient 排除不可用(如未连接、熔断中)的结点，全部不可用时仍使用原列表，调用将快速失败
+func filterRpcClient(pClientList []*Client, available func(pClient *Client) bool) []*Client {
+	if len(pClientList) <= 1 {
+		return pClientList
+	}
+
+	for i, pClient := range pClientList {
+		if available(pClient) == true {
+			continue
+		}
+
+		clientList := make([]*Client, i, len(pClientList))
+		copy(clientList, pClientList[:i])
+		for _, c := range pClientList[i+1:] {
+			if available(c) == true {
+				clientList = append(clientList, c)
+			}
+		}
+
+		if len(clientList) == 0 {
+			return pClientList
+		}
+		return clientList
+	}
+
+	return pClientList
+}
+
+func (handler *RpcHandler) goRpc(ctx context.Context, processor IRpcProcessor, bCast bool, nodeId string, serviceMethod string, args interface{}) error {
+	var err error
+	pClientList := make([]*Client, 0, maxClusterNode)
+	if bCast == true {
+		err, pClientList = handler.funcRpcClient(nodeId, serviceMethod, false, pClientList)
+	} else {
+		var pClient *Client
+		pClient, err = handler.selectRpcClient(nodeId, serviceMethod)
+		if pClient != nil {
+			pClientList = append(pClientList, pClient)
+		}
+	}
+
 	if len(pClientList) == 0 {
 		if err != nil {
 			log.Error("call serviceMethod is failed", log.String("serviceMethod", serviceMethod), log.ErrorField("error", err))
@@ -447,77 +719,114 @@ func (handler *RpcHandler) goRpc(processor IRpcProcessor, bCast bool, nodeId str
 		return err
 	}
 
-	if len(pClientList) > 1 && bCast == false {
-		log.Error("cannot call serviceMethod more then 1 node", log.String("serviceMethod", serviceMethod))
-		return errors.New("cannot call more then 1 node")
-	}
-
 	//2.rpcClient调用
 	for i := 0; i < len(pClientList); i++ {
-		pCall := pClientList[i].Go(pClientList[i].GetTargetNodeId(), DefaultRpcTimeout, handler.rpcHandler, true, serviceMethod, args, nil)
-		if pCall.Err != nil {
-			err = pCall.Err
+		pClient := pClientList[i]
+		invoker := func(ctx context.Context, info *CallInfo, args interface{}, reply interface{}) error {
+			pCall := pClient.Go(ctx, pClient.GetTargetNodeId(), DefaultRpcTimeout, handler.rpcHandler, true, serviceMethod, args, nil)
+			callErr := pCall.Err
+			pClient.RemovePending(pCall.Seq)
+			ReleaseCall(pCall)
+			return callErr
+		}
+
+		var callErr error
+		if len(handler.clientInterceptors) == 0 {
+			callErr = invoker(ctx, nil, args, nil)
+		} else {
+			info := CallInfo{NodeId: pClient.GetTargetNodeId(), ServiceMethod: serviceMethod, NoReply: true}
+			callErr = chainClientInterceptors(handler.clientInterceptors, invoker)(ctx, &info, args, nil)
+		}
+
+		if callErr != nil {
+			err = callErr
 		}
-		pClientList[i].RemovePending(pCall.Seq)
-		ReleaseCall(pCall)
 	}
 
 	return err
 }
 
-func (handler *RpcHandler) callRpc(timeout time.Duration, nodeId string, serviceMethod string, args interface{}, reply interface{}) error {
-	pClientList := make([]*Client, 0, maxClusterNode)
-	err, pClientList := handler.funcRpcClient(nodeId, serviceMethod, false, pClientList)
+func (handler *RpcHandler) callRpc(ctx context.Context, timeout time.Duration, nodeId string, serviceMethod string, args interface{}, reply interface{}) error {
+	if policy := GetRetryPolicy(serviceMethod); policy != nil {
+		err := handler.callRpcWithRetry(ctx, policy, timeout, nodeId, serviceMethod, args, reply)
+		if err != nil {
+			log.Error("Call serviceMethod is failed", log.String("serviceMethod", serviceMethod), log.ErrorField("error", err))
+		}
+		return err
+	}
+
+	pClient, err := handler.selectRpcClient(nodeId, serviceMethod)
 	if err != nil {
 		log.Error("Call serviceMethod is failed", log.ErrorField("error", err))
 		return err
-	} else if len(pClientList) <= 0 {
-		err = errors.New("Call serviceMethod is error:cannot find " + serviceMethod)
+	} else if pClient == nil {
+		err = NewError(CodeNoNode, "Call serviceMethod is error:cannot find "+serviceMethod)
 		log.Error("cannot find serviceMethod", log.String("serviceMethod", serviceMethod))
 		return err
-	} else if len(pClientList) > 1 {
-		log.Error("Cannot call more then 1 node!", log.String("serviceMethod", serviceMethod))
-		return errors.New("cannot call more then 1 node")
 	}
 
-	pClient := pClientList[0]
-	pCall := pClient.Go(pClient.GetTargetNodeId(), timeout, handler.rpcHandler, false, serviceMethod, args, reply)
+	return handler.callClient(ctx, timeout, pClient, serviceMethod, args, reply)
+}
 
-	err = pCall.Done().Err
-	pClient.RemovePending(pCall.Seq)
-	ReleaseCall(pCall)
-	return err
+// callClient 经过客户端拦截器向pClient发起同步调用
+func (handler *RpcHandler) callClient(ctx context.Context, timeout time.Duration, pClient *Client, serviceMethod string, args interface{}, reply interface{}) error {
+	invoker := func(ctx context.Context, info *CallInfo, args interface{}, reply interface{}) error {
+		pCall := pClient.Go(ctx, pClient.GetTargetNodeId(), timeout, handler.rpcHandler, false, serviceMethod, args, reply)
+		callErr := pCall.Done().Err
+		pClient.RemovePending(pCall.Seq)
+		ReleaseCall(pCall)
+		return callErr
+	}
+
+	if len(handler.clientInterceptors) == 0 {
+		return invoker(ctx, nil, args, reply)
+	}
+
+	info := CallInfo{NodeId: pClient.GetTargetNodeId(), ServiceMethod: serviceMethod}
+	return chainClientInterceptors(handler.clientInterceptors, invoker)(ctx, &info, args, reply)
 }
 
-func (handler *RpcHandler) asyncCallRpc(timeout time.Duration, nodeId string, serviceMethod string, args interface{}, callback interface{}) (CancelRpc, error) {
+func (handler *RpcHandler) getCallbackValue(serviceMethod string, callback interface{}) (reflect.Value, error) {
 	fVal := reflect.ValueOf(callback)
 	if fVal.Kind() != reflect.Func {
 		err := errors.New("call " + serviceMethod + " input callback param is error!")
 		log.Error("input callback param is error", log.String("serviceMethod", serviceMethod))
-		return emptyCancelRpc, err
+		return fVal, err
 	}
 
 	if fVal.Type().NumIn() != 2 {
 		err := errors.New("call " + serviceMethod + " callback param function is error!")
 		log.Error("callback param function is error", log.String("serviceMethod", serviceMethod))
-		return emptyCancelRpc, err
+		return fVal, err
 	}
 
 	if fVal.Type().In(0).Kind() != reflect.Ptr || fVal.Type().In(1).String() != "error" {
 		err := errors.New("call " + serviceMethod + " callback param function is error!")
 		log.Error("callback param function is error", log.String("serviceMethod", serviceMethod))
+		return fVal, err
+	}
+
+	return fVal, nil
+}
+
+func (handler *RpcHandler) asyncCallRpc(ctx context.Context, timeout time.Duration, nodeId string, serviceMethod string, args interface{}, callback interface{}) (CancelRpc, error) {
+	fVal, err := handler.getCallbackValue(serviceMethod, callback)
+	if err != nil {
 		return emptyCancelRpc, err
 	}
 
-	reply := reflect.New(fVal.Type().In(0).Elem()).Interface()
-	pClientList := make([]*Client, 0, 1)
-	err, pClientList := handler.funcRpcClient(nodeId, serviceMethod, false, pClientList[:])
-	if len(pClientList) == 0 || err != nil {
+	if policy := GetRetryPolicy(serviceMethod); policy != nil {
+		return handler.asyncCallRpcWithRetry(ctx, policy, timeout, nodeId, serviceMethod, args, fVal)
+	}
+
+	reply := newReplyParam(fVal)
+	pClient, err := handler.selectRpcClient(nodeId, serviceMethod)
+	if pClient == nil || err != nil {
 		if err == nil {
 			if nodeId != NodeIdNull {
-				err = fmt.Errorf("cannot find %s from nodeId %s", serviceMethod, nodeId)
+				err = NewError(CodeNoNode, fmt.Sprintf("cannot find %s from nodeId %s", serviceMethod, nodeId))
 			} else {
-				err = fmt.Errorf("no %s service found in the origin network", serviceMethod)
+				err = NewError(CodeNoNode, fmt.Sprintf("no %s service found in the origin network", serviceMethod))
 			}
 		}
 		fVal.Call([]reflect.Value{reflect.ValueOf(reply), reflect.ValueOf(err)})
@@ -525,15 +834,70 @@ func (handler *RpcHandler) asyncCallRpc(timeout time.Duration, nodeId string, se
 		return emptyCancelRpc, nil
 	}
 
-	if len(pClientList) > 1 {
-		err := errors.New("cannot call more then 1 node")
+	//2.rpcClient调用
+	return handler.asyncCallClient(ctx, timeout, pClient, serviceMethod, fVal, args, reply, 0)
+}
+
+// asyncCallClient 经过客户端拦截器向pClient发起异步调用，被拦截时通过回调返回错误。window大于0时为流式调用
+func (handler *RpcHandler) asyncCallClient(ctx context.Context, timeout time.Duration, pClient *Client, serviceMethod string, fVal reflect.Value, args interface{}, reply interface{}, window uint32) (CancelRpc, error) {
+	call := func(ctx context.Context, callback reflect.Value, args interface{}) (CancelRpc, error) {
+		if window > 0 {
+			return pClient.StreamCall(ctx, pClient.GetTargetNodeId(), timeout, handler.rpcHandler, serviceMethod, callback, args, reply, window)
+		}
+		return pClient.AsyncCall(ctx, pClient.GetTargetNodeId(), timeout, handler.rpcHandler, serviceMethod, callback, args, reply)
+	}
+
+	if len(handler.clientInterceptors) == 0 {
+		return call(ctx, fVal, args)
+	}
+
+	cancelRpc := emptyCancelRpc
+	invoked := false
+	invoker := func(ctx context.Context, info *CallInfo, args interface{}, reply interface{}) error {
+		invoked = true
+		callback := fVal
+		if len(info.asyncDone) > 0 {
+			callback = wrapAsyncCallback(fVal, info.asyncDone, info.Stream)
+		}
+
+		var callErr error
+		cancelRpc, callErr = call(ctx, callback, args)
+		return callErr
+	}
+
+	info := CallInfo{NodeId: pClient.GetTargetNodeId(), ServiceMethod: serviceMethod, Async: true, Stream: window > 0}
+	err := chainClientInterceptors(handler.clientInterceptors, invoker)(ctx, &info, args, reply)
+	//被拦截器拦截时，通过回调返回错误
+	if err != nil && invoked == false {
 		fVal.Call([]reflect.Value{reflect.ValueOf(reply), reflect.ValueOf(err)})
-		log.Error("cannot call more then 1 node", log.String("serviceMethod", serviceMethod))
 		return emptyCancelRpc, nil
 	}
 
-	//2.rpcClient调用
-	return pClientList[0].AsyncCall(pClientList[0].GetTargetNodeId(), timeout, handler.rpcHandler, serviceMethod, fVal, args, reply, )
+	return cancelRpc, err
+}
+
+// getNodeIdByKey 通过一致性哈希找出Key所属的结点
+func (handler *RpcHandler) getNodeIdByKey(key string, serviceMethod string) (string, error) {
+	findIndex := strings.Index(serviceMethod, ".")
+	if findIndex == -1 {
+		return NodeIdNull, fmt.Errorf("servicemethod param  %s is error!", serviceMethod)
+	}
+
+	nodeIdByKey := handler.funcNodeIdByKey
+	if nodeIdByKey == nil {
+		nodeIdByKey = funcNodeIdByKey
+	}
+	if nodeIdByKey == nil {
+		return NodeIdNull, errors.New("hash ring is not setup")
+	}
+
+	nodeId, err := nodeIdByKey(serviceMethod[:findIndex], key)
+	if err != nil {
+		log.Error("cannot find node by key", log.String("serviceMethod", serviceMethod), log.String("key", key), log.ErrorField("error", err))
+		return NodeIdNull, err
+	}
+
+	return nodeId, nil
 }
 
 func (handler *RpcHandler) GetName() string {
@@ -541,50 +905,119 @@ func (handler *RpcHandler) GetName() string {
 }
 
 func (handler *RpcHandler) CallWithTimeout(timeout time.Duration, serviceMethod string, args interface{}, reply interface{}) error {
-	return handler.callRpc(timeout, NodeIdNull, serviceMethod, args, reply)
+	return handler.callRpc(context.Background(), timeout, NodeIdNull, serviceMethod, args, reply)
 }
 
 func (handler *RpcHandler) CallNodeWithTimeout(timeout time.Duration, nodeId string, serviceMethod string, args interface{}, reply interface{}) error {
-	return handler.callRpc(timeout, nodeId, serviceMethod, args, reply)
+	return handler.callRpc(context.Background(), timeout, nodeId, serviceMethod, args, reply)
 }
 
 func (handler *RpcHandler) AsyncCallWithTimeout(timeout time.Duration, serviceMethod string, args interface{}, callback interface{}) (CancelRpc, error) {
-	return handler.asyncCallRpc(timeout, NodeIdNull, serviceMethod, args, callback)
+	return handler.asyncCallRpc(context.Background(), timeout, NodeIdNull, serviceMethod, args, callback)
 }
 
 func (handler *RpcHandler) AsyncCallNodeWithTimeout(timeout time.Duration, nodeId string, serviceMethod string, args interface{}, callback interface{}) (CancelRpc, error) {
-	return handler.asyncCallRpc(timeout, nodeId, serviceMethod, args, callback)
+	return handler.asyncCallRpc(context.Background(), timeout, nodeId, serviceMethod, args, callback)
+}
+
+// CallContext 同步调用，超时时间取自ctx的截止时间(无截止时间时为默认超时)，并传递给被调用方。ctx取消或超时时调用立即返回CodeCanceled或CodeTimeout错误，同时通知被调用方取消
+func (handler *RpcHandler) CallContext(ctx context.Context, serviceMethod string, args interface{}, reply interface{}) error {
+	return handler.CallNodeContext(ctx, NodeIdNull, serviceMethod, args, reply)
+}
+
+func (handler *RpcHandler) CallNodeContext(ctx context.Context, nodeId string, serviceMethod string, args interface{}, reply interface{}) error {
+	timeout, err := getContextTimeout(ctx)
+	if err != nil {
+		return err
+	}
+
+	return handler.callRpc(ctx, timeout, nodeId, serviceMethod, args, reply)
+}
+
+// AsyncCallContext 异步调用，ctx取消或超时时回调返回CodeCanceled或CodeTimeout错误，同时通知被调用方取消
+func (handler *RpcHandler) AsyncCallContext(ctx context.Context, serviceMethod string, args interface{}, callback interface{}) (CancelRpc, error) {
+	return handler.AsyncCallNodeContext(ctx, NodeIdNull, serviceMethod, args, callback)
+}
+
+func (handler *RpcHandler) AsyncCallNodeContext(ctx context.Context, nodeId string, serviceMethod string, args interface{}, callback interface{}) (CancelRpc, error) {
+	timeout, err := getContextTimeout(ctx)
+	if err != nil {
+		fVal, fErr := handler.getCallbackValue(serviceMethod, callback)
+		if fErr != nil {
+			return emptyCancelRpc, fErr
+		}
+
+		fVal.Call([]reflect.Value{reflect.New(fVal.Type().In(0).Elem()), reflect.ValueOf(err)})
+		return emptyCancelRpc, nil
+	}
+
+	return handler.asyncCallRpc(ctx, timeout, nodeId, serviceMethod, args, callback)
 }
 
 func (handler *RpcHandler) AsyncCall(serviceMethod string, args interface{}, callback interface{}) error {
-	_, err := handler.asyncCallRpc(DefaultRpcTimeout, NodeIdNull, serviceMethod, args, callback)
+	_, err := handler.asyncCallRpc(context.Background(), DefaultRpcTimeout, NodeIdNull, serviceMethod, args, callback)
 	return err
 }
 
 func (handler *RpcHandler) Call(serviceMethod string, args interface{}, reply interface{}) error {
-	return handler.callRpc(DefaultRpcTimeout, NodeIdNull, serviceMethod, args, reply)
+	return handler.callRpc(context.Background(), DefaultRpcTimeout, NodeIdNull, serviceMethod, args, reply)
 }
 
 func (handler *RpcHandler) Go(serviceMethod string, args interface{}) error {
-	return handler.goRpc(nil, false, NodeIdNull, serviceMethod, args)
+	return handler.goRpc(context.Background(), nil, false, NodeIdNull, serviceMethod, args)
 }
 
 func (handler *RpcHandler) AsyncCallNode(nodeId string, serviceMethod string, args interface{}, callback interface{}) error {
-	_, err := handler.asyncCallRpc(DefaultRpcTimeout, nodeId, serviceMethod, args, callback)
+	_, err := handler.asyncCallRpc(context.Background(), DefaultRpcTimeout, nodeId, serviceMethod, args, callback)
 
 	return err
 }
 
 func (handler *RpcHandler) CallNode(nodeId string, serviceMethod string, args interface{}, reply interface{}) error {
-	return handler.callRpc(DefaultRpcTimeout, nodeId, serviceMethod, args, reply)
+	return handler.callRpc(context.Background(), DefaultRpcTimeout, nodeId, serviceMethod, args, reply)
 }
 
 func (handler *RpcHandler) GoNode(nodeId string, serviceMethod string, args interface{}) error {
-	return handler.goRpc(nil, false, nodeId, serviceMethod, args)
+	return handler.goRpc(context.Background(), nil, false, nodeId, serviceMethod, args)
 }
 
 func (handler *RpcHandler) CastGo(serviceMethod string, args interface{}) error {
-	return handler.goRpc(nil, true, NodeIdNull, serviceMethod, args)
+	return handler.goRpc(context.Background(), nil, true, NodeIdNull, serviceMethod, args)
+}
+
+// CallByKey 按Key通过一致性哈希路由到服务的固定结点进行调用，相同的Key总是调用到同一个结点
+func (handler *RpcHandler) CallByKey(key string, serviceMethod string, args interface{}, reply interface{}) error {
+	nodeId, err := handler.getNodeIdByKey(key, serviceMethod)
+	if err != nil {
+		return err
+	}
+
+	return handler.callRpc(WithLinkKey(context.Background(), key), DefaultRpcTimeout, nodeId, serviceMethod, args, reply)
+}
+
+func (handler *RpcHandler) AsyncCallByKey(key string, serviceMethod string, args interface{}, callback interface{}) error {
+	fVal, err := handler.getCallbackValue(serviceMethod, callback)
+	if err != nil {
+		return err
+	}
+
+	nodeId, err := handler.getNodeIdByKey(key, serviceMethod)
+	if err != nil {
+		fVal.Call([]reflect.Value{reflect.New(fVal.Type().In(0).Elem()), reflect.ValueOf(err)})
+		return nil
+	}
+
+	_, err = handler.asyncCallRpc(WithLinkKey(context.Background(), key), DefaultRpcTimeout, nodeId, serviceMethod, args, callback)
+	return err
+}
+
+func (handler *RpcHandler) GoByKey(key string, serviceMethod string, args interface{}) error {
+	nodeId, err := handler.getNodeIdByKey(key, serviceMethod)
+	if err != nil {
+		return err
+	}
+
+	return handler.goRpc(WithLinkKey(context.Background(), key), nil, false, nodeId, serviceMethod, args)
 }
 
 func (handler *RpcHandler) RawGoNode(rpcProcessorType RpcProcessorType, nodeId string, rpcMethodId uint32, serviceName string, rawArgs []byte) error {
@@ -628,11 +1061,11 @@ func (handler *RpcHandler) UnmarshalInParam(rpcProcessor IRpcProcessor, serviceM
 
 	v, ok := handler.mapFunctions[serviceMethod]
 	if ok == false {
-		return nil, errors.New("RpcHandler " + handler.rpcHandler.GetName() + " cannot find " + serviceMethod)
+		return nil, NewError(CodeMethodNotFound, "RpcHandler "+handler.rpcHandler.GetName()+" cannot find "+serviceMethod)
 	}
 
 	var err error
-	param := reflect.New(v.inParamValue.Type().Elem()).Interface()
+	param := v.newInParam()
 	err = rpcProcessor.Unmarshal(inParam, param)
 	return param, err
 }
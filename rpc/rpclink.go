@@ -0,0 +1,336 @@
+package rpc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/duanhf2012/origin/v2/log"
+	"github.com/duanhf2012/origin/v2/network"
+)
+
+// 与目标结点的连接数，未单独设置的结点使用defaultConnNum
+var connNumLocker sync.RWMutex
+var defaultConnNum = DefaultRpcConnNum
+var mapNodeConnNum = map[string]int{}
+
+// SetDefaultConnNum 设置与每个结点的连接数，只对之后新建的Client生效
+func SetDefaultConnNum(connNum int) {
+	connNumLocker.Lock()
+	defaultConnNum = max(connNum, 1)
+	connNumLocker.Unlock()
+}
+
+// SetNodeConnNum 设置与指定结点的连接数，connNum小于等于0时删除
+func SetNodeConnNum(nodeId string, connNum int) {
+	connNumLocker.Lock()
+	defer connNumLocker.Unlock()
+
+	if connNum <= 0 {
+		delete(mapNodeConnNum, nodeId)
+		return
+	}
+	mapNodeConnNum[nodeId] = connNum
+}
+
+func getConnNum(nodeId string) int {
+	connNumLocker.RLock()
+	defer connNumLocker.RUnlock()
+
+	if connNum, ok := mapNodeConnNum[nodeId]; ok == true {
+		return connNum
+	}
+
+	return defaultConnNum
+}
+
+type linkKeyCtxKey struct{}
+
+// WithLinkKey 多连接时相同Key的调用使用同一条连接，保证到达被调用方的顺序
+func WithLinkKey(ctx context.Context, key string) context.Context {
+	h := fnv.New64a()
+	h.Write([]byte(key))
+	return context.WithValue(ctx, linkKeyCtxKey{}, h.Sum64())
+}
+
+func linkKeyFromContext(ctx context.Context) (uint64, bool) {
+	if ctx == nil {
+		return 0, false
+	}
+
+	key, ok := ctx.Value(linkKeyCtxKey{}).(uint64)
+	return key, ok
+}
+
+// LinkStats 与目标结点一条连接的状态
+type LinkStats struct {
+	ConnId       int
+	Ready        bool //握手完成，可以发送请求
+	LocalAddr    string
+	ConnectTime  time.Time
+	LastRecvTime time.Time //最后一次收到数据的时间
+	WriteFailNum uint32    //写失败次数
+}
+
+// rpcLink 与目标结点的一条连接，调用记录所用的连接，取消帧与流式窗口沿同一连接发送
+type rpcLink struct {
+	rc          *RClient
+	index       int //在RClient.links中的位置
+	connId      int //每次连接唯一，重连后变化
+	conn        *network.NetConn
+	ready       atomic.Bool
+	connectTime time.Time
+
+	lastRecvTime atomic.Int64
+	writeFailNum atomic.Uint32
+}
+
+// linkWriter 有多条连接的IWriter
+type linkWriter interface {
+	selectLink(key uint64) int
+	writeLinkMsg(connId int, args ...[]byte) error
+}
+
+// selectLink 为调用选择连接，ctx带有LinkKey时按Key选择，否则按调用序号，返回0表示不指定
+func selectLink(w IWriter, ctx context.Context, seq uint64) int {
+	lw, ok := w.(linkWriter)
+	if ok == false {
+		return 0
+	}
+
+	key, ok := linkKeyFromContext(ctx)
+	if ok == false {
+		key = seq
+	}
+
+	return lw.selectLink(key)
+}
+
+// writeLinkMsg 通过调用所用的连接发送
+func writeLinkMsg(w IWriter, nodeId string, connId int, args ...[]byte) error {
+	if lw, ok := w.(linkWriter); ok == true && connId > 0 {
+		return lw.writeLinkMsg(connId, args...)
+	}
+
+	return w.WriteMsg(nodeId, args...)
+}
+
+// newLink 新连接放入空闲的位置，TCPClient的连接数与位置数相同，一定有空闲位置
+func (rc *RClient) newLink(conn *network.NetConn) network.Agent {
+	rc.linkLocker.Lock()
+	defer rc.linkLocker.Unlock()
+
+	rc.linkSeq++
+	link := &rpcLink{rc: rc, connId: rc.linkSeq, conn: conn, connectTime: time.Now()}
+	for i := range rc.links {
+		if rc.links[i] == nil {
+			link.index = i
+			rc.links[i] = link
+			return link
+		}
+	}
+
+	link.index = len(rc.links)
+	rc.links = append(rc.links, link)
+	return link
+}
+
+// selectLink 从key对应的位置开始找到第一条可用的连接
+func (rc *RClient) selectLink(key uint64) int {
+	rc.linkLocker.RLock()
+	defer rc.linkLocker.RUnlock()
+
+	linkNum := uint64(len(rc.links))
+	for i := uint64(0); i < linkNum; i++ {
+		link := rc.links[(key+i)%linkNum]
+		if link != nil && link.isReady() == true {
+			return link.connId
+		}
+	}
+
+	return 0
+}
+
+func (rc *RClient) getLink(connId int) *rpcLink {
+	rc.linkLocker.RLock()
+	defer rc.linkLocker.RUnlock()
+
+	for _, link := range rc.links {
+		if link != nil && link.connId == connId {
+			return link
+		}
+	}
+
+	return nil
+}
+
+func (rc *RClient) writeLinkMsg(connId int, args ...[]byte) error {
+	link := rc.getLink(connId)
+	if link == nil {
+		return NewError(CodeDisconnected, fmt.Sprintf("rpc connection %d is closed", connId))
+	}
+
+	return link.writeMsg(args...)
+}
+
+// GetLinkStats 返回每条连接的状态
+func (rc *RClient) GetLinkStats() []LinkStats {
+	rc.linkLocker.RLock()
+	defer rc.linkLocker.RUnlock()
+
+	linkStats := make([]LinkStats, 0, len(rc.links))
+	for _, link := range rc.links {
+		if link == nil {
+			continue
+		}
+
+		stats := LinkStats{
+			ConnId:       link.connId,
+			Ready:        link.isReady(),
+			LocalAddr:    link.conn.LocalAddr().String(),
+			ConnectTime:  link.connectTime,
+			WriteFailNum: link.writeFailNum.Load(),
+		}
+		if lastRecvTime := link.lastRecvTime.Load(); lastRecvTime > 0 {
+			stats.LastRecvTime = time.UnixMilli(lastRecvTime)
+		}
+		linkStats = append(linkStats, stats)
+	}
+
+	return linkStats
+}
+
+// setLinkReady 返回连接数是否在0与非0之间变化
+func (rc *RClient) setLinkReady(link *rpcLink, ready bool) bool {
+	rc.linkLocker.Lock()
+	defer rc.linkLocker.Unlock()
+
+	if link.ready.Load() == ready {
+		return false
+	}
+
+	link.ready.Store(ready)
+	if ready == true {
+		rc.readyNum++
+		return rc.readyNum == 1
+	}
+
+	rc.readyNum--
+	return rc.readyNum == 0
+}
+
+func (rc *RClient) removeLink(link *rpcLink) {
+	rc.linkLocker.Lock()
+	if rc.links[link.index] == link {
+		rc.links[link.index] = nil
+	}
+	rc.linkLocker.Unlock()
+}
+
+func (rc *RClient) notifyConnEvent(isConnect bool) {
+	var connEvent RpcConnEvent
+	connEvent.IsConnect = isConnect
+	connEvent.NodeId = rc.selfClient.GetTargetNodeId()
+	rc.notifyEventFun(&connEvent)
+}
+
+func (link *rpcLink) isReady() bool {
+	return link.ready.Load() == true && link.conn.IsConnected() == true
+}
+
+// writeMsg 写缓冲满时NetConn会关闭连接，由TCPClient重连
+func (link *rpcLink) writeMsg(args ...[]byte) error {
+	if hasFaultRule() == true {
+		client := link.rc.selfClient
+		return injectFault(client.getLocalNodeId(), client.GetTargetNodeId(), link.writeConnMsg, args...)
+	}
+
+	return link.writeConnMsg(args...)
+}
+
+func (link *rpcLink) writeConnMsg(args ...[]byte) error {
+	err := link.conn.WriteMsg(args...)
+	if err != nil {
+		link.writeFailNum.Add(1)
+	}
+
+	return err
+}
+
+func (link *rpcLink) Run() {
+	defer func() {
+		if r := recover(); r != nil {
+			log.StackError(fmt.Sprint(r))
+		}
+	}()
+
+	rc := link.rc
+	err := clientHandshake(link.conn, rc.selfClient.getLocalNodeId(), rc.selfClient.GetTargetNodeId())
+	if err != nil {
+		log.Error("RClient handshake is failed", log.String("nodeId", rc.selfClient.GetTargetNodeId()), log.String("addr", rc.Addr), log.ErrorField("error", err))
+		return
+	}
+
+	//第一条连接可用时通知结点连接
+	if rc.setLinkReady(link, true) == true {
+		rc.notifyConnEvent(true)
+	}
+
+	for {
+		bytes, err := link.conn.ReadMsg()
+		if err != nil {
+			log.Error("RClient read msg is failed", log.Int("connId", link.connId), log.ErrorField("error", err))
+			return
+		}
+		link.lastRecvTime.Store(time.Now().UnixMilli())
+
+		//对端在握手后拒绝了连接
+		if len(bytes) > 0 && bytes[0] == frameHandshake {
+			msg, pErr := parseHandshake(bytes)
+			if pErr == nil {
+				pErr = errors.New(msg.Error)
+			}
+			link.conn.ReleaseReadMsg(bytes)
+			log.Error("RClient is rejected by peer", log.String("nodeId", rc.selfClient.GetTargetNodeId()), log.ErrorField("error", pErr))
+			return
+		}
+
+		if hasFaultRule() == true {
+			err = injectFault(rc.selfClient.GetTargetNodeId(), rc.selfClient.getLocalNodeId(), rc.selfClient.deliverRpcResponse, bytes)
+		} else {
+			err = rc.selfClient.processRpcResponse(bytes)
+		}
+		link.conn.ReleaseReadMsg(bytes)
+		if err != nil {
+			return
+		}
+	}
+}
+
+func (link *rpcLink) OnClose() {
+	rc := link.rc
+	lastLink := rc.setLinkReady(link, false)
+	rc.removeLink(link)
+
+	//连接断开时该连接上等待中的调用不会再有返回，立即失败以便重试
+	rc.selfClient.cleanLinkPending(rc.selfClient.GetTargetNodeId(), link.connId)
+
+	//最后一条连接断开时通知结点断开，握手未完成的连接没有通知过连接
+	if lastLink == true {
+		rc.notifyConnEvent(false)
+	}
+}
+
+// GetLinkStats 返回与目标结点每条连接的状态，非跨结点连接的Client返回nil
+func (client *Client) GetLinkStats() []LinkStats {
+	rc, ok := client.IRealClient.(*RClient)
+	if ok == false {
+		return nil
+	}
+
+	return rc.GetLinkStats()
+}
@@ -0,0 +1,172 @@
+package rpc
+
+import (
+	"fmt"
+	"math/rand"
+	"sort"
+	"sync"
+	"sync/atomic"
+)
+
+// 负载均衡策略类型
+const (
+	SelectorRandom       = "Random"       //随机
+	SelectorRoundRobin   = "RoundRobin"   //轮询
+	SelectorWeighted     = "Weighted"     //按权重随机
+	SelectorLeastPending = "LeastPending" //最少等待中的调用
+)
+
+// ISelector 当一个服务部署在多个结点上时，从可用的Client中选出一个进行调用
+type ISelector interface {
+	Select(serviceName string, clientList []*Client) *Client
+}
+
+var selectorLocker sync.RWMutex
+var defaultSelector ISelector = &RandomSelector{}
+var mapServiceSelector = map[string]ISelector{}
+
+// SetDefaultSelector 设置未单独配置服务时使用的负载均衡策略
+func SetDefaultSelector(selector ISelector) {
+	selectorLocker.Lock()
+	defaultSelector = selector
+	selectorLocker.Unlock()
+}
+
+// SetServiceSelector 设置指定服务的负载均衡策略，selector为nil时使用默认策略
+func SetServiceSelector(serviceName string, selector ISelector) {
+	selectorLocker.Lock()
+	if selector == nil {
+		delete(mapServiceSelector, serviceName)
+	} else {
+		mapServiceSelector[serviceName] = selector
+	}
+	selectorLocker.Unlock()
+}
+
+func GetServiceSelector(serviceName string) ISelector {
+	selectorLocker.RLock()
+	defer selectorLocker.RUnlock()
+
+	selector, ok := mapServiceSelector[serviceName]
+	if ok == false {
+		return defaultSelector
+	}
+
+	return selector
+}
+
+// NewSelector 根据策略名创建负载均衡器，mapWeight只在Weighted策略下生效，map[NodeId]权重
+func NewSelector(selectorType string, mapWeight map[string]int) (ISelector, error) {
+	switch selectorType {
+	case "", SelectorRandom:
+		return &RandomSelector{}, nil
+	case SelectorRoundRobin:
+		return &RoundRobinSelector{}, nil
+	case SelectorWeighted:
+		return &WeightedSelector{MapWeight: mapWeight}, nil
+	case SelectorLeastPending:
+		return &LeastPendingSelector{}, nil
+	}
+
+	return nil, fmt.Errorf("selector type %s is not support", selectorType)
+}
+
+// RandomSelector 随机选择
+type RandomSelector struct {
+}
+
+func (rs *RandomSelector) Select(_ string, clientList []*Client) *Client {
+	if len(clientList) == 0 {
+		return nil
+	}
+
+	return clientList[rand.Intn(len(clientList))]
+}
+
+// RoundRobinSelector 轮询选择
+type RoundRobinSelector struct {
+	index uint32
+}
+
+func (rs *RoundRobinSelector) Select(_ string, clientList []*Client) *Client {
+	if len(clientList) == 0 {
+		return nil
+	}
+
+	//clientList来源于map遍历，顺序不固定，先按NodeId排序保证轮询顺序稳定
+	idx := atomic.AddUint32(&rs.index, 1)
+	sort.Slice(clientList, func(i, j int) bool {
+		return clientList[i].GetTargetNodeId() < clientList[j].GetTargetNodeId()
+	})
+	return clientList[int(idx%uint32(len(clientList)))]
+}
+
+// WeightedSelector 按结点权重随机选择，未配置权重的结点权重为1，权重小于等于0的结点不参与选择
+type WeightedSelector struct {
+	MapWeight map[string]int
+}
+
+func (ws *WeightedSelector) getWeight(nodeId string) int {
+	weight, ok := ws.MapWeight[nodeId]
+	if ok == false {
+		return 1
+	}
+
+	return weight
+}
+
+func (ws *WeightedSelector) Select(_ string, clientList []*Client) *Client {
+	totalWeight := 0
+	for _, client := range clientList {
+		if weight := ws.getWeight(client.GetTargetNodeId()); weight > 0 {
+			totalWeight += weight
+		}
+	}
+
+	if totalWeight <= 0 {
+		return nil
+	}
+
+	r := rand.Intn(totalWeight)
+	for _, client := range clientList {
+		weight := ws.getWeight(client.GetTargetNodeId())
+		if weight <= 0 {
+			continue
+		}
+
+		if r < weight {
+			return client
+		}
+		r -= weight
+	}
+
+	return nil
+}
+
+// LeastPendingSelector 选择等待返回的调用数最少的结点，数量相同时随机选择
+type LeastPendingSelector struct {
+}
+
+func (ls *LeastPendingSelector) Select(_ string, clientList []*Client) *Client {
+	var selectClient *Client
+	minPending := 0
+	sameNum := 0
+	for _, client := range clientList {
+		pendingNum := client.GetPendingNum()
+		if selectClient == nil || pendingNum < minPending {
+			selectClient = client
+			minPending = pendingNum
+			sameNum = 1
+			continue
+		}
+
+		if pendingNum == minPending {
+			sameNum++
+			if rand.Intn(sameNum) == 0 {
+				selectClient = client
+			}
+		}
+	}
+
+	return selectClient
+}
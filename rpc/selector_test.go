@@ -0,0 +1,130 @@
+package rpc
+
+import (
+	"testing"
+)
+
+// selectorRealClient 只实现IsConnected，用于测试结点选择
+type selectorRealClient struct {
+	IRealClient
+	connected bool
+}
+
+func (rc *selectorRealClient) IsConnected() bool {
+	return rc.connected
+}
+
+type selectorNode struct {
+	nodeId    string
+	pending   int
+	connected bool
+}
+
+func newSelectorClients(nodeList ...selectorNode) []*Client {
+	callSet := &CallSet{mapNodePending: map[string]int{}}
+	clientList := make([]*Client, 0, len(nodeList))
+	for _, node := range nodeList {
+		callSet.mapNodePending[node.nodeId] = node.pending
+		clientList = append(clientList, &Client{targetNodeId: node.nodeId, CallSet: callSet, IRealClient: &selectorRealClient{connected: node.connected}})
+	}
+
+	return clientList
+}
+
+func selectCount(selector ISelector, clientList []*Client, times int) map[string]int {
+	mapCount := map[string]int{}
+	for i := 0; i < times; i++ {
+		if pClient := selector.Select("SelectorService", clientList); pClient != nil {
+			mapCount[pClient.GetTargetNodeId()]++
+		} else {
+			mapCount[""]++
+		}
+	}
+
+	return mapCount
+}
+
+func TestSelector(t *testing.T) {
+	n1 := selectorNode{nodeId: "node_1", pending: 3, connected: true}
+	n2 := selectorNode{nodeId: "node_2", pending: 1, connected: true}
+	n3 := selectorNode{nodeId: "node_3", pending: 2, connected: true}
+
+	tests := []struct {
+		name     string
+		selector ISelector
+		nodeList []selectorNode
+		times    int
+		check    func(mapCount map[string]int) bool
+	}{
+		{"random", &RandomSelector{}, []selectorNode{n1, n2, n3}, 300, func(mapCount map[string]int) bool {
+			return mapCount["node_1"] > 0 && mapCount["node_2"] > 0 && mapCount["node_3"] > 0 && mapCount[""] == 0
+		}},
+		{"random empty", &RandomSelector{}, nil, 1, func(mapCount map[string]int) bool {
+			return mapCount[""] == 1
+		}},
+		{"round robin", &RoundRobinSelector{}, []selectorNode{n3, n1, n2}, 30, func(mapCount map[string]int) bool {
+			return mapCount["node_1"] == 10 && mapCount["node_2"] == 10 && mapCount["node_3"] == 10
+		}},
+		{"weighted", &WeightedSelector{MapWeight: map[string]int{"node_1": 3, "node_2": 0}}, []selectorNode{n1, n2, n3}, 400, func(mapCount map[string]int) bool {
+			return mapCount["node_2"] == 0 && mapCount["node_1"] > mapCount["node_3"] && mapCount["node_3"] > 0
+		}},
+		{"weighted all zero", &WeightedSelector{MapWeight: map[string]int{"node_1": 0, "node_2": -1}}, []selectorNode{n1, n2}, 10, func(mapCount map[string]int) bool {
+			return mapCount[""] == 10
+		}},
+		{"least pending", &LeastPendingSelector{}, []selectorNode{n1, n2, n3}, 10, func(mapCount map[string]int) bool {
+			return mapCount["node_2"] == 10
+		}},
+		{"least pending same", &LeastPendingSelector{}, []selectorNode{n2, {nodeId: "node_4", pending: 1, connected: true}}, 200, func(mapCount map[string]int) bool {
+			return mapCount["node_2"] > 0 && mapCount["node_4"] > 0
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mapCount := selectCount(tt.selector, newSelectorClients(tt.nodeList...), tt.times)
+			if tt.check(mapCount) == false {
+				t.Fatalf("select count = %v", mapCount)
+			}
+		})
+	}
+}
+
+func TestRoundRobinOrder(t *testing.T) {
+	selector := &RoundRobinSelector{}
+	clientList := newSelectorClients(selectorNode{nodeId: "node_2"}, selectorNode{nodeId: "node_3"}, selectorNode{nodeId: "node_1"})
+
+	var last string
+	for i := 0; i < 6; i++ {
+		nodeId := selector.Select("SelectorService", clientList).GetTargetNodeId()
+		if nodeId == last {
+			t.Fatalf("round robin selects %s twice", nodeId)
+		}
+		last = nodeId
+	}
+}
+
+func TestFilterConnectedClient(t *testing.T) {
+	tests := []struct {
+		name     string
+		nodeList []selectorNode
+		want     []string
+	}{
+		{"all connected", []selectorNode{{nodeId: "node_1", connected: true}, {nodeId: "node_2", connected: true}}, []string{"node_1", "node_2"}},
+		{"one reconnecting", []selectorNode{{nodeId: "node_1", connected: true}, {nodeId: "node_2"}, {nodeId: "node_3", connected: true}}, []string{"node_1", "node_3"}},
+		{"none connected", []selectorNode{{nodeId: "node_1"}, {nodeId: "node_2"}}, []string{"node_1", "node_2"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clientList := filterRpcClient(newSelectorClients(tt.nodeList...), isClientConnected)
+			if len(clientList) != len(tt.want) {
+				t.Fatalf("client count = %d, want %d", len(clientList), len(tt.want))
+			}
+			for i, pClient := range clientList {
+				if pClient.GetTargetNodeId() != tt.want[i] {
+					t.Fatalf("client %d = %s, want %s", i, pClient.GetTargetNodeId(), tt.want[i])
+				}
+			}
+		})
+	}
+}
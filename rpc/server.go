@@ -1,6 +1,7 @@
 package rpc
 
 import (
+	"context"
 	"fmt"
 	"github.com/duanhf2012/origin/v2/log"
 	"github.com/duanhf2012/origin/v2/network"
@@ -9,6 +10,7 @@ import (
 	"reflect"
 
 	"strings"
+	"sync/atomic"
 	"time"
 )
 
@@ -28,26 +30,38 @@ var LittleEndian bool
 type IServer interface {
 	Start() error
 	Stop()
-
-	selfNodeRpcHandlerGo(timeout time.Duration, processor IRpcProcessor, client *Client, noReply bool, handlerName string, rpcMethodId uint32, serviceMethod string, args interface{}, reply interface{}, rawArgs []byte) *Call
-	myselfRpcHandlerGo(client *Client, handlerName string, serviceMethod string, args interface{}, callBack reflect.Value, reply interface{}) error
-	selfNodeRpcHandlerAsyncGo(timeout time.Duration, client *Client, callerRpcHandler IRpcHandler, noReply bool, handlerName string, serviceMethod string, args interface{}, reply interface{}, callback reflect.Value) (CancelRpc, error)
+	GetLocalNodeId() string
+	SetRateLimit(serviceMethod string, limit *RateLimit) error
+
+	selfNodeRpcHandlerGo(ctx context.Context, timeout time.Duration, processor IRpcProcessor, client *Client, noReply bool, handlerName string, rpcMethodId uint32, serviceMethod string, args interface{}, reply interface{}, rawArgs []byte) *Call
+	myselfRpcHandlerGo(ctx context.Context, client *Client, handlerName string, serviceMethod string, args interface{}, callBack reflect.Value, reply interface{}) error
+	selfNodeRpcHandlerAsyncGo(ctx context.Context, timeout time.Duration, client *Client, callerRpcHandler IRpcHandler, noReply bool, handlerName string, serviceMethod string, args interface{}, reply interface{}, callback reflect.Value) (CancelRpc, error)
+	selfNodeRpcHandlerStream(ctx context.Context, timeout time.Duration, client *Client, callerRpcHandler IRpcHandler, handlerName string, serviceMethod string, args interface{}, reply interface{}, callback reflect.Value, window uint32) (CancelRpc, error)
+	getRateLimiter(serviceMethod string) *rateLimiter
 }
 
-type writeResponse func(processor IRpcProcessor, connTag string, serviceMethod string, seq uint64, reply interface{}, rpcError RpcError)
+// callerNodeId为调用方结点，开启结点认证时为认证的结点，用于选择返回的压缩策略
+type writeResponse func(processor IRpcProcessor, connTag string, callerNodeId string, serviceMethod string, seq uint64, reply interface{}, rpcError error, metadata map[string]string)
+type writeStreamMsg func(processor IRpcProcessor, connTag string, callerNodeId string, serviceMethod string, seq uint64, msg interface{}) error
 
 type Server struct {
 	BaseServer
 
-	functions map[interface{}]interface{}
-	rpcServer *network.TCPServer
+	functions  map[interface{}]interface{}
+	rpcServer  *network.TCPServer
+	unixServer *network.TCPServer //同一主机上的结点通过Unix domain socket连接
 
 	listenAddr     string
+	unixListenAddr string
 	maxRpcParamLen uint32
 }
 
+var unixConnSeq uint64
+
 type RpcAgent struct {
 	conn      network.Conn
+	connTag   string
+	nodeId    string //握手认证的调用方结点，未开启认证时为空
 	rpcServer *Server
 	userData  interface{}
 }
@@ -82,81 +96,124 @@ func (server *Server) Init(listenAddr string, maxRpcParamLen uint32, compressByt
 	server.rpcServer = &network.TCPServer{}
 }
 
+// SetUnixListenAddr 在ListenAddr之外再监听一个unix://地址，供同一主机上的结点连接，需在Start之前调用
+func (server *Server) SetUnixListenAddr(unixListenAddr string) {
+	server.unixListenAddr = unixListenAddr
+}
+
 func (server *Server) Start() error {
-	splitAddr := strings.Split(server.listenAddr, ":")
-	if len(splitAddr) != 2 {
-		return fmt.Errorf("listen addr is failed,listenAddr:%s", server.listenAddr)
+	if netType, _ := network.ParseAddr(server.listenAddr); netType == "unix" {
+		server.rpcServer.Addr = server.listenAddr
+	} else {
+		splitAddr := strings.Split(server.listenAddr, ":")
+		if len(splitAddr) != 2 {
+			return fmt.Errorf("listen addr is failed,listenAddr:%s", server.listenAddr)
+		}
+		server.rpcServer.Addr = ":" + splitAddr[1]
+	}
+
+	server.setupTCPServer(server.rpcServer)
+	err := server.rpcServer.Start()
+	if err != nil {
+		return err
+	}
+
+	if server.unixListenAddr == "" {
+		return nil
 	}
 
-	server.rpcServer.Addr = ":" + splitAddr[1]
-	server.rpcServer.MinMsgLen = 2
+	if netType, _ := network.ParseAddr(server.unixListenAddr); netType != "unix" {
+		server.rpcServer.Close()
+		return fmt.Errorf("unix listen addr is failed,unixListenAddr:%s", server.unixListenAddr)
+	}
+
+	server.unixServer = &network.TCPServer{Addr: server.unixListenAddr}
+	server.setupTCPServer(server.unixServer)
+	err = server.unixServer.Start()
+	if err != nil {
+		server.unixServer = nil
+		server.rpcServer.Close()
+	}
+
+	return err
+}
+
+func (server *Server) setupTCPServer(tcpServer *network.TCPServer) {
+	tcpServer.MinMsgLen = 2
 	if server.maxRpcParamLen > 0 {
-		server.rpcServer.MaxMsgLen = server.maxRpcParamLen
+		tcpServer.MaxMsgLen = server.maxRpcParamLen
 	} else {
-		server.rpcServer.MaxMsgLen = math.MaxUint32
+		tcpServer.MaxMsgLen = math.MaxUint32
 	}
 
-	server.rpcServer.MaxConnNum = 100000
-	server.rpcServer.PendingWriteNum = 2000000
-	server.rpcServer.NewAgent = server.NewAgent
-	server.rpcServer.LittleEndian = LittleEndian
-	server.rpcServer.WriteDeadline = Default_ReadWriteDeadline
-	server.rpcServer.ReadDeadline = Default_ReadWriteDeadline
-	server.rpcServer.LenMsgLen = DefaultRpcLenMsgLen
-
-	return server.rpcServer.Start()
+	tcpServer.MaxConnNum = 100000
+	tcpServer.PendingWriteNum = 2000000
+	tcpServer.NewAgent = server.NewAgent
+	tcpServer.LittleEndian = LittleEndian
+	tcpServer.WriteDeadline = Default_ReadWriteDeadline
+	tcpServer.ReadDeadline = Default_ReadWriteDeadline
+	tcpServer.LenMsgLen = DefaultRpcLenMsgLen
+	tcpServer.TLSConfig = getServerTLSConfig()
 }
 
 func (server *Server) Stop() {
 	server.rpcServer.Close()
+	if server.unixServer != nil {
+		server.unixServer.Close()
+	}
 }
 
 func (agent *RpcAgent) OnDestroy() {}
 
-func (agent *RpcAgent) WriteResponse(processor IRpcProcessor, connTag string, serviceMethod string, seq uint64, reply interface{}, rpcError RpcError) {
+func (agent *RpcAgent) WriteResponse(processor IRpcProcessor, connTag string, callerNodeId string, serviceMethod string, seq uint64, reply interface{}, rpcError error, metadata map[string]string) {
+	agent.writeResponse(processor, connTag, callerNodeId, serviceMethod, seq, reply, rpcError, metadata, false)
+}
+
+// WriteStreamMsg 发送流式调用的一条消息
+func (agent *RpcAgent) WriteStreamMsg(processor IRpcProcessor, connTag string, callerNodeId string, serviceMethod string, seq uint64, msg interface{}) error {
+	return agent.writeResponse(processor, connTag, callerNodeId, serviceMethod, seq, msg, nil, nil, true)
+}
+
+func (agent *RpcAgent) writeResponse(processor IRpcProcessor, connTag string, callerNodeId string, serviceMethod string, seq uint64, reply interface{}, rpcError error, metadata map[string]string, streamMsg bool) error {
 	var mReply []byte
 	var errM error
 
 	if reply != nil {
 		mReply, errM = processor.Marshal(reply)
+		if errM != nil && streamMsg == true {
+			return errM
+		}
 		if errM != nil {
-			rpcError = ConvertError(errM)
+			rpcError = errM
 		}
 	}
 
 	var rpcResponse RpcResponse
 	rpcResponse.RpcResponseData = processor.MakeRpcResponse(seq, rpcError, mReply)
+	rpcResponse.RpcResponseData.SetMetadata(metadata)
+	rpcResponse.RpcResponseData.SetStreamMsg(streamMsg)
 	bytes, errM := processor.Marshal(rpcResponse.RpcResponseData)
 	defer processor.ReleaseRpcResponse(rpcResponse.RpcResponseData)
 
 	if errM != nil {
 		log.Error("marshal RpcResponseData failed", log.String("serviceMethod", serviceMethod), log.ErrorField("error", errM))
-		return
+		return errM
 	}
 
-	var compressBuff []byte
-	bCompress := uint8(0)
-	if agent.rpcServer.compressBytesLen > 0 && len(bytes) >= agent.rpcServer.compressBytesLen {
-		var cErr error
-
-		compressBuff, cErr = compressor.CompressBlock(bytes)
-		if cErr != nil {
-			log.Error("CompressBlock failed", log.String("serviceMethod", serviceMethod), log.ErrorField("error", cErr))
-			return
-		}
-		if len(compressBuff) < len(bytes) {
-			bytes = compressBuff
-			bCompress = 1 << 7
-		}
+	captureFrame(CaptureServerResponse, processor.GetProcessorType(), seq, callerNodeId, connTag, serviceMethod, bytes)
+	head, bytes, release, cErr := compressFrame(uint8(processor.GetProcessorType()), getCompressPolicy(callerNodeId, serviceMethod, agent.rpcServer.compressBytesLen), bytes)
+	if cErr != nil {
+		log.Error("CompressBlock failed", log.String("serviceMethod", serviceMethod), log.ErrorField("error", cErr))
+		return cErr
 	}
 
-	errM = agent.conn.WriteMsg([]byte{uint8(processor.GetProcessorType()) | bCompress}, bytes)
-	if cap(compressBuff) > 0 {
-		compressor.CompressBufferCollection(compressBuff)
-	}
+	errM = agent.conn.WriteMsg(head, bytes)
+	release()
 	if errM != nil {
 		log.Error("WriteMsg error,Rpc return is fail", log.String("serviceMethod", serviceMethod), log.ErrorField("error", errM))
 	}
+
+	return errM
 }
 
 func (agent *RpcAgent) Run() {
@@ -166,6 +223,25 @@ func (agent *RpcAgent) Run() {
 		}
 	}()
 
+	nodeId, err := serverHandshake(agent.conn, agent.rpcServer.GetLocalNodeId())
+	if err != nil {
+		//will close conn
+		log.Error("rpc handshake is failed", log.String("remoteAddress", agent.conn.RemoteAddr().String()), log.ErrorField("error", err))
+		return
+	}
+	if nodeId != "" {
+		//TLS证书与认证的结点不符时拒绝连接
+		err = verifyPeerNode(agent.conn, nodeId)
+		if err != nil {
+			writeHandshake(agent.conn, &PBHandshake{Error: err.Error()})
+			log.Error("rpc node is rejected", log.String("nodeId", nodeId), log.String("remoteAddress", agent.conn.RemoteAddr().String()), log.ErrorField("error", err))
+			return
+		}
+
+		agent.nodeId = nodeId
+		log.Info("rpc node is authenticated", log.String("nodeId", nodeId), log.String("remoteAddress", agent.conn.RemoteAddr().String()))
+	}
+
 	for {
 		data, err := agent.conn.ReadMsg()
 		if err != nil {
@@ -174,7 +250,7 @@ func (agent *RpcAgent) Run() {
 			break
 		}
 
-		err = agent.rpcServer.processRpcRequest(data, "", agent.WriteResponse)
+		err = agent.rpcServer.processRpcRequest(data, agent.connTag, agent.nodeId, agent.WriteResponse, agent.WriteStreamMsg)
 		if err != nil {
 			//will close conn
 			agent.conn.ReleaseReadMsg(data)
@@ -211,5 +287,13 @@ func (agent *RpcAgent) Destroy() {
 func (server *Server) NewAgent(c network.Conn) network.Agent {
 	agent := &RpcAgent{conn: c, rpcServer: server}
 
+	//Unix domain socket连接的调用方地址为空，用序号区分不同连接
+	remoteAddr := c.RemoteAddr()
+	if remoteAddr == nil || remoteAddr.Network() == "unix" {
+		agent.connTag = fmt.Sprintf("unix#%d", atomic.AddUint64(&unixConnSeq, 1))
+	} else {
+		agent.connTag = remoteAddr.String()
+	}
+
 	return agent
 }
@@ -0,0 +1,372 @@
+package rpc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/duanhf2012/origin/v2/log"
+)
+
+// DefaultStreamWindow 流式调用默认的接收窗口，被调用方最多发出这么多条调用方还未处理的消息
+var DefaultStreamWindow uint32 = 64
+
+var serverStreamType = reflect.TypeOf((*ServerStream)(nil))
+
+var errStreamClosed = errors.New("stream is closed")
+
+// ServerStream 流式调用中被调用方的发送端，RPC函数格式为RPC_X(stream *rpc.ServerStream, req *Req) error，
+// 也可以在stream前加context.Context参数。通过Send按顺序发送消息，发送完毕后调用Close结束，
+// RPC函数返回error时自动以该错误结束。Send与Close可以在其他协程中调用
+type ServerStream struct {
+	locker   sync.Mutex
+	credits  uint32        //调用方剩余的接收窗口
+	notify   chan struct{} //窗口增加或取消时通知
+	closed   bool
+	canceled bool //调用方取消、超时或消费失败
+
+	request     *RpcRequest
+	ctx         context.Context
+	waitTimeout time.Duration //窗口为0时最长等待时间，超过后Send返回超时
+	writeMsg    func(msg interface{}) error
+}
+
+func newServerStream(request *RpcRequest, window uint32, waitTimeout time.Duration, writeMsg func(msg interface{}) error) *ServerStream {
+	if waitTimeout <= 0 {
+		waitTimeout = DefaultRpcTimeout
+	}
+
+	return &ServerStream{
+		credits:     window,
+		notify:      make(chan struct{}, 1),
+		request:     request,
+		waitTimeout: waitTimeout,
+		writeMsg:    writeMsg,
+	}
+}
+
+// Context 调用方取消或断开时Done，在其他协程中发送时可用于退出
+func (stream *ServerStream) Context() context.Context {
+	if stream.ctx == nil {
+		return context.Background()
+	}
+
+	return stream.ctx
+}
+
+// Send 发送一条消息，调用方的接收窗口用完时阻塞等待，调用方取消或等待超时时返回错误
+func (stream *ServerStream) Send(msg interface{}) error {
+	err := stream.waitCredit()
+	if err != nil {
+		return err
+	}
+
+	return stream.writeMsg(msg)
+}
+
+// Close 结束流，err为nil时调用方收到io.EOF，否则收到该错误。重复调用无效
+func (stream *ServerStream) Close(err error) {
+	stream.locker.Lock()
+	if stream.closed == true {
+		stream.locker.Unlock()
+		return
+	}
+	stream.closed = true
+	canceled := stream.canceled
+	stream.locker.Unlock()
+
+	//调用方已不再接收，只释放请求
+	if canceled == true {
+		ReleaseRpcRequest(stream.request)
+		return
+	}
+
+	stream.request.requestHandle(nil, err)
+}
+
+func (stream *ServerStream) waitCredit() error {
+	var done <-chan struct{}
+	if stream.ctx != nil {
+		done = stream.ctx.Done()
+	}
+
+	var timer *time.Timer
+	for {
+		stream.locker.Lock()
+		if stream.closed == true {
+			stream.locker.Unlock()
+			return errStreamClosed
+		}
+		if stream.canceled == true {
+			stream.locker.Unlock()
+			return NewError(CodeCanceled, "stream is canceled")
+		}
+		if stream.credits > 0 {
+			stream.credits--
+			stream.locker.Unlock()
+			return nil
+		}
+		stream.locker.Unlock()
+
+		if timer == nil {
+			timer = time.NewTimer(stream.waitTimeout)
+			defer timer.Stop()
+		}
+
+		select {
+		case <-stream.notify:
+		case <-done:
+			return contextError(stream.ctx.Err())
+		case <-timer.C:
+			return NewError(CodeTimeout, "wait stream window timeout")
+		}
+	}
+}
+
+func (stream *ServerStream) addCredit(credit uint32) {
+	stream.locker.Lock()
+	stream.credits += credit
+	stream.locker.Unlock()
+	stream.signal()
+}
+
+func (stream *ServerStream) cancel() {
+	stream.locker.Lock()
+	stream.canceled = true
+	stream.locker.Unlock()
+	stream.signal()
+}
+
+func (stream *ServerStream) signal() {
+	select {
+	case stream.notify <- struct{}{}:
+	default:
+	}
+}
+
+// clientStream 流式调用中调用方的接收状态，消息在调用方服务协程中处理后归还窗口
+type clientStream struct {
+	client   *Client
+	nodeId   string
+	seq      uint64
+	connId   int //流式消息与窗口沿发起调用的连接
+	window   uint32
+	consumed uint32 //已处理还未归还的消息数
+	canceled atomic.Bool
+
+	serverStream *ServerStream //本结点调用时直接增加被调用方的窗口
+}
+
+// onMsg 回调一条消息，已取消的流不再回调
+func (stream *clientStream) onMsg(callback reflect.Value, reply interface{}) {
+	if stream.canceled.Load() == true {
+		return
+	}
+
+	stream.consume()
+	invokeCallback(callback, reply, nil)
+}
+
+// consume 处理过半个窗口的消息后归还给被调用方
+func (stream *clientStream) consume() {
+	stream.consumed++
+	if stream.consumed < max(stream.window/2, 1) {
+		return
+	}
+
+	credit := stream.consumed
+	stream.consumed = 0
+	if stream.serverStream != nil {
+		stream.serverStream.addCredit(credit)
+		return
+	}
+
+	stream.client.SendStreamCredit(stream.nodeId, stream.seq, stream.connId, credit)
+}
+
+// cancel 调用方取消或超时，通知被调用方停止发送
+func (stream *clientStream) cancel() {
+	stream.canceled.Store(true)
+	if stream.serverStream != nil {
+		stream.serverStream.cancel()
+		return
+	}
+
+	stream.client.SendCancel(stream.nodeId, stream.seq, stream.connId)
+}
+
+// touchStream 收到流式消息时刷新调用的超时时间，超时时间表示两条消息之间的最长间隔
+func (cs *CallSet) touchStream(seq uint64) (reflect.Value, IRpcHandler, *clientStream) {
+	cs.pendingLock.Lock()
+	defer cs.pendingLock.Unlock()
+
+	call := cs.pending[seq]
+	if call == nil || call.stream == nil {
+		return reflect.Value{}, nil, nil
+	}
+
+	cs.callTimerHeap.Cancel(seq)
+	cs.callTimerHeap.AddTimer(seq, call.TimeOut)
+	return *call.callback, call.rpcHandler, call.stream
+}
+
+// pushStreamMsg 将一条消息投递到调用方服务
+func (client *Client) pushStreamMsg(callback reflect.Value, rpcHandler IRpcHandler, stream *clientStream, reply interface{}) error {
+	call := MakeCall()
+	call.Reply = reply
+	call.callback = &callback
+	call.rpcHandler = rpcHandler
+	call.stream = stream
+
+	err := rpcHandler.PushRpcResponse(call)
+	if err != nil {
+		ReleaseCall(call)
+	}
+
+	return err
+}
+
+// processStreamMsg 处理跨结点流式调用的一条消息，无法解析时结束调用并通知被调用方取消
+func (client *Client) processStreamMsg(processor IRpcProcessor, responseData IRpcResponseData) {
+	seq := responseData.GetSeq()
+	callback, rpcHandler, stream := client.touchStream(seq)
+	if stream == nil {
+		log.Warn("rpcClient cannot find stream seq", log.Uint64("seq", seq))
+		return
+	}
+
+	reply := newReplyParam(callback)
+	err := processor.Unmarshal(responseData.GetReply(), reply)
+	if err == nil {
+		err = client.pushStreamMsg(callback, rpcHandler, stream, reply)
+	}
+
+	if err != nil {
+		log.Error("rpcClient process stream message failed", log.Uint64("seq", seq), log.ErrorField("error", err))
+		client.abortStream(seq, err)
+	}
+}
+
+// deliverStreamMsg 本结点的流式调用，克隆消息后投递到调用方服务
+func (client *Client) deliverStreamMsg(seq uint64, msg interface{}) error {
+	callback, rpcHandler, stream := client.touchStream(seq)
+	if stream == nil || stream.canceled.Load() == true {
+		return NewError(CodeCanceled, "stream is canceled")
+	}
+
+	_, processor := GetProcessorType(msg)
+	bytes, err := processor.Marshal(msg)
+	if err != nil {
+		return err
+	}
+
+	reply := newReplyParam(callback)
+	err = processor.Unmarshal(bytes, reply)
+	if err != nil {
+		return err
+	}
+
+	err = client.pushStreamMsg(callback, rpcHandler, stream, reply)
+	if err != nil {
+		client.abortStream(seq, err)
+	}
+
+	return err
+}
+
+// abortStream 调用方无法继续接收，结束流式调用
+func (client *Client) abortStream(seq uint64, err error) {
+	call := client.RemovePending(seq)
+	if call == nil {
+		return
+	}
+
+	call.stream.cancel()
+	call.Err = err
+	call.reportBreaker(nil, true)
+	client.makeCallFail(call)
+}
+
+// StreamCall 流式调用，callback为func(msg *Res, err error)，在本服务协程中按顺序回调每条消息(err为nil)，
+// 最后回调一次err不为nil表示结束，io.EOF为被调用方正常结束。超时时间为两条消息之间的最长间隔，返回的CancelRpc可以提前结束
+func (handler *RpcHandler) StreamCall(serviceMethod string, args interface{}, callback interface{}) (CancelRpc, error) {
+	return handler.streamCallRpc(context.Background(), DefaultRpcTimeout, NodeIdNull, serviceMethod, args, callback)
+}
+
+func (handler *RpcHandler) StreamCallNode(nodeId string, serviceMethod string, args interface{}, callback interface{}) (CancelRpc, error) {
+	return handler.streamCallRpc(context.Background(), DefaultRpcTimeout, nodeId, serviceMethod, args, callback)
+}
+
+// StreamCallContext 流式调用，ctx取消时回调返回CodeCanceled错误，同时通知被调用方取消
+func (handler *RpcHandler) StreamCallContext(ctx context.Context, serviceMethod string, args interface{}, callback interface{}) (CancelRpc, error) {
+	return handler.StreamCallNodeContext(ctx, NodeIdNull, serviceMethod, args, callback)
+}
+
+func (handler *RpcHandler) StreamCallNodeContext(ctx context.Context, nodeId string, serviceMethod string, args interface{}, callback interface{}) (CancelRpc, error) {
+	timeout, err := getContextTimeout(ctx)
+	if err != nil {
+		fVal, fErr := handler.getCallbackValue(serviceMethod, callback)
+		if fErr != nil {
+			return emptyCancelRpc, fErr
+		}
+
+		fVal.Call([]reflect.Value{reflect.New(fVal.Type().In(0).Elem()), reflect.ValueOf(err)})
+		return emptyCancelRpc, nil
+	}
+
+	return handler.streamCallRpc(ctx, timeout, nodeId, serviceMethod, args, callback)
+}
+
+func (handler *RpcHandler) streamCallRpc(ctx context.Context, timeout time.Duration, nodeId string, serviceMethod string, args interface{}, callback interface{}) (CancelRpc, error) {
+	fVal, err := handler.getCallbackValue(serviceMethod, callback)
+	if err != nil {
+		return emptyCancelRpc, err
+	}
+
+	reply := newReplyParam(fVal)
+	pClient, err := handler.selectRpcClient(nodeId, serviceMethod)
+	if pClient == nil || err != nil {
+		if err == nil {
+			err = NewError(CodeNoNode, fmt.Sprintf("cannot find %s from nodeId %s", serviceMethod, nodeId))
+		}
+		fVal.Call([]reflect.Value{reflect.ValueOf(reply), reflect.ValueOf(err)})
+		log.Error("cannot find serviceMethod from node", log.String("serviceMethod", serviceMethod), log.String("nodeId", nodeId))
+		return emptyCancelRpc, nil
+	}
+
+	window := DefaultStreamWindow
+	if window == 0 {
+		window = 1
+	}
+
+	return handler.asyncCallClient(ctx, timeout, pClient, serviceMethod, fVal, args, reply, window)
+}
+
+// callStreamMethod 调用流式RPC函数，崩溃时以CodePanic结束流
+func (handler *RpcHandler) callStreamMethod(v *RpcMethodInfo, ctx context.Context, stream *ServerStream, args interface{}) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			log.StackError(fmt.Sprint(r))
+			err = NewError(CodePanic, "call error : core dumps")
+		}
+	}()
+
+	stream.ctx = ctx
+	paramList := make([]reflect.Value, 0, 4)
+	paramList = append(paramList, reflect.ValueOf(handler.GetRpcHandler())) //接受者
+	if v.hasContext == true {
+		paramList = append(paramList, reflect.ValueOf(ctx))
+	}
+	paramList = append(paramList, reflect.ValueOf(stream), reflect.ValueOf(args))
+
+	returnValues := v.method.Func.Call(paramList)
+	if errInter := returnValues[0].Interface(); errInter != nil {
+		err = errInter.(error)
+	}
+
+	return err
+}
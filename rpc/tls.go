@@ -0,0 +1,246 @@
+package rpc
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"sync/atomic"
+
+	"github.com/duanhf2012/origin/v2/log"
+	"github.com/duanhf2012/origin/v2/network"
+)
+
+// TLSConfig 结点间RPC连接的TLS配置，所有结点需同时开启或关闭
+type TLSConfig struct {
+	CertFile   string //本结点证书，同时用作服务端证书与mTLS的客户端证书
+	KeyFile    string
+	CAFile     string //校验对端证书的CA，不配置时使用系统CA
+	ServerName string //校验服务端证书中的域名，不配置时只校验证书链
+	ClientAuth bool   //mTLS，服务端要求并校验客户端证书
+}
+
+// VerifyPeerFun 校验对端结点身份，certs[0]为对端证书。作为客户端时nodeId为目标结点；作为服务端时，
+// 开启结点认证(Auth)后在认证握手完成时以认证的调用方结点校验，未开启认证时对端结点未知，nodeId为空
+type VerifyPeerFun func(nodeId string, certs []*x509.Certificate) error
+
+type tlsMaterial struct {
+	cfg  TLSConfig
+	cert *tls.Certificate
+	pool *x509.CertPool //为nil时使用系统CA
+}
+
+var tlsLocker sync.Mutex
+var tlsSetting atomic.Pointer[tlsMaterial]
+var verifyPeerFun atomic.Pointer[VerifyPeerFun]
+
+// SetTLSConfig 设置结点间连接的TLS配置，cfg为nil时不使用TLS。需在Server启动与创建Client之前调用
+func SetTLSConfig(cfg *TLSConfig) error {
+	tlsLocker.Lock()
+	defer tlsLocker.Unlock()
+
+	if cfg == nil {
+		tlsSetting.Store(nil)
+		return nil
+	}
+
+	material, err := loadTLSMaterial(cfg)
+	if err != nil {
+		return err
+	}
+
+	tlsSetting.Store(material)
+	return nil
+}
+
+// ReloadTLS 重新读取证书与CA文件，用于证书轮换。已建立的连接不受影响，之后的握手使用新证书
+func ReloadTLS() error {
+	tlsLocker.Lock()
+	defer tlsLocker.Unlock()
+
+	material := tlsSetting.Load()
+	if material == nil {
+		return errors.New("tls is not enabled")
+	}
+
+	newMaterial, err := loadTLSMaterial(&material.cfg)
+	if err != nil {
+		return err
+	}
+
+	tlsSetting.Store(newMaterial)
+	return nil
+}
+
+// SetVerifyPeerFun 设置对端结点身份校验，在证书链校验通过后调用，fun为nil时不校验
+func SetVerifyPeerFun(fun VerifyPeerFun) {
+	if fun == nil {
+		verifyPeerFun.Store(nil)
+		return
+	}
+
+	verifyPeerFun.Store(&fun)
+}
+
+func loadTLSMaterial(cfg *TLSConfig) (*tlsMaterial, error) {
+	if cfg.CertFile == "" || cfg.KeyFile == "" {
+		return nil, errors.New("tls CertFile and KeyFile must be configured")
+	}
+
+	cert, err := tls.LoadX509KeyPair(cfg.CertFile, cfg.KeyFile)
+	if err != nil {
+		return nil, fmt.Errorf("load tls key pair fail:%s", err.Error())
+	}
+
+	material := &tlsMaterial{cfg: *cfg, cert: &cert}
+	if cfg.CAFile == "" {
+		return material, nil
+	}
+
+	caData, err := os.ReadFile(cfg.CAFile)
+	if err != nil {
+		return nil, fmt.Errorf("read tls CAFile fail:%s", err.Error())
+	}
+
+	material.pool = x509.NewCertPool()
+	if material.pool.AppendCertsFromPEM(caData) == false {
+		return nil, fmt.Errorf("no certificate found in tls CAFile %s", cfg.CAFile)
+	}
+
+	return material, nil
+}
+
+func getTLSCertificate() (*tls.Certificate, error) {
+	material := tlsSetting.Load()
+	if material == nil {
+		return nil, errors.New("tls is not enabled")
+	}
+
+	return material.cert, nil
+}
+
+// verifyPeer 用当前的CA校验证书链，verifyNode为true时再校验结点身份。证书与CA在每次握手时读取，轮换后无需重建连接配置
+func verifyPeer(nodeId string, isServer bool, state tls.ConnectionState, verifyNode bool) error {
+	material := tlsSetting.Load()
+	if material == nil {
+		return errors.New("tls is not enabled")
+	}
+
+	if len(state.PeerCertificates) == 0 {
+		//服务端不要求客户端证书
+		if isServer == true && material.cfg.ClientAuth == false {
+			return nil
+		}
+		return errors.New("peer certificate is not provided")
+	}
+
+	opts := x509.VerifyOptions{
+		Roots:         material.pool,
+		Intermediates: x509.NewCertPool(),
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	if isServer == true {
+		opts.KeyUsages = []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth}
+	} else {
+		opts.DNSName = material.cfg.ServerName
+	}
+	for _, cert := range state.PeerCertificates[1:] {
+		opts.Intermediates.AddCert(cert)
+	}
+
+	_, err := state.PeerCertificates[0].Verify(opts)
+	if err == nil && verifyNode == true {
+		if fun := verifyPeerFun.Load(); fun != nil {
+			err = (*fun)(nodeId, state.PeerCertificates)
+		}
+	}
+
+	if err != nil {
+		log.Error("verify tls peer fail", log.String("nodeId", nodeId), log.Bool("isServer", isServer), log.ErrorField("error", err))
+	}
+
+	return err
+}
+
+// getServerTLSConfig 未开启TLS时返回nil
+func getServerTLSConfig() *tls.Config {
+	if tlsSetting.Load() == nil {
+		return nil
+	}
+
+	return &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		//是否要求客户端证书在握手时按当前配置决定，证书链在VerifyConnection中校验
+		GetConfigForClient: func(*tls.ClientHelloInfo) (*tls.Config, error) {
+			material := tlsSetting.Load()
+			if material == nil {
+				return nil, errors.New("tls is not enabled")
+			}
+
+			clientAuth := tls.NoClientCert
+			if material.cfg.ClientAuth == true {
+				clientAuth = tls.RequireAnyClientCert
+			}
+
+			return &tls.Config{
+				MinVersion:   tls.VersionTLS12,
+				ClientAuth:   clientAuth,
+				Certificates: []tls.Certificate{*material.cert},
+				//开启认证时对端结点在认证握手后才确定，由verifyPeerNode校验结点身份
+				VerifyConnection: func(state tls.ConnectionState) error {
+					return verifyPeer("", true, state, handshakeConfig.Load() == nil)
+				},
+			}, nil
+		},
+	}
+}
+
+// getClientTLSConfig 连接nodeId的TLS配置，未开启TLS时返回nil
+func getClientTLSConfig(nodeId string) *tls.Config {
+	if tlsSetting.Load() == nil {
+		return nil
+	}
+
+	return &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		//证书链与域名在VerifyConnection中用当前的CA校验
+		InsecureSkipVerify: true,
+		GetClientCertificate: func(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+			return getTLSCertificate()
+		},
+		VerifyConnection: func(state tls.ConnectionState) error {
+			return verifyPeer(nodeId, false, state, true)
+		},
+	}
+}
+
+type tlsStateConn interface {
+	TLSConnectionState() (tls.ConnectionState, bool)
+}
+
+// verifyPeerNode 服务端认证握手完成后以认证的调用方结点校验结点身份，非TLS连接或对端没有证书时不校验
+func verifyPeerNode(conn network.Conn, nodeId string) error {
+	fun := verifyPeerFun.Load()
+	if fun == nil {
+		return nil
+	}
+
+	tc, ok := conn.(tlsStateConn)
+	if ok == false {
+		return nil
+	}
+
+	state, ok := tc.TLSConnectionState()
+	if ok == false || len(state.PeerCertificates) == 0 {
+		return nil
+	}
+
+	err := (*fun)(nodeId, state.PeerCertificates)
+	if err != nil {
+		log.Error("verify tls peer node fail", log.String("nodeId", nodeId), log.ErrorField("error", err))
+	}
+
+	return err
+}
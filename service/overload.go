@@ -0,0 +1,100 @@
+package service
+
+import (
+	"sync/atomic"
+
+	"github.com/duanhf2012/origin/v2/event"
+	"github.com/duanhf2012/origin/v2/log"
+	"github.com/duanhf2012/origin/v2/rpc"
+)
+
+// OverloadStats 服务过载保护的统计
+type OverloadStats struct {
+	Overloaded  bool   //当前是否过载
+	OverloadNum uint64 //进入过载的次数
+	RejectedNum uint64 //过载时拒绝的Rpc请求数
+	DroppedNum  uint64 //过载时丢弃的事件数
+}
+
+type serviceOverload struct {
+	cfg         atomic.Pointer[rpc.OverloadConfig]
+	overloaded  atomic.Bool
+	overloadNum atomic.Uint64
+	rejectedNum atomic.Uint64
+	droppedNum  atomic.Uint64
+}
+
+// SetOverloadConfig 设置本服务的过载保护，优先于集群配置，cfg为nil时使用集群配置。只对本服务生效，不修改集群配置
+func (s *Service) SetOverloadConfig(cfg *rpc.OverloadConfig) error {
+	if cfg == nil {
+		s.overload.cfg.Store(rpc.GetOverloadConfig(s.GetName()))
+		return nil
+	}
+
+	c, err := rpc.CheckOverloadConfig(cfg)
+	if err != nil {
+		return err
+	}
+
+	s.overload.cfg.Store(c)
+	return nil
+}
+
+// GetOverloadStats 获取过载保护的统计
+func (s *Service) GetOverloadStats() OverloadStats {
+	return OverloadStats{
+		Overloaded:  s.overload.overloaded.Load(),
+		OverloadNum: s.overload.overloadNum.Load(),
+		RejectedNum: s.overload.rejectedNum.Load(),
+		DroppedNum:  s.overload.droppedNum.Load(),
+	}
+}
+
+// admitEvent 过载时拒绝Rpc请求，丢弃低优先级事件，其他事件(如Rpc返回)不受影响
+func (s *Service) admitEvent(ev event.IEvent) error {
+	cfg := s.overload.cfg.Load()
+	if cfg == nil || s.checkOverload(cfg) == false {
+		return nil
+	}
+
+	eventType := ev.GetEventType()
+	if eventType == event.ServiceRpcRequestEvent {
+		s.overload.rejectedNum.Add(1)
+		return rpc.NewError(rpc.CodeOverload, "service "+s.GetName()+" is overloaded")
+	}
+
+	for _, dropType := range cfg.DropEventTypes {
+		if dropType == eventType {
+			s.overload.droppedNum.Add(1)
+			return rpc.NewError(rpc.CodeOverload, "service "+s.GetName()+" is overloaded, the event is dropped")
+		}
+	}
+
+	return nil
+}
+
+// checkOverload 按队列长度更新过载状态，达到HighWater时进入过载，降到LowWater时才恢复
+func (s *Service) checkOverload(cfg *rpc.OverloadConfig) bool {
+	eventNum := len(s.chanEvent)
+	if s.overload.overloaded.Load() == true {
+		if eventNum > cfg.LowWater {
+			return true
+		}
+
+		if s.overload.overloaded.CompareAndSwap(true, false) == true {
+			log.Info("service is recovered from overload", log.String("serviceName", s.GetName()), log.Int("eventNum", eventNum),
+				log.Uint64("rejectedNum", s.overload.rejectedNum.Load()), log.Uint64("droppedNum", s.overload.droppedNum.Load()))
+		}
+		return false
+	}
+
+	if eventNum < cfg.HighWater {
+		return false
+	}
+
+	if s.overload.overloaded.CompareAndSwap(false, true) == true {
+		s.overload.overloadNum.Add(1)
+		log.Warn("service is overloaded", log.String("serviceName", s.GetName()), log.Int("eventNum", eventNum), log.Int("highWater", cfg.HighWater))
+	}
+	return true
+}
@@ -38,6 +38,7 @@ type IService interface {
 	GetProfiler() *profiler.Profiler
 	GetServiceEventChannelNum() int
 	GetServiceTimerChannelNum() int
+	GetOverloadStats() OverloadStats
 
 	SetEventChannelNum(num int)
 	OpenProfiler()
@@ -62,8 +63,11 @@ type Service struct {
 	nodeConnLister         rpc.INodeConnListener
 	natsConnListener       rpc.INatsConnListener
 	discoveryServiceLister rpc.IDiscoveryServiceListener
+	hashRingListener       rpc.IHashRingListener
 	chanEvent              chan event.IEvent
+	overload               serviceOverload //事件队列的过载保护
 	closeSig               chan struct{}
+	serviceMgr             *ServiceManager //所属结点的服务管理器
 }
 
 // DiscoveryServiceEvent 发现服务结点
@@ -73,6 +77,11 @@ type DiscoveryServiceEvent struct {
 	NodeId      string
 }
 
+// HashRingChangeEvent 服务的一致性哈希环发生变化
+type HashRingChangeEvent struct {
+	ServiceName []string
+}
+
 type EtcdServiceRecordEvent struct {
 	NetworkName string
 	TTLSecond   int64
@@ -91,6 +100,10 @@ func (rpcEventData *DiscoveryServiceEvent) GetEventType() event.EventType {
 	return event.Sys_Event_DiscoverService
 }
 
+func (hashRingEvent *HashRingChangeEvent) GetEventType() event.EventType {
+	return event.Sys_Event_HashRing_Change
+}
+
 func (s *Service) OnSetup(iService IService) {
 	if iService.GetName() == "" {
 		s.name = reflect.Indirect(reflect.ValueOf(iService)).Type().Name()
@@ -132,6 +145,7 @@ func (s *Service) Init(iService IService, getClientFun rpc.FuncRpcClient, getSer
 	s.seedModuleId = InitModuleId
 	s.descendants = map[uint32]IModule{}
 	s.serviceCfg = serviceCfg
+	s.overload.cfg.Store(rpc.GetOverloadConfig(iService.GetName()))
 	s.goroutineNum = 1
 	s.eventProcessor = event.NewEventProcessor()
 	s.eventProcessor.Init(s)
@@ -324,6 +338,11 @@ func (s *Service) RegRawRpc(rpcMethodId uint32, rawRpcCB rpc.RawRpcCallBack) {
 	s.rpcHandler.RegRawRpc(rpcMethodId, rawRpcCB)
 }
 
+// SetRateLimit 设置本服务RPC函数的限流，method为空时对服务的所有函数生效
+func (s *Service) SetRateLimit(method string, limit *rpc.RateLimit) error {
+	return s.rpcHandler.SetRateLimit(method, limit)
+}
+
 func (s *Service) OnStart() {
 }
 
@@ -354,37 +373,78 @@ func (s *Service) OnDiscoverServiceEvent(ev event.IEvent) {
 	}
 }
 
+func (s *Service) OnHashRingChangeEvent(ev event.IEvent) {
+	he := ev.(*HashRingChangeEvent)
+	s.hashRingListener.OnHashRingChange(he.ServiceName)
+}
+
+func (s *Service) setServiceMgr(mgr *ServiceManager) {
+	s.serviceMgr = mgr
+}
+
+// GetServiceManager 返回安装本服务的ServiceManager
+func (s *Service) GetServiceManager() *ServiceManager {
+	return s.getServiceMgr()
+}
+
+func (s *Service) getServiceMgr() *ServiceManager {
+	if s.serviceMgr == nil {
+		return defaultServiceMgr
+	}
+
+	return s.serviceMgr
+}
+
+func (s *Service) regRpcEvent() {
+	s.getServiceMgr().regRpcEvent(s.GetName())
+}
+
+func (s *Service) unRegRpcEvent() {
+	s.getServiceMgr().unRegRpcEvent(s.GetName())
+}
+
 func (s *Service) RegNodeConnListener(nodeConnListener rpc.INodeConnListener) {
 	s.nodeConnLister = nodeConnListener
 	s.RegEventReceiverFunc(event.Sys_Event_Node_Conn_Event, s.GetEventHandler(), s.OnNodeConnEvent)
-	RegRpcEventFun(s.GetName())
+	s.regRpcEvent()
 }
 
 func (s *Service) UnRegNodeConnListener() {
 	s.UnRegEventReceiverFunc(event.Sys_Event_Node_Conn_Event, s.GetEventHandler())
-	UnRegRpcEventFun(s.GetName())
+	s.unRegRpcEvent()
 }
 
 func (s *Service) RegNatsConnListener(natsConnListener rpc.INatsConnListener) {
 	s.natsConnListener = natsConnListener
 	s.RegEventReceiverFunc(event.Sys_Event_Nats_Conn_Event, s.GetEventHandler(), s.OnNatsConnEvent)
-	RegRpcEventFun(s.GetName())
+	s.regRpcEvent()
 }
 
 func (s *Service) UnRegNatsConnListener() {
 	s.UnRegEventReceiverFunc(event.Sys_Event_Nats_Conn_Event, s.GetEventHandler())
-	UnRegRpcEventFun(s.GetName())
+	s.unRegRpcEvent()
 }
 
 func (s *Service) RegDiscoverListener(discoveryServiceListener rpc.IDiscoveryServiceListener) {
 	s.discoveryServiceLister = discoveryServiceListener
 	s.RegEventReceiverFunc(event.Sys_Event_DiscoverService, s.GetEventHandler(), s.OnDiscoverServiceEvent)
-	RegRpcEventFun(s.GetName())
+	s.regRpcEvent()
 }
 
 func (s *Service) UnRegDiscoverListener() {
 	s.UnRegEventReceiverFunc(event.Sys_Event_DiscoverService, s.GetEventHandler())
-	UnRegRpcEventFun(s.GetName())
+	s.unRegRpcEvent()
+}
+
+func (s *Service) RegHashRingListener(hashRingListener rpc.IHashRingListener) {
+	s.hashRingListener = hashRingListener
+	s.RegEventReceiverFunc(event.Sys_Event_HashRing_Change, s.GetEventHandler(), s.OnHashRingChangeEvent)
+	s.regRpcEvent()
+}
+
+func (s *Service) UnRegHashRingListener() {
+	s.UnRegEventReceiverFunc(event.Sys_Event_HashRing_Change, s.GetEventHandler())
+	s.unRegRpcEvent()
 }
 
 func (s *Service) PushRpcRequest(rpcRequest *rpc.RpcRequest) error {
@@ -409,11 +469,16 @@ func (s *Service) PushEvent(ev event.IEvent) error {
 
 func (s *Service) pushEvent(ev event.IEvent) error {
 	if len(s.chanEvent) >= maxServiceEventChannelNum {
-		err := errors.New("the event channel in the service is full")
+		err := rpc.NewError(rpc.CodeOverload, "the event channel in the service is full")
 		log.Error(err.Error())
 		return err
 	}
 
+	err := s.admitEvent(ev)
+	if err != nil {
+		return err
+	}
+
 	s.chanEvent <- ev
 	return nil
 }
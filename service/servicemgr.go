@@ -1,47 +1,89 @@
 package service
 
 import (
+	"fmt"
 	"github.com/duanhf2012/origin/v2/log"
 	"os"
 )
 
-//本地所有的service
-var mapServiceName map[string]IService
-var setupServiceList []IService
-
 type RegRpcEventFunType func(serviceName string)
 type RegDiscoveryServiceEventFunType func(serviceName string)
 var RegRpcEventFun RegRpcEventFunType
 var UnRegRpcEventFun RegRpcEventFunType
 
-func init(){
-	mapServiceName = map[string]IService{}
-	setupServiceList = []IService{}
+// ServiceManager 管理一个结点的所有服务，同一进程运行多个结点时每个结点使用各自的ServiceManager
+type ServiceManager struct {
+	mapServiceName   map[string]IService
+	setupServiceList []IService
+
+	regRpcEventFun   RegRpcEventFunType //为nil时使用RegRpcEventFun
+	unRegRpcEventFun RegRpcEventFunType //为nil时使用UnRegRpcEventFun
 }
 
-func Init() {
-	for _,s := range setupServiceList {
+//本地所有的service
+var defaultServiceMgr = NewServiceManager()
+
+func NewServiceManager() *ServiceManager {
+	return &ServiceManager{mapServiceName: map[string]IService{}}
+}
+
+// GetServiceManager 返回进程默认的ServiceManager
+func GetServiceManager() *ServiceManager {
+	return defaultServiceMgr
+}
+
+// SetRpcEventFun 设置服务注册与取消Rpc事件监听的函数，通常为所在结点Cluster的RegRpcEvent与UnRegRpcEvent
+func (mgr *ServiceManager) SetRpcEventFun(regFun RegRpcEventFunType, unRegFun RegRpcEventFunType) {
+	mgr.regRpcEventFun = regFun
+	mgr.unRegRpcEventFun = unRegFun
+}
+
+func (mgr *ServiceManager) regRpcEvent(serviceName string) {
+	if mgr.regRpcEventFun != nil {
+		mgr.regRpcEventFun(serviceName)
+		return
+	}
+
+	RegRpcEventFun(serviceName)
+}
+
+func (mgr *ServiceManager) unRegRpcEvent(serviceName string) {
+	if mgr.unRegRpcEventFun != nil {
+		mgr.unRegRpcEventFun(serviceName)
+		return
+	}
+
+	UnRegRpcEventFun(serviceName)
+}
+
+// Init 按安装顺序调用服务的OnInit
+func (mgr *ServiceManager) Init() error {
+	for _,s := range mgr.setupServiceList {
 		err := s.OnInit()
 		if err != nil {
-			log.Error("Failed to initialize "+s.GetName()+" service",log.ErrorField("err",err))
-			os.Exit(1)
+			return fmt.Errorf("failed to initialize %s service: %w", s.GetName(), err)
 		}
 	}
+
+	return nil
 }
 
-func Setup(s IService) bool {
-	_,ok := mapServiceName[s.GetName()]
+func (mgr *ServiceManager) Setup(s IService) bool {
+	_,ok := mgr.mapServiceName[s.GetName()]
 	if ok == true {
 		return false
 	}
 
-	mapServiceName[s.GetName()] = s
-	setupServiceList = append(setupServiceList, s)
+	if ms, ok := s.(interface{ setServiceMgr(mgr *ServiceManager) }); ok == true {
+		ms.setServiceMgr(mgr)
+	}
+	mgr.mapServiceName[s.GetName()] = s
+	mgr.setupServiceList = append(mgr.setupServiceList, s)
 	return true
 }
 
-func GetService(serviceName string) IService {
-	s,ok := mapServiceName[serviceName]
+func (mgr *ServiceManager) GetService(serviceName string) IService {
+	s,ok := mgr.mapServiceName[serviceName]
 	if ok == false {
 		return nil
 	}
@@ -49,20 +91,58 @@ func GetService(serviceName string) IService {
 	return s
 }
 
-func Start(){
-	for _,s := range setupServiceList {
+// GetServiceList 按安装顺序返回结点的所有服务
+func (mgr *ServiceManager) GetServiceList() []IService {
+	return append([]IService{}, mgr.setupServiceList...)
+}
+
+func (mgr *ServiceManager) Start(){
+	for _,s := range mgr.setupServiceList {
 		s.Start()
 	}
 }
 
-func StopAllService(){
-	for i := len(setupServiceList) - 1; i >= 0; i-- {
-		setupServiceList[i].Stop()
+func (mgr *ServiceManager) StopAllService(){
+	for i := len(mgr.setupServiceList) - 1; i >= 0; i-- {
+		mgr.setupServiceList[i].Stop()
 	}
 }
 
-func NotifyAllServiceRetire(){
-	for i := len(setupServiceList) - 1; i >= 0; i-- {
-		setupServiceList[i].SetRetire()
+func (mgr *ServiceManager) NotifyAllServiceRetire(){
+	for i := len(mgr.setupServiceList) - 1; i >= 0; i-- {
+		mgr.setupServiceList[i].SetRetire()
+	}
+}
+
+func Init() {
+	err := defaultServiceMgr.Init()
+	if err != nil {
+		log.Error("Failed to initialize service",log.ErrorField("err",err))
+		os.Exit(1)
 	}
-}
\ No newline at end of file
+}
+
+func Setup(s IService) bool {
+	return defaultServiceMgr.Setup(s)
+}
+
+func GetService(serviceName string) IService {
+	return defaultServiceMgr.GetService(serviceName)
+}
+
+// GetServiceList 按安装顺序返回本结点的所有服务
+func GetServiceList() []IService {
+	return defaultServiceMgr.GetServiceList()
+}
+
+func Start(){
+	defaultServiceMgr.Start()
+}
+
+func StopAllService(){
+	defaultServiceMgr.StopAllService()
+}
+
+func NotifyAllServiceRetire(){
+	defaultServiceMgr.NotifyAllServiceRetire()
+}
@@ -189,7 +189,12 @@ func (ge *GinEvent) GetEventType() event.EventType {
 }
 
 func (gm *GinModule) handleMethod(httpMethod, relativePath string, handlers ...SafeHandlerFunc) gin.IRoutes {
-	return gm.Engine.Handle(httpMethod, relativePath, func(c *gin.Context) {
+	return gm.Engine.Handle(httpMethod, relativePath, gm.safeHandler(handlers...))
+}
+
+// safeHandler 将handlers转到service协程中执行，gin协程等待处理完成或超时
+func (gm *GinModule) safeHandler(handlers ...SafeHandlerFunc) gin.HandlerFunc {
+	return func(c *gin.Context) {
 		for _, p := range gm.processor {
 			_, err := p.Process(c)
 			if err != nil {
@@ -213,7 +218,7 @@ func (gm *GinModule) handleMethod(httpMethod, relativePath string, handlers ...S
 			c.AbortWithStatus(http.StatusRequestTimeout)
 		case <-chanWait:
 		}
-	})
+	}
 }
 
 // GET 回调处理是在gin协程中
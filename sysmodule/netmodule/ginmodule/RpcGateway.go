@@ -0,0 +1,203 @@
+package ginmodule
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/duanhf2012/origin/v2/log"
+	"github.com/duanhf2012/origin/v2/rpc"
+	"github.com/gin-gonic/gin"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+// GatewayAuthFun 校验http请求能否调用serviceMethod，返回错误时回复403。返回的Metadata随RPC请求发给被调用方，如用户Id
+type GatewayAuthFun func(c *gin.Context, serviceMethod string) (rpc.Metadata, error)
+
+// GatewayErrorRes 调用失败时返回的json
+type GatewayErrorRes struct {
+	Code  rpc.ErrCode
+	Error string
+}
+
+// DefaultGatewayMaxBodySize 请求体的默认最大字节数
+const DefaultGatewayMaxBodySize = 1 << 20
+
+const gatewayBodyKey = "origin/rpcGatewayBody"
+
+type gatewayRoute func(gw *RpcGateway, c *SafeContext, ctx context.Context, nodeId string, serviceMethod string, body []byte)
+
+// RpcGateway 把POST {relativePath}/{Service}/{Method}转为对集群的AsyncCall，请求与返回都为json，
+// 只有通过ExposeRpc注册的函数可以调用。请求体在gin协程中读取，鉴权、调用与回调在GinModule所在服务的协程中执行
+type RpcGateway struct {
+	gm          *GinModule
+	timeout     time.Duration
+	maxBodySize int64
+	authFun     GatewayAuthFun
+	mapRoute    map[string]gatewayRoute //map["Service.RPC_Method"]
+}
+
+// NewRpcGateway 创建网关，timeout为RPC调用超时，需小于GinModule的handleTimeout
+func NewRpcGateway(gm *GinModule, timeout time.Duration) *RpcGateway {
+	return &RpcGateway{gm: gm, timeout: timeout, maxBodySize: DefaultGatewayMaxBodySize, mapRoute: map[string]gatewayRoute{}}
+}
+
+// SetMaxBodySize 设置请求体的最大字节数，超过时回复413，默认为DefaultGatewayMaxBodySize
+func (gw *RpcGateway) SetMaxBodySize(maxBodySize int64) {
+	gw.maxBodySize = maxBodySize
+}
+
+// SetAuthFun 设置鉴权函数，不设置时所有注册的函数都可以调用
+func (gw *RpcGateway) SetAuthFun(authFun GatewayAuthFun) {
+	gw.authFun = authFun
+}
+
+// Setup 注册路由，如gw.Setup("/rpc")后POST /rpc/TestService1/Sum调用TestService1.RPC_Sum，
+// 可以通过?node=nodeId指定结点。需在GinModule的Start之前调用
+func (gw *RpcGateway) Setup(relativePath string) gin.IRoutes {
+	return gw.gm.Engine.POST(strings.TrimSuffix(relativePath, "/")+"/:service/:method", gw.readBody, gw.gm.safeHandler(gw.handle))
+}
+
+// readBody 在gin协程中读取请求体，避免慢速或过大的请求阻塞服务协程
+func (gw *RpcGateway) readBody(c *gin.Context) {
+	body, err := io.ReadAll(http.MaxBytesReader(c.Writer, c.Request.Body, gw.maxBodySize))
+	if err != nil {
+		status := http.StatusBadRequest
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			status = http.StatusRequestEntityTooLarge
+		}
+		c.AbortWithStatusJSON(status, &GatewayErrorRes{Code: rpc.CodeInvalidParam, Error: err.Error()})
+		return
+	}
+
+	c.Set(gatewayBodyKey, body)
+}
+
+// ExposeRpc 允许通过网关调用serviceMethod，Req与Res为RPC函数的参数与返回类型，protobuf消息使用protojson编解码。
+// serviceMethod如"TestService1.RPC_Sum"
+func ExposeRpc[Req any, Res any](gw *RpcGateway, serviceMethod string) {
+	gw.mapRoute[serviceMethod] = func(gw *RpcGateway, c *SafeContext, ctx context.Context, nodeId string, serviceMethod string, body []byte) {
+		req := new(Req)
+		err := unmarshalGatewayJson(body, req)
+		if err != nil {
+			gw.replyError(c, http.StatusBadRequest, rpc.NewError(rpc.CodeInvalidParam, err.Error()))
+			return
+		}
+
+		callCtx, cancel := context.WithTimeout(ctx, gw.timeout)
+		callback := func(res *Res, err error) {
+			cancel()
+
+			//请求已经结束时gin.Context可能已被复用，不能再写入
+			if ctx.Err() != nil {
+				return
+			}
+			if err != nil {
+				gw.replyError(c, gatewayStatus(err), err)
+				return
+			}
+
+			data, err := marshalGatewayJson(res)
+			if err != nil {
+				gw.replyError(c, http.StatusInternalServerError, err)
+				return
+			}
+			c.DataAndDone(http.StatusOK, "application/json; charset=utf-8", data)
+		}
+
+		rpcHandler := gw.gm.GetService().GetRpcHandler()
+		if nodeId != "" {
+			_, err = rpcHandler.AsyncCallNodeContext(callCtx, nodeId, serviceMethod, req, callback)
+		} else {
+			_, err = rpcHandler.AsyncCallContext(callCtx, serviceMethod, req, callback)
+		}
+		if err != nil {
+			cancel()
+			gw.replyError(c, gatewayStatus(err), err)
+		}
+	}
+}
+
+func (gw *RpcGateway) handle(c *SafeContext) {
+	serviceMethod := c.Param("service") + "." + c.Param("method")
+	if strings.HasPrefix(c.Param("method"), "RPC") == false {
+		serviceMethod = c.Param("service") + ".RPC_" + c.Param("method")
+	}
+
+	route, ok := gw.mapRoute[serviceMethod]
+	if ok == false {
+		gw.replyError(c, http.StatusNotFound, rpc.NewError(rpc.CodeMethodNotFound, serviceMethod+" is not exposed"))
+		return
+	}
+
+	ctx := c.Request.Context()
+	if gw.authFun != nil {
+		md, err := gw.authFun(c.Context, serviceMethod)
+		if err != nil {
+			gw.replyError(c, http.StatusForbidden, err)
+			return
+		}
+		if md != nil {
+			ctx = rpc.NewOutgoingContext(ctx, md)
+		}
+	}
+
+	body, _ := c.Get(gatewayBodyKey)
+	route(gw, c, ctx, c.Query("node"), serviceMethod, body.([]byte))
+}
+
+func (gw *RpcGateway) replyError(c *SafeContext, status int, err error) {
+	if status >= http.StatusInternalServerError {
+		log.Error("rpc gateway call failed", log.String("path", c.Request.URL.Path), log.ErrorField("error", err))
+	}
+
+	c.JSONAndDone(status, &GatewayErrorRes{Code: rpc.Code(err), Error: err.Error()})
+}
+
+// gatewayStatus 按RPC错误码返回http状态码
+func gatewayStatus(err error) int {
+	switch rpc.Code(err) {
+	case rpc.CodeInvalidParam:
+		return http.StatusBadRequest
+	case rpc.CodeMethodNotFound, rpc.CodeNoNode:
+		return http.StatusNotFound
+	case rpc.CodeTimeout:
+		return http.StatusGatewayTimeout
+	case rpc.CodeRateLimited:
+		return http.StatusTooManyRequests
+	case rpc.CodeOverload, rpc.CodeDisconnected, rpc.CodeCircuitOpen:
+		return http.StatusServiceUnavailable
+	case rpc.CodeCanceled:
+		return http.StatusRequestTimeout
+	}
+
+	return http.StatusInternalServerError
+}
+
+func unmarshalGatewayJson(data []byte, v interface{}) error {
+	if msg, ok := v.(proto.Message); ok == true {
+		if len(data) == 0 {
+			return nil
+		}
+		return protojson.Unmarshal(data, msg)
+	}
+
+	if len(data) == 0 {
+		data = []byte("{}")
+	}
+	return json.Unmarshal(data, v)
+}
+
+func marshalGatewayJson(v interface{}) ([]byte, error) {
+	if msg, ok := v.(proto.Message); ok == true {
+		return protojson.Marshal(msg)
+	}
+
+	return json.Marshal(v)
+}
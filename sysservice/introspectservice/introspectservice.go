@@ -0,0 +1,134 @@
+package introspectservice
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/duanhf2012/origin/v2/log"
+	"github.com/duanhf2012/origin/v2/rpc"
+	"github.com/duanhf2012/origin/v2/service"
+)
+
+// HttpPattern 查询RPC函数的http路径，如/debug/origin/rpc?service=TestService1
+const HttpPattern = "/debug/origin/rpc"
+
+// IntrospectService 查询本结点所有服务的RPC函数，供工具发现与动态调用。
+// 其他结点通过IntrospectService.RPC_ListServices查询，也可以通过http查询：
+// 配置了ListenAddr时单独监听；配置UseDefaultServeMux为true时注册到http.DefaultServeMux，开启-pprof时可以访问，
+// 同一进程运行多个结点时只返回第一个初始化的结点。两者都未配置时只能通过Rpc查询
+type IntrospectService struct {
+	service.Service
+
+	httpServer *http.Server
+}
+
+type ListServicesReq struct {
+	ServiceName string //只返回该服务，为空时返回所有服务
+}
+
+type ListServicesRes struct {
+	NodeId   string
+	Services []rpc.RpcServiceDesc
+}
+
+var registerOnce sync.Once
+
+func (is *IntrospectService) OnInit() error {
+	var listenAddr string
+	var useDefaultServeMux bool
+	if iConfig, ok := is.GetServiceCfg().(map[string]interface{}); ok == true {
+		if addr, ok := iConfig["ListenAddr"].(string); ok == true {
+			listenAddr = addr
+		}
+		if useDefault, ok := iConfig["UseDefaultServeMux"].(bool); ok == true {
+			useDefaultServeMux = useDefault
+		}
+	}
+
+	if listenAddr == "" {
+		if useDefaultServeMux == true {
+			registerOnce.Do(func() {
+				http.HandleFunc(HttpPattern, is.serveHttp)
+			})
+		}
+		return nil
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc(HttpPattern, is.serveHttp)
+	is.httpServer = &http.Server{Addr: listenAddr, Handler: mux, ReadTimeout: 10 * time.Second, WriteTimeout: 10 * time.Second}
+	go func() {
+		err := is.httpServer.ListenAndServe()
+		if err != nil && err != http.ErrServerClosed {
+			log.Error("introspect http server listen failed", log.String("addr", listenAddr), log.ErrorField("error", err))
+		}
+	}()
+
+	return nil
+}
+
+func (is *IntrospectService) OnRelease() {
+	if is.httpServer != nil {
+		is.httpServer.Close()
+	}
+}
+
+// ListServices 返回进程默认结点服务的RPC函数描述，服务按安装顺序排列。服务的RegRawRpc需在OnInit中调用
+func ListServices(serviceName string) ([]rpc.RpcServiceDesc, error) {
+	return listServices(service.GetServiceList(), serviceName)
+}
+
+func listServices(serviceList []service.IService, serviceName string) ([]rpc.RpcServiceDesc, error) {
+	var descList []rpc.RpcServiceDesc
+	for _, s := range serviceList {
+		if serviceName != "" && s.GetName() != serviceName {
+			continue
+		}
+		descList = append(descList, s.GetRpcHandler().DescribeRpc())
+	}
+
+	if serviceName != "" && len(descList) == 0 {
+		return nil, rpc.NewError(rpc.CodeMethodNotFound, fmt.Sprintf("service %s is not found", serviceName))
+	}
+
+	return descList, nil
+}
+
+// listServices 返回安装本服务的结点的服务
+func (is *IntrospectService) listServices(serviceName string) ([]rpc.RpcServiceDesc, error) {
+	return listServices(is.GetServiceManager().GetServiceList(), serviceName)
+}
+
+func (is *IntrospectService) getNodeId() string {
+	return is.GetRpcServer()().GetLocalNodeId()
+}
+
+func (is *IntrospectService) RPC_ListServices(req *ListServicesReq, res *ListServicesRes) error {
+	descList, err := is.listServices(req.ServiceName)
+	if err != nil {
+		return err
+	}
+
+	res.NodeId = is.getNodeId()
+	res.Services = descList
+	return nil
+}
+
+func (is *IntrospectService) serveHttp(w http.ResponseWriter, r *http.Request) {
+	descList, err := is.listServices(r.URL.Query().Get("service"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	err = encoder.Encode(&ListServicesRes{NodeId: is.getNodeId(), Services: descList})
+	if err != nil {
+		log.Error("introspect write response failed", log.ErrorField("error", err))
+	}
+}
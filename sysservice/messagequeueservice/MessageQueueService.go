@@ -35,6 +35,8 @@ type MessageQueueService struct {
 	maxProcessTopicBacklogNum int32 //最大积压的数据量，因为是写入到channel中，然后由协程取出再持久化,不设置有默认值100000
 }
 
+var _ rpc.IMessageQueueServiceServer = (*MessageQueueService)(nil)
+
 func (ms *MessageQueueService) OnInit() error {
 	ms.mapTopicRoom = map[string]*TopicRoom{}
 	errC := ms.ReadCfg()
@@ -18,6 +18,8 @@ type RankService struct {
 	rankModule  IRankModule
 }
 
+var _ rpc.IRankServiceServer = (*RankService)(nil)
+
 func (rs *RankService) OnInit() error {
 	if rs.rankModule != nil {
 		_, err := rs.AddModule(rs.rankModule)
@@ -0,0 +1,203 @@
+// protoc-gen-origin 根据proto中的service定义生成origin的RPC代码：
+// 服务端需要实现的RPC_函数接口，以及包装IRpcHandler的强类型客户端。
+//
+// 使用方式:
+//
+//	go install github.com/duanhf2012/origin/v2/tools/protoc-gen-origin
+//	protoc --go_out=. --origin_out=. rank.proto
+//
+// proto中service的名称即origin中的服务名，rpc Method(Req) returns (Res)对应服务的RPC_Method函数，
+// rpc Method(Req) returns (stream Res)对应流式RPC函数RPC_Method(stream *rpc.ServerStream, req *Req) error。
+package main
+
+import (
+	"fmt"
+
+	"google.golang.org/protobuf/compiler/protogen"
+	"google.golang.org/protobuf/types/pluginpb"
+)
+
+const version = "1.0.0"
+
+const (
+	contextPackage = protogen.GoImportPath("context")
+	rpcPackage     = protogen.GoImportPath("github.com/duanhf2012/origin/v2/rpc")
+)
+
+func main() {
+	protogen.Options{}.Run(func(gen *protogen.Plugin) error {
+		gen.SupportedFeatures = uint64(pluginpb.CodeGeneratorResponse_FEATURE_PROTO3_OPTIONAL)
+		for _, f := range gen.Files {
+			if f.Generate == false || len(f.Services) == 0 {
+				continue
+			}
+
+			for _, service := range f.Services {
+				for _, method := range service.Methods {
+					if method.Desc.IsStreamingClient() == true {
+						return fmt.Errorf("%s.%s: client streaming is not supported", service.GoName, method.GoName)
+					}
+				}
+			}
+
+			generateFile(gen, f)
+		}
+
+		return nil
+	})
+}
+
+func generateFile(gen *protogen.Plugin, file *protogen.File) {
+	g := gen.NewGeneratedFile(file.GeneratedFilenamePrefix+"_origin.pb.go", file.GoImportPath)
+	g.P("// Code generated by protoc-gen-origin. DO NOT EDIT.")
+	g.P("// versions:")
+	g.P("// \tprotoc-gen-origin v", version)
+	g.P("// source: ", file.Desc.Path())
+	g.P()
+	g.P("package ", file.GoPackageName)
+	g.P()
+
+	for _, service := range file.Services {
+		generateService(g, service)
+	}
+}
+
+func methodConst(service *protogen.Service, method *protogen.Method) string {
+	return service.GoName + "_" + method.GoName
+}
+
+func generateService(g *protogen.GeneratedFile, service *protogen.Service) {
+	serviceName := service.GoName
+	serverName := "I" + serviceName + "Server"
+	clientName := serviceName + "Client"
+
+	g.P("// ", serviceName, "Name 服务名")
+	g.P("const ", serviceName, "Name = ", fmt.Sprintf("%q", serviceName))
+	g.P()
+	g.P("// ", serviceName, "的RPC函数名，可用于Call等调用")
+	g.P("const (")
+	for _, method := range service.Methods {
+		g.P(methodConst(service, method), " = ", fmt.Sprintf("%q", serviceName+".RPC_"+method.GoName))
+	}
+	g.P(")")
+	g.P()
+
+	//服务端接口
+	g.P("// ", serverName, " ", serviceName, "需要实现的RPC函数")
+	g.P("type ", serverName, " interface {")
+	hasUnary := false
+	for _, method := range service.Methods {
+		if method.Desc.IsStreamingServer() == true {
+			g.P(method.Comments.Leading, "RPC_", method.GoName, "(stream *", g.QualifiedGoIdent(rpcPackage.Ident("ServerStream")), ", req *", g.QualifiedGoIdent(method.Input.GoIdent), ") error")
+			continue
+		}
+
+		hasUnary = true
+		g.P(method.Comments.Leading, "RPC_", method.GoName, "(req *", g.QualifiedGoIdent(method.Input.GoIdent), ", res *", g.QualifiedGoIdent(method.Output.GoIdent), ") error")
+	}
+	g.P("}")
+	g.P()
+
+	//实现了服务端接口的服务免反射调用RPC函数，流式函数仍通过反射调用
+	if hasUnary == true {
+		registerRpcMethod := g.QualifiedGoIdent(rpcPackage.Ident("RegisterRpcMethod"))
+		g.P("func init() {")
+		for _, method := range service.Methods {
+			if method.Desc.IsStreamingServer() == false {
+				g.P(registerRpcMethod, "(", fmt.Sprintf("%q", "RPC_"+method.GoName), ", ", serverName, ".RPC_", method.GoName, ")")
+			}
+		}
+		g.P("}")
+		g.P()
+	}
+
+	//客户端
+	handler := g.QualifiedGoIdent(rpcPackage.Ident("IRpcHandler"))
+	ctx := g.QualifiedGoIdent(contextPackage.Ident("Context"))
+	g.P("// ", clientName, " 调用", serviceName, "的强类型客户端，通过调用方服务的IRpcHandler发起调用")
+	g.P("type ", clientName, " struct {")
+	g.P("handler ", handler)
+	g.P("}")
+	g.P()
+	g.P("func New", clientName, "(handler ", handler, ") *", clientName, " {")
+	g.P("return &", clientName, "{handler: handler}")
+	g.P("}")
+	g.P()
+
+	for _, method := range service.Methods {
+		in := g.QualifiedGoIdent(method.Input.GoIdent)
+		out := g.QualifiedGoIdent(method.Output.GoIdent)
+		name := method.GoName
+		serviceMethod := methodConst(service, method)
+
+		if method.Desc.IsStreamingServer() == true {
+			generateStreamMethod(g, service, method)
+			continue
+		}
+
+		g.P("// Call", name, " 同步调用", serviceName, ".RPC_", name)
+		g.P("func (c *", clientName, ") Call", name, "(req *", in, ") (*", out, ", error) {")
+		g.P("res := &", out, "{}")
+		g.P("err := c.handler.Call(", serviceMethod, ", req, res)")
+		g.P("return res, err")
+		g.P("}")
+		g.P()
+
+		g.P("// Call", name, "Context 同步调用", serviceName, ".RPC_", name, "，超时时间与取消由ctx决定")
+		g.P("func (c *", clientName, ") Call", name, "Context(ctx ", ctx, ", req *", in, ") (*", out, ", error) {")
+		g.P("res := &", out, "{}")
+		g.P("err := c.handler.CallContext(ctx, ", serviceMethod, ", req, res)")
+		g.P("return res, err")
+		g.P("}")
+		g.P()
+
+		g.P("// CallNode", name, " 同步调用指定结点的", serviceName, ".RPC_", name)
+		g.P("func (c *", clientName, ") CallNode", name, "(nodeId string, req *", in, ") (*", out, ", error) {")
+		g.P("res := &", out, "{}")
+		g.P("err := c.handler.CallNode(nodeId, ", serviceMethod, ", req, res)")
+		g.P("return res, err")
+		g.P("}")
+		g.P()
+
+		g.P("// AsyncCall", name, " 异步调用", serviceName, ".RPC_", name, "，callback在调用方服务的协程中执行")
+		g.P("func (c *", clientName, ") AsyncCall", name, "(req *", in, ", callback func(res *", out, ", err error)) error {")
+		g.P("return c.handler.AsyncCall(", serviceMethod, ", req, callback)")
+		g.P("}")
+		g.P()
+
+		g.P("// Go", name, " 调用", serviceName, ".RPC_", name, "，不等待返回")
+		g.P("func (c *", clientName, ") Go", name, "(req *", in, ") error {")
+		g.P("return c.handler.Go(", serviceMethod, ", req)")
+		g.P("}")
+		g.P()
+	}
+}
+
+// generateStreamMethod 生成流式调用的客户端函数
+func generateStreamMethod(g *protogen.GeneratedFile, service *protogen.Service, method *protogen.Method) {
+	clientName := service.GoName + "Client"
+	in := g.QualifiedGoIdent(method.Input.GoIdent)
+	out := g.QualifiedGoIdent(method.Output.GoIdent)
+	name := method.GoName
+	serviceMethod := methodConst(service, method)
+	ctx := g.QualifiedGoIdent(contextPackage.Ident("Context"))
+	cancelRpc := g.QualifiedGoIdent(rpcPackage.Ident("CancelRpc"))
+
+	g.P("// Stream", name, " 流式调用", service.GoName, ".RPC_", name, "，callback在调用方服务的协程中按顺序收到每条消息，结束时err不为nil，io.EOF表示正常结束")
+	g.P("func (c *", clientName, ") Stream", name, "(req *", in, ", callback func(msg *", out, ", err error)) (", cancelRpc, ", error) {")
+	g.P("return ", g.QualifiedGoIdent(rpcPackage.Ident("TypedStreamCall")), "(c.handler, ", serviceMethod, ", req, callback)")
+	g.P("}")
+	g.P()
+
+	g.P("// Stream", name, "Context 流式调用", service.GoName, ".RPC_", name, "，取消由ctx决定")
+	g.P("func (c *", clientName, ") Stream", name, "Context(ctx ", ctx, ", req *", in, ", callback func(msg *", out, ", err error)) (", cancelRpc, ", error) {")
+	g.P("return ", g.QualifiedGoIdent(rpcPackage.Ident("TypedStreamCallContext")), "(c.handler, ctx, ", serviceMethod, ", req, callback)")
+	g.P("}")
+	g.P()
+
+	g.P("// StreamNode", name, " 流式调用指定结点的", service.GoName, ".RPC_", name)
+	g.P("func (c *", clientName, ") StreamNode", name, "(nodeId string, req *", in, ", callback func(msg *", out, ", err error)) (", cancelRpc, ", error) {")
+	g.P("return ", g.QualifiedGoIdent(rpcPackage.Ident("TypedStreamCallNode")), "(c.handler, nodeId, ", serviceMethod, ", req, callback)")
+	g.P("}")
+	g.P()
+}
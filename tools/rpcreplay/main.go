@@ -0,0 +1,104 @@
+// rpcreplay 把结点抓包中收到的请求重新发给本地运行的结点，用于离线复现依赖请求顺序的问题。
+// 抓包通过结点配置的Capture或rpc.StartCapture开启，只回放方向为ServerRequest的记录。
+//
+// 使用方式:
+//
+//	go install github.com/duanhf2012/origin/v2/tools/rpcreplay
+//	rpcreplay -addr 127.0.0.1:9001 -speed 1 ./capture/rpc-capture-*.cap
+//	rpcreplay -dump ./capture/rpc-capture-20240101-120000.000000.cap
+//
+// 开启了结点认证或TLS时需配置-node、-secret、-cluster或-cert、-key、-ca，与被回放结点的配置一致。
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/duanhf2012/origin/v2/rpc"
+)
+
+func main() {
+	addr := flag.String("addr", "127.0.0.1:9001", "rpc listen address of the node to replay")
+	target := flag.String("target", "", "node id of the node to replay, required by auth or tls")
+	speed := flag.Float64("speed", 1, "replay speed, 1 keeps the captured intervals, 0 sends without waiting")
+	services := flag.String("service", "", "only replay these services, separated by comma")
+	wait := flag.Duration("wait", 10*time.Second, "max time to wait for responses after sending")
+	dump := flag.Bool("dump", false, "print records instead of replaying")
+	nodeId := flag.String("node", "", "node id used by handshake auth")
+	cluster := flag.String("cluster", "", "cluster name used by handshake auth")
+	secret := flag.String("secret", "", "secret used by handshake auth")
+	certFile := flag.String("cert", "", "tls certificate file")
+	keyFile := flag.String("key", "", "tls key file")
+	caFile := flag.String("ca", "", "tls ca file")
+	serverName := flag.String("servername", "", "server name in tls certificate")
+	flag.Parse()
+
+	files := flag.Args()
+	if len(files) == 0 {
+		fmt.Fprintln(os.Stderr, "usage: rpcreplay [flags] capture files...")
+		flag.PrintDefaults()
+		os.Exit(2)
+	}
+
+	if *dump == true {
+		for _, fileName := range files {
+			if err := dumpCapture(fileName); err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				os.Exit(1)
+			}
+		}
+		return
+	}
+
+	if *secret != "" {
+		err := rpc.SetHandshakeConfig(&rpc.HandshakeConfig{NodeId: *nodeId, ClusterName: *cluster, Secret: *secret})
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+	}
+	if *certFile != "" {
+		err := rpc.SetTLSConfig(&rpc.TLSConfig{CertFile: *certFile, KeyFile: *keyFile, CAFile: *caFile, ServerName: *serverName})
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+	}
+
+	cfg := rpc.ReplayConfig{Addr: *addr, TargetNodeId: *target, Speed: *speed, WaitTimeout: *wait}
+	if *services != "" {
+		cfg.Services = strings.Split(*services, ",")
+	}
+
+	stats, err := rpc.NewReplayer(&cfg).Replay(files...)
+	fmt.Println(stats.String())
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+func dumpCapture(fileName string) error {
+	reader, err := rpc.OpenCapture(fileName)
+	if err != nil {
+		return err
+	}
+	defer reader.Close()
+
+	for {
+		record, err := reader.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		fmt.Printf("%s %-14s seq:%d peer:%s conn:%s method:%s processor:%d len:%d\n", record.Time.Format("2006-01-02 15:04:05.000000"),
+			record.Direction, record.Seq, record.PeerNodeId, record.ConnTag, record.ServiceMethod, record.ProcessorType, len(record.Payload))
+	}
+}
@@ -0,0 +1,106 @@
+package hash
+
+import (
+	"hash/crc32"
+	"sort"
+	"strconv"
+)
+
+const DefaultReplicas = 160
+
+// ConsistentHash 一致性哈希环，增加或移除一个成员时，只有少量的Key会被重新映射
+type ConsistentHash struct {
+	replicas   int               //每个成员的虚拟结点数量
+	hashList   []uint32          //排好序的虚拟结点哈希值
+	mapMember  map[uint32]string //map[虚拟结点哈希值]成员
+	memberList []string          //排好序的成员列表
+}
+
+// NewConsistentHash 创建一致性哈希环，replicas<=0时使用DefaultReplicas
+func NewConsistentHash(replicas int, members ...string) *ConsistentHash {
+	if replicas <= 0 {
+		replicas = DefaultReplicas
+	}
+
+	ch := &ConsistentHash{replicas: replicas, mapMember: map[uint32]string{}}
+	ch.Add(members...)
+	return ch
+}
+
+func (ch *ConsistentHash) hashKey(key string) uint32 {
+	return crc32.ChecksumIEEE([]byte(key))
+}
+
+// Add 增加成员，已经存在的成员会被忽略
+func (ch *ConsistentHash) Add(members ...string) {
+	for _, member := range members {
+		if ch.HasMember(member) {
+			continue
+		}
+
+		for i := 0; i < ch.replicas; i++ {
+			h := ch.hashKey(strconv.Itoa(i) + "#" + member)
+			//哈希冲突时，保留成员名较小的，保证与添加顺序无关
+			if old, ok := ch.mapMember[h]; ok == true {
+				if old < member {
+					continue
+				}
+			} else {
+				ch.hashList = append(ch.hashList, h)
+			}
+			ch.mapMember[h] = member
+		}
+		ch.memberList = append(ch.memberList, member)
+	}
+
+	sort.Slice(ch.hashList, func(i, j int) bool { return ch.hashList[i] < ch.hashList[j] })
+	sort.Strings(ch.memberList)
+}
+
+// Remove 移除成员
+func (ch *ConsistentHash) Remove(member string) {
+	if ch.HasMember(member) == false {
+		return
+	}
+
+	members := make([]string, 0, len(ch.memberList)-1)
+	for _, m := range ch.memberList {
+		if m != member {
+			members = append(members, m)
+		}
+	}
+
+	ch.hashList = ch.hashList[:0]
+	ch.memberList = ch.memberList[:0]
+	ch.mapMember = map[uint32]string{}
+	ch.Add(members...)
+}
+
+// Get 获取Key所属的成员，环为空时返回空串
+func (ch *ConsistentHash) Get(key string) string {
+	if len(ch.hashList) == 0 {
+		return ""
+	}
+
+	h := ch.hashKey(key)
+	idx := sort.Search(len(ch.hashList), func(i int) bool { return ch.hashList[i] >= h })
+	if idx == len(ch.hashList) {
+		idx = 0
+	}
+
+	return ch.mapMember[ch.hashList[idx]]
+}
+
+func (ch *ConsistentHash) HasMember(member string) bool {
+	idx := sort.SearchStrings(ch.memberList, member)
+	return idx < len(ch.memberList) && ch.memberList[idx] == member
+}
+
+// GetMembers 获取排好序的成员列表
+func (ch *ConsistentHash) GetMembers() []string {
+	return ch.memberList
+}
+
+func (ch *ConsistentHash) IsEmpty() bool {
+	return len(ch.memberList) == 0
+}
@@ -0,0 +1,60 @@
+package hash
+
+import (
+	"strconv"
+	"testing"
+)
+
+func TestConsistentHash(t *testing.T) {
+	ch := NewConsistentHash(0, "node_1", "node_2", "node_3")
+	if ch.Get("player_1") == "" {
+		t.Fatal("key should be mapped to a member")
+	}
+
+	//与添加顺序无关
+	ch2 := NewConsistentHash(0, "node_3", "node_1", "node_2")
+	for i := 0; i < 1000; i++ {
+		key := strconv.Itoa(i)
+		if ch.Get(key) != ch2.Get(key) {
+			t.Fatalf("key %s is mapped to %s and %s", key, ch.Get(key), ch2.Get(key))
+		}
+	}
+
+	//增加一个成员时，只有映射到新成员的Key发生变化
+	mapOwner := map[string]string{}
+	for i := 0; i < 10000; i++ {
+		key := strconv.Itoa(i)
+		mapOwner[key] = ch.Get(key)
+	}
+
+	ch.Add("node_4")
+	moved := 0
+	for key, owner := range mapOwner {
+		newOwner := ch.Get(key)
+		if newOwner == owner {
+			continue
+		}
+		if newOwner != "node_4" {
+			t.Fatalf("key %s is moved from %s to %s", key, owner, newOwner)
+		}
+		moved++
+	}
+	if moved == 0 || moved > 4000 {
+		t.Fatalf("unexpected moved key count %d", moved)
+	}
+
+	//移除成员后恢复原有的映射
+	ch.Remove("node_4")
+	for key, owner := range mapOwner {
+		if ch.Get(key) != owner {
+			t.Fatalf("key %s should be mapped to %s", key, owner)
+		}
+	}
+
+	ch.Remove("node_1")
+	ch.Remove("node_2")
+	ch.Remove("node_3")
+	if ch.IsEmpty() == false || ch.Get("player_1") != "" {
+		t.Fatal("hash ring should be empty")
+	}
+}